@@ -3,14 +3,17 @@ package api
 import (
 	"loja-online/internal/config"
 	"loja-online/internal/handlers"
+	"loja-online/internal/jwtkeys"
 	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SetupRouter configura todas as rotas da aplicação
-func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+func SetupRouter(db *gorm.DB, cfg *config.Config, keys *jwtkeys.Manager) *gin.Engine {
 	router := gin.Default()
 
 	// Middleware global
@@ -22,74 +25,229 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	router.LoadHTMLGlob("web/templates/*")
 
 	// Handlers
-	h := handlers.New(db, cfg)
+	h := handlers.New(db, cfg, keys)
+
+	// Limitação de tentativas nas rotas públicas de autenticação
+	limiter := ratelimit.NewStore(cfg.RateLimitStore, db)
+	limitByIP := middleware.RateLimit(limiter, "auth", cfg.RateLimitPerIP, cfg.RateLimitWindow, middleware.ByIP)
+	// Login e recuperação de senha contam separadamente, para que pedidos de
+	// recuperação não bloqueiem o login da conta e vice-versa
+	limitLogin := middleware.RateLimit(limiter, "login", cfg.RateLimitPerEmail, cfg.RateLimitWindow, middleware.ByJSONField("email"))
+	limitForgot := middleware.RateLimit(limiter, "password_forgot", cfg.RateLimitPerEmail, cfg.RateLimitWindow, middleware.ByJSONField("email"))
+	limitTwoFactor := middleware.RateLimit(limiter, "login_2fa", cfg.RateLimitPerEmail, cfg.RateLimitWindow, h.TwoFactorChallengeKey)
+
+	// Imagens de produtos e miniaturas
+	router.GET("/media/*key", h.ServeMedia)
+
+	// Chaves públicas para outros serviços validarem os tokens de acesso
+	router.GET("/.well-known/jwks.json", h.JWKS)
 
 	// Rotas públicas
 	authPublic := router.Group("/api/v1/auth")
+	authPublic.Use(limitByIP)
 	{
-		authPublic.POST("/login", h.Login)
+		authPublic.POST("/login", limitLogin, h.Login)
+		authPublic.POST("/login/2fa", limitTwoFactor, h.LoginTwoFactor)
 		authPublic.POST("/register", h.Register)
+		authPublic.POST("/refresh", h.Refresh)
+		authPublic.POST("/password/forgot", limitForgot, h.ForgotPassword)
+		authPublic.POST("/password/reset", h.ResetPassword)
+		authPublic.POST("/invitations/accept", h.AcceptInvitation)
+		authPublic.GET("/oidc/login", h.OIDCLogin)
+		authPublic.GET("/oidc/callback", h.OIDCCallback)
 	}
 
 	// Rotas protegidas
 	api := router.Group("/api/v1")
-	api.Use(middleware.AuthRequired(cfg.JWTSecret))
+	api.Use(middleware.AuthRequired(keys.Keyfunc, middleware.CheckSessionInDB(db), middleware.AuthenticateAPIKeyInDB(db)))
+	loadUser := middleware.LoadUserFromDB(db)
 	{
 		// Auth protegido
 		authProtected := api.Group("/auth")
 		{
 			authProtected.GET("/me", h.Me)
+			authProtected.PUT("/me/password", h.ChangePassword)
+			authProtected.POST("/logout", h.Logout)
+			authProtected.GET("/sessions", h.GetSessions)
+			authProtected.DELETE("/sessions/:id", h.RevokeSession)
+			authProtected.POST("/store", h.SelectStore)
+			authProtected.POST("/2fa/enroll", h.EnrollTwoFactor)
+			authProtected.POST("/2fa/verify", h.VerifyTwoFactor)
+			authProtected.POST("/2fa/disable", h.DisableTwoFactor)
+			authProtected.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
 		}
+
+		// Demais módulos exigem 2FA ativo quando obrigatório para o perfil
+		modules := api.Group("")
+		modules.Use(middleware.RequireTwoFactorSetup(loadUser, cfg.TwoFactorMandatory))
+		modules.Use(middleware.StoreScope(loadUser, middleware.StoresFromDB(db)))
+
+		// Cada grupo exige acesso ao módulo e cada rota a capacidade específica
+		requireCap := func(capability string) gin.HandlerFunc {
+			return middleware.RequireCapability(loadUser, capability)
+		}
+
 		// Produtos
-		products := api.Group("/products")
+		products := modules.Group("/products")
+		products.Use(middleware.RequirePermission(loadUser, models.ModuleProducts))
+		{
+			products.GET("", requireCap(models.CapProductsRead), h.GetProducts)
+			products.POST("", requireCap(models.CapProductsWrite), h.CreateProduct)
+			products.GET("/search", requireCap(models.CapProductsRead), h.SearchProducts)
+			products.GET("/autocomplete", requireCap(models.CapProductsRead), h.AutocompleteProducts)
+			products.GET("/export", requireCap(models.CapProductsRead), h.ExportProducts)
+			products.POST("/import", requireCap(models.CapProductsWrite), h.ImportProducts)
+			products.POST("/group-styles", middleware.RequireAdmin(loadUser), h.GroupProductStyles)
+			products.GET("/by-barcode/:code", requireCap(models.CapProductsRead), h.GetProductByBarcode)
+			products.POST("/labels", requireCap(models.CapProductsRead), h.PrintLabels)
+			products.GET("/:id", requireCap(models.CapProductsRead), h.GetProduct)
+			products.PUT("/:id", requireCap(models.CapProductsWrite), h.UpdateProduct)
+			products.DELETE("/:id", requireCap(models.CapProductsDelete), h.DeleteProduct)
+			products.POST("/:id/variants", requireCap(models.CapProductsWrite), h.CreateVariants)
+			products.PUT("/:id/components", requireCap(models.CapProductsWrite), h.UpdateBundleComponents)
+			products.POST("/:id/barcode", requireCap(models.CapProductsWrite), h.GenerateBarcode)
+			products.GET("/:id/price-history", requireCap(models.CapProductsRead), h.GetPriceHistory)
+			products.GET("/:id/scheduled-prices", requireCap(models.CapProductsRead), h.GetScheduledPrices)
+			products.POST("/:id/scheduled-prices", requireCap(models.CapProductsPrice), h.CreateScheduledPrice)
+			products.DELETE("/:id/scheduled-prices/:schedule_id", requireCap(models.CapProductsPrice), h.CancelScheduledPrice)
+			products.GET("/:id/images", requireCap(models.CapProductsRead), h.GetProductImages)
+			products.POST("/:id/images", requireCap(models.CapProductsWrite), h.UploadProductImages)
+			products.PUT("/:id/images", requireCap(models.CapProductsWrite), h.ReorderProductImages)
+			products.PUT("/:id/images/:image_id/primary", requireCap(models.CapProductsWrite), h.SetPrimaryProductImage)
+			products.DELETE("/:id/images/:image_id", requireCap(models.CapProductsWrite), h.DeleteProductImage)
+		}
+
+		// Categorias de produto
+		categories := modules.Group("/categories")
+		categories.Use(middleware.RequirePermission(loadUser, models.ModuleProducts))
 		{
-			products.GET("", h.GetProducts)
-			products.POST("", h.CreateProduct)
-			products.GET("/:id", h.GetProduct)
-			products.PUT("/:id", h.UpdateProduct)
-			products.DELETE("/:id", h.DeleteProduct)
+			categories.GET("", requireCap(models.CapProductsRead), h.GetCategories)
+			categories.POST("", requireCap(models.CapProductsWrite), h.CreateCategory)
+			categories.GET("/:id", requireCap(models.CapProductsRead), h.GetCategory)
+			categories.PUT("/:id", requireCap(models.CapProductsWrite), h.UpdateCategory)
+			categories.DELETE("/:id", requireCap(models.CapProductsDelete), h.DeleteCategory)
 		}
 
 		// Clientes
-		customers := api.Group("/customers")
+		customers := modules.Group("/customers")
+		customers.Use(middleware.RequirePermission(loadUser, models.ModuleCustomers))
 		{
-			customers.GET("", h.GetCustomers)
-			customers.POST("", h.CreateCustomer)
-			customers.GET("/:id", h.GetCustomer)
-			customers.PUT("/:id", h.UpdateCustomer)
-			customers.DELETE("/:id", h.DeleteCustomer)
+			customers.GET("", requireCap(models.CapCustomersRead), h.GetCustomers)
+			customers.POST("", requireCap(models.CapCustomersWrite), h.CreateCustomer)
+			customers.GET("/:id", requireCap(models.CapCustomersRead), h.GetCustomer)
+			customers.PUT("/:id", requireCap(models.CapCustomersWrite), h.UpdateCustomer)
+			customers.DELETE("/:id", requireCap(models.CapCustomersDelete), h.DeleteCustomer)
 		}
 
 		// Vendas
-		sales := api.Group("/sales")
+		sales := modules.Group("/sales")
+		sales.Use(middleware.RequirePermission(loadUser, models.ModuleSales))
 		{
-			sales.GET("", h.GetSales)
-			sales.POST("", h.CreateSale)
-			sales.GET("/:id", h.GetSale)
-			sales.PUT("/:id", h.UpdateSale)
+			sales.GET("", requireCap(models.CapSalesRead), h.GetSales)
+			sales.POST("", requireCap(models.CapSalesWrite), h.CreateSale)
+			sales.POST("/quote", requireCap(models.CapSalesWrite), h.QuoteSale)
+			sales.GET("/:id", requireCap(models.CapSalesRead), h.GetSale)
+			sales.PUT("/:id", requireCap(models.CapSalesWrite), h.UpdateSale)
+		}
+
+		// Promoções aplicadas automaticamente nas vendas
+		promotions := modules.Group("/promotions")
+		promotions.Use(middleware.RequirePermission(loadUser, models.ModuleSales))
+		{
+			promotions.GET("", requireCap(models.CapSalesRead), h.GetPromotions)
+			promotions.POST("", requireCap(models.CapSalesPromotions), h.CreatePromotion)
+			promotions.PUT("/:id", requireCap(models.CapSalesPromotions), h.UpdatePromotion)
+			promotions.DELETE("/:id", requireCap(models.CapSalesPromotions), h.DeletePromotion)
 		}
 
 		// Estoque
-		inventory := api.Group("/inventory")
+		inventory := modules.Group("/inventory")
+		inventory.Use(middleware.RequirePermission(loadUser, models.ModuleInventory))
 		{
-			inventory.GET("", h.GetInventory)
-			inventory.POST("/adjust", h.AdjustInventory)
-			inventory.GET("/movements/:product_id", h.GetInventoryMovements)
+			inventory.GET("", requireCap(models.CapInventoryRead), h.GetInventory)
+			inventory.POST("/adjust", requireCap(models.CapInventoryAdjust), h.AdjustInventory)
+			inventory.GET("/movements/:product_id", requireCap(models.CapInventoryRead), h.GetInventoryMovements)
 		}
 
 		// Relatórios
-		reports := api.Group("/reports")
+		reports := modules.Group("/reports")
+		reports.Use(middleware.RequirePermission(loadUser, models.ModuleReports))
 		{
-			reports.GET("/sales", h.GetSalesReport)
+			reports.GET("/sales", requireCap(models.CapReportsRead), h.GetSalesReport)
 		}
 
 		// Usuários
-		users := api.Group("/users")
+		users := modules.Group("/users")
+		users.Use(middleware.RequirePermission(loadUser, models.ModuleUsers))
 		{
-			users.GET("", h.GetUsers)
-			users.POST("", h.CreateUser)
-			users.PUT("/:id", h.UpdateUser)
-			users.DELETE("/:id", h.DeleteUser)
+			users.GET("", requireCap(models.CapUsersRead), h.GetUsers)
+			users.POST("", requireCap(models.CapUsersWrite), h.CreateUser)
+			users.PUT("/:id", requireCap(models.CapUsersWrite), h.UpdateUser)
+			users.DELETE("/:id", requireCap(models.CapUsersDelete), h.DeleteUser)
+			users.POST("/:id/unlock", requireCap(models.CapUsersWrite), h.UnlockUser)
+			users.PUT("/:id/stores", requireCap(models.CapUsersWrite), h.SetUserStores)
+		}
+
+		// Convites
+		invitations := modules.Group("/invitations")
+		invitations.Use(middleware.RequirePermission(loadUser, models.ModuleUsers))
+		{
+			invitations.GET("", requireCap(models.CapUsersRead), h.GetInvitations)
+			invitations.POST("", requireCap(models.CapUsersWrite), h.CreateInvitation)
+			invitations.DELETE("/:id", requireCap(models.CapUsersWrite), h.DeleteInvitation)
+		}
+
+		// Chaves de API para integrações
+		apiKeys := modules.Group("/api-keys")
+		apiKeys.Use(middleware.RequirePermission(loadUser, models.ModuleUsers))
+		{
+			apiKeys.GET("", requireCap(models.CapUsersRead), h.GetAPIKeys)
+			apiKeys.POST("", requireCap(models.CapUsersWrite), h.CreateAPIKey)
+			apiKeys.DELETE("/:id", requireCap(models.CapUsersWrite), h.RevokeAPIKey)
+		}
+
+		// Comissões: cada vendedor consulta o próprio extrato
+		commissions := modules.Group("/commissions")
+		{
+			commissions.GET("/statement", h.GetCommissionStatement)
+			commissions.GET("/periods", requireCap(models.CapReportsCommissions), h.GetCommissionPeriods)
+			commissions.POST("/periods", requireCap(models.CapReportsCommissionsManage), h.CloseCommissionPeriod)
+			commissions.GET("/rules", requireCap(models.CapReportsCommissions), h.GetCommissionRules)
+			commissions.POST("/rules", requireCap(models.CapReportsCommissionsManage), h.CreateCommissionRule)
+			commissions.PUT("/rules/:id", requireCap(models.CapReportsCommissionsManage), h.UpdateCommissionRule)
+			commissions.DELETE("/rules/:id", requireCap(models.CapReportsCommissionsManage), h.DeleteCommissionRule)
+		}
+
+		// Metas: o progresso fica visível a cada vendedor no dashboard
+		goals := modules.Group("/goals")
+		{
+			goals.GET("/progress", h.GetGoalProgress)
+			goals.GET("", requireCap(models.CapReportsGoals), h.GetGoals)
+			goals.POST("", requireCap(models.CapReportsGoals), h.CreateGoal)
+			goals.PUT("/:id", requireCap(models.CapReportsGoals), h.UpdateGoal)
+			goals.DELETE("/:id", requireCap(models.CapReportsGoals), h.DeleteGoal)
+		}
+
+		// Lojas
+		stores := modules.Group("/stores")
+		{
+			stores.GET("", h.GetStores)
+			stores.POST("", middleware.RequireAdmin(loadUser), h.CreateStore)
+			stores.PUT("/:id", middleware.RequireAdmin(loadUser), h.UpdateStore)
+		}
+
+		// Auditoria
+		auditLogs := modules.Group("/audit")
+		auditLogs.Use(middleware.RequireAdmin(loadUser))
+		{
+			auditLogs.GET("", h.GetAuditLogs)
+		}
+
+		// Chaves de assinatura dos tokens
+		signingKeys := modules.Group("/signing-keys")
+		signingKeys.Use(middleware.RequireAdmin(loadUser))
+		{
+			signingKeys.POST("/rotate", h.RotateSigningKey)
 		}
 	}
 
@@ -97,7 +255,7 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	router.GET("/", h.Dashboard)
 	router.GET("/login", h.LoginPage)
 	router.GET("/dashboard", h.Dashboard)
-	router.GET("/dashboard", h.Dashboard)
+	router.GET(handlers.ResetPasswordPath, h.ResetPasswordPage)
 
 	return router
 }
@@ -0,0 +1,145 @@
+// Package audit calcula as diferenças registradas na trilha de auditoria
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change guarda o valor de um campo antes e depois de uma alteração
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Campos que mudam em toda gravação e não interessam à auditoria
+var ignoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Redacted substitui o valor dos campos secretos na trilha de auditoria
+const Redacted = "[redigido]"
+
+// Campos secretos: a auditoria registra que mudaram, mas não o valor
+var redactedFields = map[string]bool{
+	"password":           true,
+	"password_hash":      true,
+	"two_factor_secret":  true,
+	"recovery_codes":     true,
+	"token_hash":         true,
+	"refresh_token_hash": true,
+	"key_hash":           true,
+}
+
+// Diff compara duas entidades pelo seu formato JSON e retorna os campos
+// alterados. Passe nil em before para criações e em after para exclusões.
+// Objetos aninhados sem "id" (como as permissões) são achatados com ponto;
+// listas de valores simples (como as capacidades) são comparadas como
+// conjuntos, sem considerar a ordem, e relacionamentos são ignorados. Os
+// valores dos campos secretos, como senhas, aparecem como Redacted.
+func Diff(before, after interface{}) (map[string]Change, error) {
+	beforeFields, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for key, value := range beforeFields {
+		if next, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, next) {
+			changes[key] = Change{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = Change{After: value}
+		}
+	}
+	// Campo nulo de um lado e ausente do outro não é alteração
+	for key, change := range changes {
+		if change.Before == nil && change.After == nil {
+			delete(changes, key)
+		}
+	}
+
+	for key, change := range changes {
+		if redactedFields[key[strings.LastIndex(key, ".")+1:]] {
+			changes[key] = Change{Before: redact(change.Before), After: redact(change.After)}
+		}
+	}
+	return changes, nil
+}
+
+// redact esconde o valor secreto, mantendo visível se estava vazio
+func redact(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return Redacted
+}
+
+// flatten converte a entidade em um mapa de campos escalares
+func flatten(entity interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if entity == nil || (reflect.ValueOf(entity).Kind() == reflect.Ptr && reflect.ValueOf(entity).IsNil()) {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	flattenInto(fields, "", raw)
+	return fields, nil
+}
+
+func flattenInto(fields map[string]interface{}, prefix string, raw map[string]interface{}) {
+	for key, value := range raw {
+		if prefix == "" && ignoredFields[key] {
+			continue
+		}
+		switch v := value.(type) {
+		case []interface{}:
+			if set, ok := scalarSet(v); ok {
+				fields[prefix+key] = set
+			}
+		case map[string]interface{}:
+			if _, isRelation := v["id"]; isRelation {
+				continue
+			}
+			flattenInto(fields, prefix+key+".", v)
+		default:
+			fields[prefix+key] = v
+		}
+	}
+}
+
+// scalarSet ordena uma lista de valores simples para que a ordem dos itens
+// não conte como alteração. Listas de objetos não são valores simples.
+func scalarSet(list []interface{}) ([]interface{}, bool) {
+	set := make([]interface{}, len(list))
+	for i, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, false
+		}
+		set[i] = item
+	}
+	sort.SliceStable(set, func(i, j int) bool {
+		return fmt.Sprint(set[i]) < fmt.Sprint(set[j])
+	})
+	return set, true
+}
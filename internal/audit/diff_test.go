@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"loja-online/internal/models"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type owner struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type account struct {
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"password_hash"`
+	Address      address   `json:"address"`
+	Owner        *owner    `json:"owner,omitempty"`
+	Tags         []string  `json:"tags"`
+	Items        []owner   `json:"items"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func TestDiff(t *testing.T) {
+	base := account{Name: "Loja", PasswordHash: "$2a$10$antigo", Address: address{City: "Recife", Zip: "50000"}, Tags: []string{"a", "b"}}
+
+	tests := []struct {
+		name   string
+		before interface{}
+		after  func(a account) interface{}
+		want   map[string]Change
+	}{
+		{
+			name:   "objeto aninhado achatado com ponto",
+			before: base,
+			after:  func(a account) interface{} { a.Address.City = "Olinda"; return a },
+			want:   map[string]Change{"address.city": {Before: "Recife", After: "Olinda"}},
+		},
+		{
+			name:   "campos de data de gravação ignorados",
+			before: base,
+			after:  func(a account) interface{} { a.UpdatedAt = time.Now(); return a },
+			want:   map[string]Change{},
+		},
+		{
+			name:   "relacionamento com id ignorado",
+			before: base,
+			after:  func(a account) interface{} { a.Owner = &owner{ID: 3, Name: "Ana"}; return a },
+			want:   map[string]Change{},
+		},
+		{
+			name:   "hash de senha redigido",
+			before: base,
+			after:  func(a account) interface{} { a.PasswordHash = "$2a$10$novo"; return a },
+			want:   map[string]Change{"password_hash": {Before: Redacted, After: Redacted}},
+		},
+		{
+			name:   "senha definida a partir de vazio",
+			before: map[string]interface{}{"password": ""},
+			after:  func(account) interface{} { return map[string]interface{}{"password": "$2a$10$novo"} },
+			want:   map[string]Change{"password": {Before: "", After: Redacted}},
+		},
+		{
+			name:   "lista de valores simples como conjunto",
+			before: base,
+			after:  func(a account) interface{} { a.Tags = []string{"c", "a"}; return a },
+			want:   map[string]Change{"tags": {Before: []interface{}{"a", "b"}, After: []interface{}{"a", "c"}}},
+		},
+		{
+			name:   "lista de objetos ignorada",
+			before: base,
+			after:  func(a account) interface{} { a.Items = []owner{{ID: 1}}; return a },
+			want:   map[string]Change{},
+		},
+		{
+			name:   "criação redige o segredo",
+			before: nil,
+			after:  func(account) interface{} { return map[string]interface{}{"name": "Loja", "key_hash": "abc"} },
+			want:   map[string]Change{"name": {After: "Loja"}, "key_hash": {After: Redacted}},
+		},
+	}
+
+	for _, tt := range tests {
+		changes, err := Diff(tt.before, tt.after(base))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(changes, tt.want) {
+			t.Errorf("%s: alterações = %v, esperado %v", tt.name, changes, tt.want)
+		}
+	}
+}
+
+func TestDiffCapabilitiesOnly(t *testing.T) {
+	before := models.User{ID: 1, Name: "Ana", Permissions: models.UserPermissions{
+		Products:     true,
+		Capabilities: models.Capabilities{models.CapProductsRead, models.CapProductsWrite},
+	}}
+	after := before
+	after.Permissions.Capabilities = models.Capabilities{models.CapProductsPrice, models.CapProductsRead, models.CapProductsWrite}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, ok := changes["permissions.capabilities"]
+	if len(changes) != 1 || !ok {
+		t.Fatalf("alterações = %v, esperado apenas permissions.capabilities", changes)
+	}
+	want := []interface{}{models.CapProductsPrice, models.CapProductsRead, models.CapProductsWrite}
+	if !reflect.DeepEqual(change.After, want) {
+		t.Fatalf("depois = %v, esperado %v", change.After, want)
+	}
+}
+
+func TestDiffIgnoresListOrder(t *testing.T) {
+	before := models.UserPermissions{Capabilities: models.Capabilities{models.CapSalesWrite, models.CapSalesRead}}
+	after := models.UserPermissions{Capabilities: models.Capabilities{models.CapSalesRead, models.CapSalesWrite}}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("alterações = %v, esperado nenhuma", changes)
+	}
+}
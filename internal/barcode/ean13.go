@@ -0,0 +1,120 @@
+// Package barcode valida, gera e codifica códigos de barras EAN-13.
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// InternalPrefix é o prefixo dos códigos gerados pela loja. Os prefixos GS1
+// de 200 a 299 são reservados para uso interno e nunca coincidem com códigos
+// de fornecedores.
+const InternalPrefix = "200"
+
+// MaxInternalSequence é o maior número de sequência de um código interno
+const MaxInternalSequence = 999_999_999
+
+// ErrInvalid indica um código que não é um EAN-13 válido
+var ErrInvalid = errors.New("código de barras inválido; informe um EAN-13 (13 dígitos) ou UPC-A (12 dígitos) com dígito verificador correto")
+
+// Normalize remove espaços e hífens, completa um UPC-A com zero à esquerda e
+// valida o dígito verificador
+func Normalize(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	if len(code) == 12 {
+		code = "0" + code
+	}
+	if !Valid(code) {
+		return "", ErrInvalid
+	}
+	return code, nil
+}
+
+// Valid indica se o código tem 13 dígitos e o dígito verificador correto
+func Valid(code string) bool {
+	if len(code) != 13 || !digits(code) {
+		return false
+	}
+	return CheckDigit(code[:12]) == code[12]
+}
+
+// CheckDigit calcula o dígito verificador dos 12 primeiros dígitos: pesos 1 e
+// 3 alternados, completando a soma até o próximo múltiplo de 10
+func CheckDigit(first12 string) byte {
+	sum := 0
+	for i := 0; i < 12 && i < len(first12); i++ {
+		digit := int(first12[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Internal monta o código interno de número seq: prefixo, sequência com nove
+// dígitos e dígito verificador
+func Internal(seq int64) (string, error) {
+	if seq <= 0 || seq > MaxInternalSequence {
+		return "", fmt.Errorf("sequência de código de barras fora da faixa: %d", seq)
+	}
+	first12 := fmt.Sprintf("%s%09d", InternalPrefix, seq)
+	return first12 + string(CheckDigit(first12)), nil
+}
+
+// IsInternal indica se o código foi gerado pela loja
+func IsInternal(code string) bool {
+	return len(code) == 13 && strings.HasPrefix(code, InternalPrefix)
+}
+
+// Padrões dos dígitos: conjunto A (ímpar), B (par) e C (lado direito)
+var (
+	setA = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	setB = [10]string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	setC = [10]string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+
+	// O primeiro dígito não é desenhado: ele define quais dos seis dígitos
+	// seguintes usam o conjunto A ou B
+	parity = [10]string{"AAAAAA", "AABABB", "AABBAB", "AABBBA", "ABAABB", "ABBAAB", "ABBBAA", "ABABAB", "ABABBA", "ABBABA"}
+)
+
+// Modules retorna as 95 barras do código (true = barra escura), sem as
+// margens de silêncio
+func Modules(code string) ([]bool, error) {
+	if !Valid(code) {
+		return nil, ErrInvalid
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("101")
+	sets := parity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		digit := code[i] - '0'
+		if sets[i-1] == 'A' {
+			pattern.WriteString(setA[digit])
+		} else {
+			pattern.WriteString(setB[digit])
+		}
+	}
+	pattern.WriteString("01010")
+	for i := 7; i <= 12; i++ {
+		pattern.WriteString(setC[code[i]-'0'])
+	}
+	pattern.WriteString("101")
+
+	modules := make([]bool, 0, pattern.Len())
+	for _, r := range pattern.String() {
+		modules = append(modules, r == '1')
+	}
+	return modules, nil
+}
+
+func digits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := map[string]byte{
+		"789100031550": '7',
+		"400638133393": '1',
+		"590123412345": '7',
+		"000000000000": '0',
+	}
+	for first12, want := range tests {
+		if got := CheckDigit(first12); got != want {
+			t.Errorf("CheckDigit(%s) = %c, esperado %c", first12, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"7891000315507", "7891000315507", true},
+		{" 789-1000-31550-7 ", "7891000315507", true},
+		{"036000291452", "0036000291452", true}, // UPC-A
+		{"7891000315508", "", false},
+		{"789100031550", "", false},
+		{"78910003155A7", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.input)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; esperado %q, ok=%v", tt.input, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestInternal(t *testing.T) {
+	code, err := Internal(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "2000000000428" || !Valid(code) || !IsInternal(code) {
+		t.Fatalf("Internal(42) = %s", code)
+	}
+	if IsInternal("7891000315507") {
+		t.Fatal("código de fornecedor identificado como interno")
+	}
+
+	for _, seq := range []int64{0, -1, MaxInternalSequence + 1} {
+		if _, err := Internal(seq); err == nil {
+			t.Errorf("Internal(%d) deveria falhar", seq)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	modules, err := Modules("4006381333931")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pattern strings.Builder
+	for _, dark := range modules {
+		if dark {
+			pattern.WriteByte('1')
+		} else {
+			pattern.WriteByte('0')
+		}
+	}
+
+	// Guarda inicial, 0 0 6 3 8 1 nos conjuntos A B A A B B (paridade do 4),
+	// guarda central, 3 3 3 9 3 1 e guarda final
+	want := "101" +
+		"0001101" + "0100111" + "0101111" + "0111101" + "0001001" + "0110011" +
+		"01010" +
+		"1000010" + "1000010" + "1000010" + "1110100" + "1000010" + "1100110" +
+		"101"
+	if pattern.String() != want {
+		t.Fatalf("Modules = %s\nesperado  %s", pattern.String(), want)
+	}
+
+	if _, err := Modules("4006381333932"); err == nil {
+		t.Fatal("dígito verificador incorreto deveria falhar")
+	}
+}
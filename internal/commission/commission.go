@@ -0,0 +1,195 @@
+// Package commission calcula as comissões dos vendedores a partir das regras
+// cadastradas e das vendas de um período.
+package commission
+
+import (
+	"sort"
+	"time"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+// Pesos de especificidade de cada campo de escopo da regra. A soma decide
+// qual regra vale quando mais de uma se aplica ao mesmo item.
+const (
+	weightProduct  = 32
+	weightBrand    = 16
+	weightCategory = 8
+	weightSeller   = 4
+	weightRole     = 2
+	weightStore    = 1
+)
+
+// Categories guarda, para cada categoria, os IDs dela e de seus ancestrais, da
+// raiz até ela. Uma regra de categoria vale para os produtos de qualquer
+// subcategoria.
+type Categories map[uint][]uint
+
+// Calculate gera os lançamentos de comissão das vendas informadas. As vendas
+// devem vir com SaleItems.Product e User carregados e já sem as canceladas.
+// A margem usa o custo registrado no item na data da venda.
+// O total vendido por vendedor no conjunto define a faixa de meta atingida.
+func Calculate(rules []models.CommissionRule, categories Categories, sales []models.Sale) []models.CommissionEntry {
+	revenue := make(map[uint]money.Amount)
+	for _, sale := range sales {
+		revenue[sale.UserID] += sale.FinalAmount
+	}
+
+	sorted := make([]models.CommissionRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	var entries []models.CommissionEntry
+	for _, sale := range sales {
+		var itemsTotal money.Amount
+		totals := make([]money.Amount, len(sale.SaleItems))
+		for i, item := range sale.SaleItems {
+			totals[i] = item.TotalPrice
+			itemsTotal += item.TotalPrice
+		}
+		if itemsTotal <= 0 {
+			continue
+		}
+
+		// O desconto da venda é rateado entre os itens proporcionalmente, com
+		// as partes somando exatamente o valor final
+		shares := sale.FinalAmount.Allocate(totals)
+
+		for i, item := range sale.SaleItems {
+			rule := Match(sorted, categories, &sale, &item)
+			if rule == nil {
+				continue
+			}
+
+			base := shares[i]
+			if rule.Base == models.CommissionBaseMargin {
+				base = max(base-item.UnitCost.Mul(item.Quantity), 0)
+			}
+
+			rate := rule.RateFor(revenue[sale.UserID])
+			entries = append(entries, models.CommissionEntry{
+				UserID:     sale.UserID,
+				StoreID:    sale.StoreID,
+				SaleID:     sale.ID,
+				SaleItemID: item.ID,
+				ProductID:  item.ProductID,
+				RuleID:     rule.ID,
+				Type:       models.CommissionEntrySale,
+				Base:       rule.Base,
+				BaseAmount: base,
+				Rate:       rate,
+				Amount:     base.Percent(rate),
+			})
+		}
+	}
+	return entries
+}
+
+// Match retorna a regra mais específica aplicável ao item, ou nil. Entre
+// regras de mesma especificidade, a de categoria mais próxima do produto vence.
+func Match(rules []models.CommissionRule, categories Categories, sale *models.Sale, item *models.SaleItem) *models.CommissionRule {
+	var best *models.CommissionRule
+	bestScore, bestDepth := -1, 0
+	for i := range rules {
+		score, depth, ok := matchScore(&rules[i], categories, sale, item)
+		if ok && (score > bestScore || score == bestScore && depth > bestDepth) {
+			best, bestScore, bestDepth = &rules[i], score, depth
+		}
+	}
+	return best
+}
+
+// matchScore verifica se todos os campos de escopo da regra coincidem com a
+// venda e retorna a especificidade da regra e, nas regras de categoria, a
+// profundidade da categoria da regra
+func matchScore(rule *models.CommissionRule, categories Categories, sale *models.Sale, item *models.SaleItem) (int, int, bool) {
+	if !rule.Active || !validAt(rule, sale.SaleDate) {
+		return 0, 0, false
+	}
+
+	score, depth := 0, 0
+	if rule.ProductID != nil {
+		if *rule.ProductID != item.ProductID {
+			return 0, 0, false
+		}
+		score += weightProduct
+	}
+	if rule.Brand != "" {
+		if rule.Brand != item.Product.Brand {
+			return 0, 0, false
+		}
+		score += weightBrand
+	}
+	if rule.CategoryID != nil {
+		if depth = categoryDepth(categories, *rule.CategoryID, item.Product.CategoryID); depth == 0 {
+			return 0, 0, false
+		}
+		score += weightCategory
+	}
+	if rule.UserID != nil {
+		if *rule.UserID != sale.UserID {
+			return 0, 0, false
+		}
+		score += weightSeller
+	}
+	if rule.Role != "" {
+		if rule.Role != sale.User.Role {
+			return 0, 0, false
+		}
+		score += weightRole
+	}
+	if rule.StoreID != nil {
+		if *rule.StoreID != sale.StoreID {
+			return 0, 0, false
+		}
+		score += weightStore
+	}
+	return score, depth, true
+}
+
+// categoryDepth retorna a posição da categoria da regra na cadeia de
+// ancestrais da categoria do produto, a partir de 1 na raiz, ou 0 se o
+// produto não pertence a ela
+func categoryDepth(categories Categories, ruleCategory uint, productCategory *uint) int {
+	if productCategory == nil {
+		return 0
+	}
+	chain, ok := categories[*productCategory]
+	if !ok {
+		chain = []uint{*productCategory}
+	}
+	for i, id := range chain {
+		if id == ruleCategory {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func validAt(rule *models.CommissionRule, at time.Time) bool {
+	if rule.ValidFrom != nil && at.Before(*rule.ValidFrom) {
+		return false
+	}
+	if rule.ValidTo != nil && !at.Before(*rule.ValidTo) {
+		return false
+	}
+	return true
+}
+
+// Deductions estorna os lançamentos de vendas já fechadas que foram
+// canceladas ou devolvidas depois do fechamento
+func Deductions(closed []models.CommissionEntry) []models.CommissionEntry {
+	deductions := make([]models.CommissionEntry, 0, len(closed))
+	for _, entry := range closed {
+		deduction := entry
+		deduction.ID = 0
+		deduction.PeriodID = nil
+		deduction.CreatedAt = time.Time{}
+		deduction.Type = models.CommissionEntryDeduction
+		deduction.BaseAmount = -entry.BaseAmount
+		deduction.Amount = -entry.Amount
+		deductions = append(deductions, deduction)
+	}
+	return deductions
+}
@@ -0,0 +1,199 @@
+package commission
+
+import (
+	"testing"
+	"time"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+// Árvore de categorias dos testes: Calça > Jeans, e Camiseta na raiz
+const (
+	camiseta uint = 1
+	calca    uint = 2
+	jeans    uint = 3
+)
+
+var categories = Categories{
+	camiseta: {camiseta},
+	calca:    {calca},
+	jeans:    {calca, jeans},
+}
+
+func sale(id, seller uint, role string, final float64, items ...models.SaleItem) models.Sale {
+	return models.Sale{
+		ID:          id,
+		UserID:      seller,
+		StoreID:     1,
+		FinalAmount: money.FromFloat(final),
+		SaleDate:    time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		User:        models.User{ID: seller, Role: role},
+		SaleItems:   items,
+	}
+}
+
+func item(id, productID, categoryID uint, brand string, quantity int, total, cost float64) models.SaleItem {
+	return models.SaleItem{
+		ID:         id,
+		ProductID:  productID,
+		Quantity:   quantity,
+		TotalPrice: money.FromFloat(total),
+		UnitCost:   money.FromFloat(cost),
+		Product:    models.Product{ID: productID, CategoryID: &categoryID, Brand: brand},
+	}
+}
+
+func TestMatchPrefersMostSpecificRule(t *testing.T) {
+	rules := []models.CommissionRule{
+		{ID: 1, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(2_00)},
+		{ID: 2, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(3_00), Role: "user"},
+		{ID: 3, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(4_00), UserID: uintPtr(7)},
+		{ID: 4, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(5_00), CategoryID: uintPtr(calca)},
+		{ID: 5, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(6_00), Brand: "Marca X"},
+		{ID: 6, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(7_00), ProductID: uintPtr(99)},
+		{ID: 7, Active: false, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(50_00)},
+	}
+
+	tests := []struct {
+		name   string
+		seller uint
+		item   models.SaleItem
+		want   uint
+	}{
+		{"regra geral", 1, item(1, 10, camiseta, "", 1, 100, 0), 2},
+		{"vendedor vence papel", 7, item(1, 10, camiseta, "", 1, 100, 0), 3},
+		{"categoria vence vendedor", 7, item(1, 10, calca, "", 1, 100, 0), 4},
+		{"marca vence categoria", 7, item(1, 10, calca, "Marca X", 1, 100, 0), 5},
+		{"produto vence todas", 7, item(1, 99, calca, "Marca X", 1, 100, 0), 6},
+		{"subcategoria herda a regra da categoria", 7, item(1, 10, jeans, "", 1, 100, 0), 4},
+	}
+
+	for _, tt := range tests {
+		s := sale(1, tt.seller, "user", 100, tt.item)
+		rule := Match(rules, categories, &s, &s.SaleItems[0])
+		if rule == nil || rule.ID != tt.want {
+			t.Errorf("%s: regra %v, esperada %d", tt.name, rule, tt.want)
+		}
+	}
+}
+
+func TestMatchPrefersNearestCategory(t *testing.T) {
+	rules := []models.CommissionRule{
+		{ID: 1, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(5_00), CategoryID: uintPtr(calca)},
+		{ID: 2, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(8_00), CategoryID: uintPtr(jeans)},
+	}
+
+	tests := []struct {
+		name     string
+		category uint
+		want     uint
+	}{
+		{"categoria da regra", calca, 1},
+		{"subcategoria com regra própria", jeans, 2},
+	}
+
+	for _, tt := range tests {
+		s := sale(1, 7, "user", 100, item(1, 10, tt.category, "", 1, 100, 0))
+		rule := Match(rules, categories, &s, &s.SaleItems[0])
+		if rule == nil || rule.ID != tt.want {
+			t.Errorf("%s: regra %v, esperada %d", tt.name, rule, tt.want)
+		}
+	}
+
+	s := sale(1, 7, "user", 100, item(1, 10, camiseta, "", 1, 100, 0))
+	if rule := Match(rules, categories, &s, &s.SaleItems[0]); rule != nil {
+		t.Errorf("produto de outra categoria não deveria casar com a regra %d", rule.ID)
+	}
+}
+
+func TestCalculateRevenueAndMargin(t *testing.T) {
+	rules := []models.CommissionRule{
+		{ID: 1, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(5_00)},
+		{ID: 2, Active: true, Base: models.CommissionBaseMargin, Rate: money.BasisPoints(10_00), CategoryID: uintPtr(calca)},
+	}
+
+	// Desconto de 10% rateado entre os dois itens
+	s := sale(1, 7, "user", 180,
+		item(1, 10, camiseta, "", 1, 100, 40),
+		item(2, 20, calca, "", 2, 100, 30),
+	)
+
+	entries := Calculate(rules, categories, []models.Sale{s})
+	if len(entries) != 2 {
+		t.Fatalf("esperados 2 lançamentos, obtidos %d", len(entries))
+	}
+	if entries[0].BaseAmount != money.FromCents(9000) || entries[0].Amount != money.FromCents(450) {
+		t.Errorf("comissão sobre faturamento: %+v", entries[0])
+	}
+	if entries[1].BaseAmount != money.FromCents(3000) || entries[1].Amount != money.FromCents(300) {
+		t.Errorf("comissão sobre margem: %+v", entries[1])
+	}
+}
+
+func TestCalculateMarginUsesCostAtSale(t *testing.T) {
+	rules := []models.CommissionRule{{ID: 1, Active: true, Base: models.CommissionBaseMargin, Rate: money.BasisPoints(10_00)}}
+
+	// O custo do produto subiu depois da venda; o extrato não muda
+	s := sale(1, 7, "user", 100, item(1, 10, camiseta, "", 1, 100, 40))
+	s.SaleItems[0].Product.CostPrice = money.FromCents(9000)
+
+	entries := Calculate(rules, categories, []models.Sale{s})
+	if len(entries) != 1 || entries[0].BaseAmount != money.FromCents(6000) {
+		t.Fatalf("margem deveria usar o custo da venda (R$ 40,00): %+v", entries)
+	}
+}
+
+func TestCalculateTiers(t *testing.T) {
+	rules := []models.CommissionRule{{
+		ID: 1, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(1_00),
+		Tiers: models.CommissionTiers{{MinAmount: money.FromCents(100000), Rate: money.BasisPoints(2_00)}, {MinAmount: money.FromCents(500000), Rate: money.BasisPoints(3_00)}},
+	}}
+
+	below := Calculate(rules, categories, []models.Sale{sale(1, 7, "user", 500, item(1, 10, camiseta, "", 1, 500, 0))})
+	if below[0].Rate != money.BasisPoints(1_00) {
+		t.Errorf("abaixo da primeira faixa: percentual %v, esperado 1", below[0].Rate)
+	}
+
+	sales := []models.Sale{
+		sale(1, 7, "user", 800, item(1, 10, camiseta, "", 1, 800, 0)),
+		sale(2, 7, "user", 700, item(2, 10, camiseta, "", 1, 700, 0)),
+	}
+	for _, entry := range Calculate(rules, categories, sales) {
+		if entry.Rate != money.BasisPoints(2_00) {
+			t.Errorf("meta de 1000 atingida no período: percentual %v, esperado 2", entry.Rate)
+		}
+	}
+}
+
+func TestDeductions(t *testing.T) {
+	periodID := uint(3)
+	closed := []models.CommissionEntry{{ID: 9, PeriodID: &periodID, UserID: 7, SaleID: 1, Type: models.CommissionEntrySale, BaseAmount: money.FromCents(9000), Amount: money.FromCents(450)}}
+
+	deductions := Deductions(closed)
+	if len(deductions) != 1 || deductions[0].Amount != money.FromCents(-450) || deductions[0].Type != models.CommissionEntryDeduction || deductions[0].PeriodID != nil {
+		t.Errorf("estorno inesperado: %+v", deductions)
+	}
+}
+
+func TestCalculateSharesAddUpToFinalAmount(t *testing.T) {
+	rules := []models.CommissionRule{{ID: 1, Active: true, Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(10_00)}}
+
+	// Desconto de R$ 0,01 em três itens iguais: as partes não podem perder
+	// nem criar centavos
+	s := sale(1, 7, "user", 29.99,
+		item(1, 10, camiseta, "", 1, 10, 0),
+		item(2, 11, camiseta, "", 1, 10, 0),
+		item(3, 12, camiseta, "", 1, 10, 0),
+	)
+
+	var total money.Amount
+	for _, entry := range Calculate(rules, categories, []models.Sale{s}) {
+		total += entry.BaseAmount
+	}
+	if total != s.FinalAmount {
+		t.Fatalf("bases somam %s, esperado %s", total, s.FinalAmount)
+	}
+}
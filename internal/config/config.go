@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +12,79 @@ type Config struct {
 	Environment   string
 	Port          string
 	SessionSecret string
+
+	// Cadastro público em /auth/register (desabilitado por padrão)
+	AllowPublicRegistration bool
+	// Validade dos convites de novos usuários
+	InvitationTTL time.Duration
+	// Assinatura dos tokens de acesso: "RS256" (padrão) ou "EdDSA", com rotação
+	// periódica da chave. Chaves aposentadas seguem publicadas no JWKS por
+	// JWTRetiredKeyTTL (no mínimo a validade do token de acesso).
+	JWTAlgorithm     string
+	JWTKeyRotation   time.Duration
+	JWTRetiredKeyTTL time.Duration
+	// Aceita tokens HS256 assinados com JWTSecret durante a migração
+	JWTAcceptHS256 bool
+
+	// Validade do token de acesso (JWT) e do refresh token da sessão
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Validade do link de redefinição de senha
+	PasswordResetTTL time.Duration
+
+	// Torna a autenticação em dois fatores obrigatória para admin e manager
+	TwoFactorMandatory bool
+	// Nome exibido nos aplicativos autenticadores
+	TwoFactorIssuer string
+
+	// Limitação de tentativas nas rotas públicas de autenticação
+	RateLimitStore    string // "memory" (padrão) ou "postgres"
+	RateLimitWindow   time.Duration
+	RateLimitPerIP    int
+	RateLimitPerEmail int
+	// Bloqueio temporário da conta após falhas consecutivas de login
+	LoginMaxFailures     int
+	LoginLockoutDuration time.Duration
+
+	// URL pública usada nos links enviados por email
+	AppBaseURL string
+
+	// Login único via OpenID Connect (desabilitado sem OIDC_ISSUER)
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       string // Separados por espaço
+	// Cria automaticamente o usuário no primeiro login, com o papel padrão
+	OIDCAutoProvision bool
+	OIDCDefaultRole   string
+	// Domínios de email aceitos no login único (vazio aceita qualquer um)
+	OIDCAllowedDomains string
+	// Página que recebe os tokens no fragmento da URL após o login único
+	OIDCPostLoginRedirect string
+
+	// Armazenamento das imagens: "local" (padrão) ou "s3" (AWS S3, MinIO etc.)
+	StorageDriver   string
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3AccessKey     string
+	S3SecretKey     string
+	S3PathStyle     bool // URLs endpoint/bucket/chave, exigidas pelo MinIO
+	// Tamanho máximo de cada imagem enviada, em bytes
+	ImageMaxSize int
+	// Maiores dimensões das miniaturas geradas, em pixels (ex.: "150,400,800")
+	ImageThumbnailSizes string
+
+	// Envio de emails: "log" (padrão) ou "smtp"
+	MailDriver   string
+	MailFrom     string
+	MailLogFile  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func Load() *Config {
@@ -19,6 +94,57 @@ func Load() *Config {
 		Environment:   getEnv("ENVIRONMENT", "development"),
 		Port:          getEnv("PORT", "8080"),
 		SessionSecret: getEnv("SESSION_SECRET", "minha_chave_secreta_de_sessao"),
+
+		JWTAlgorithm:     getEnv("JWT_ALGORITHM", "RS256"),
+		JWTKeyRotation:   getEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour),
+		JWTRetiredKeyTTL: getEnvDuration("JWT_RETIRED_KEY_TTL", 24*time.Hour),
+		JWTAcceptHS256:   getEnvBool("JWT_ACCEPT_HS256", true),
+
+		AllowPublicRegistration: getEnvBool("ALLOW_PUBLIC_REGISTRATION", false),
+		InvitationTTL:           getEnvDuration("INVITATION_TTL", 72*time.Hour),
+		AccessTokenTTL:          getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:         getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordResetTTL:        getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		TwoFactorMandatory:      getEnvBool("TWO_FACTOR_MANDATORY", false),
+		TwoFactorIssuer:         getEnv("TWO_FACTOR_ISSUER", "Loja Online"),
+
+		RateLimitStore:       getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitWindow:      getEnvDuration("RATE_LIMIT_WINDOW", 15*time.Minute),
+		RateLimitPerIP:       getEnvInt("RATE_LIMIT_PER_IP", 50),
+		RateLimitPerEmail:    getEnvInt("RATE_LIMIT_PER_EMAIL", 10),
+		LoginMaxFailures:     getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginLockoutDuration: getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+
+		AppBaseURL: getEnv("APP_BASE_URL", "http://localhost:8080"),
+
+		OIDCIssuer:            getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:          getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:       getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:            getEnv("OIDC_SCOPES", "openid email profile"),
+		OIDCAutoProvision:     getEnvBool("OIDC_AUTO_PROVISION", false),
+		OIDCDefaultRole:       getEnv("OIDC_DEFAULT_ROLE", "user"),
+		OIDCAllowedDomains:    getEnv("OIDC_ALLOWED_DOMAINS", ""),
+		OIDCPostLoginRedirect: getEnv("OIDC_POST_LOGIN_REDIRECT", "/login"),
+
+		StorageDriver:       getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalDir:     getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+		S3Endpoint:          getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		S3Region:            getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:            getEnv("S3_BUCKET", ""),
+		S3AccessKey:         getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:         getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:         getEnvBool("S3_PATH_STYLE", false),
+		ImageMaxSize:        getEnvInt("IMAGE_MAX_SIZE", 5<<20),
+		ImageThumbnailSizes: getEnv("IMAGE_THUMBNAIL_SIZES", "150,400,800"),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "Loja Online <nao-responda@loja-online.local>"),
+		MailLogFile:  getEnv("MAIL_LOG_FILE", ""),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "1025"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 	}
 }
 
@@ -28,3 +154,24 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -1,7 +1,10 @@
 package database
 
 import (
+	"fmt"
 	"log"
+
+	"loja-online/internal/barcode"
 	"loja-online/internal/models"
 
 	"golang.org/x/crypto/bcrypt"
@@ -20,7 +23,11 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 
 // Migrate executa as migrações do banco de dados
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := migrateMoneyColumns(db); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Product{},
 		&models.Customer{},
@@ -29,7 +36,379 @@ func Migrate(db *gorm.DB) error {
 		&models.InventoryMovement{},
 		&models.Sale{},
 		&models.SaleItem{},
-	)
+		&models.Invitation{},
+		&models.Session{},
+		&models.PasswordReset{},
+		&models.RecoveryCode{},
+		&models.APIKey{},
+		&models.AuditLog{},
+		&models.SigningKey{},
+		&models.Store{},
+		&models.CommissionRule{},
+		&models.CommissionPeriod{},
+		&models.CommissionEntry{},
+		&models.Goal{},
+		&models.ProductImage{},
+		&models.PriceHistory{},
+		&models.ScheduledPrice{},
+		&models.Promotion{},
+		&models.Category{},
+		&models.BundleComponent{},
+		&models.SaleItemComponent{},
+	); err != nil {
+		return err
+	}
+
+	if err := migrateRatesAndUnits(db); err != nil {
+		return err
+	}
+	if err := migrateCapabilities(db); err != nil {
+		return err
+	}
+	if err := migrateStores(db); err != nil {
+		return err
+	}
+	if err := migratePriceHistory(db); err != nil {
+		return err
+	}
+	if err := migrateBarcodeSequence(db); err != nil {
+		return err
+	}
+	if err := migrateCategories(db); err != nil {
+		return err
+	}
+	if err := migrateCommissionCategories(db); err != nil {
+		return err
+	}
+	if err := migrateSaleItemCosts(db); err != nil {
+		return err
+	}
+	return migrateProductSearch(db)
+}
+
+// moneyColumns são as colunas de valores em reais, gravadas em numeric(12,2)
+var moneyColumns = []struct{ table, column string }{
+	{"products", "price"},
+	{"products", "cost_price"},
+	{"price_histories", "price"},
+	{"price_histories", "cost_price"},
+	{"price_histories", "previous_price"},
+	{"price_histories", "previous_cost_price"},
+	{"scheduled_prices", "price"},
+	{"scheduled_prices", "cost_price"},
+	{"sales", "total_amount"},
+	{"sales", "promotion_discount"},
+	{"sales", "discount"},
+	{"sales", "final_amount"},
+	{"sale_items", "unit_price"},
+	{"sale_items", "discount"},
+	{"sale_items", "total_price"},
+	{"sale_items", "unit_cost"},
+	{"promotions", "value"},
+	{"goals", "target"},
+	{"commission_periods", "total_amount"},
+	{"commission_entries", "base_amount"},
+	{"commission_entries", "amount"},
+}
+
+// rateColumns são as colunas de percentuais, gravadas em numeric(5,2)
+var rateColumns = []struct{ table, column string }{
+	{"commission_rules", "rate"},
+	{"commission_entries", "rate"},
+}
+
+// migrateMoneyColumns converte para numeric as colunas de valores e de
+// percentuais criadas em double precision, arredondando cada valor para duas
+// casas. Roda antes do AutoMigrate, que mudaria o tipo sem o arredondamento
+// explícito.
+func migrateMoneyColumns(db *gorm.DB) error {
+	var floats []struct{ TableName, ColumnName string }
+	if err := db.Raw(`SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND data_type = 'double precision'`).Scan(&floats).Error; err != nil {
+		return err
+	}
+	pending := make(map[string]bool, len(floats))
+	for _, column := range floats {
+		pending[column.TableName+"."+column.ColumnName] = true
+	}
+
+	converted := 0
+	convert := func(columns []struct{ table, column string }, sqlType string) error {
+		for _, column := range columns {
+			if !pending[column.table+"."+column.column] {
+				continue
+			}
+			if err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s TYPE %s USING ROUND(%s::numeric, 2)`,
+				column.table, column.column, sqlType, column.column)).Error; err != nil {
+				return err
+			}
+			converted++
+		}
+		return nil
+	}
+	if err := convert(moneyColumns, "numeric(12,2)"); err != nil {
+		return err
+	}
+	if err := convert(rateColumns, "numeric(5,2)"); err != nil {
+		return err
+	}
+	if converted > 0 {
+		log.Printf("%d colunas de valores e percentuais convertidas para numeric", converted)
+	}
+	return nil
+}
+
+// migrateRatesAndUnits move para as novas colunas os percentuais das
+// promoções e as metas em peças, que antes ficavam nas colunas de valores em
+// reais. Só afeta linhas com a coluna nova ainda zerada.
+func migrateRatesAndUnits(db *gorm.DB) error {
+	promotions := db.Exec(`UPDATE promotions SET percent = value, value = 0
+		WHERE type = ? AND percent = 0 AND value <> 0`, models.PromotionPercentage)
+	if promotions.Error != nil {
+		return promotions.Error
+	}
+	goals := db.Exec(`UPDATE goals SET target_units = ROUND(target), target = 0
+		WHERE metric = ? AND target_units = 0`, models.GoalMetricUnits)
+	if goals.Error != nil {
+		return goals.Error
+	}
+	if promotions.RowsAffected+goals.RowsAffected > 0 {
+		log.Printf("%d promoções percentuais e %d metas de unidades migradas para as novas colunas",
+			promotions.RowsAffected, goals.RowsAffected)
+	}
+	return nil
+}
+
+// migrateCategories cria uma categoria raiz para cada nome de categoria dos
+// produtos ainda sem category_id e vincula os produtos a ela. Nomes que
+// diferem só em maiúsculas, acentos ou espaços viram a mesma categoria.
+func migrateCategories(db *gorm.DB) error {
+	var names []string
+	if err := db.Unscoped().Model(&models.Product{}).
+		Where("category_id IS NULL AND TRIM(category) <> ''").
+		Distinct().Order("TRIM(category)").Pluck("TRIM(category)", &names).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var existing []models.Category
+	if err := db.Find(&existing).Error; err != nil {
+		return err
+	}
+	bySlug := make(map[string]models.Category, len(existing))
+	for _, category := range existing {
+		bySlug[category.Slug] = category
+	}
+
+	linked := 0
+	for _, name := range names {
+		slug := models.Slugify(name)
+		if slug == "" {
+			continue
+		}
+		category, ok := bySlug[slug]
+		if !ok {
+			category = models.Category{Name: name, Slug: slug, Active: true}
+			if err := db.Create(&category).Error; err != nil {
+				return err
+			}
+			category.SetParent(nil)
+			if err := db.Model(&category).Updates(map[string]interface{}{"path": category.Path, "depth": category.Depth}).Error; err != nil {
+				return err
+			}
+			bySlug[slug] = category
+		}
+
+		result := db.Unscoped().Model(&models.Product{}).
+			Where("category_id IS NULL AND TRIM(category) = ?", name).
+			Updates(map[string]interface{}{"category_id": category.ID, "category": category.Name})
+		if result.Error != nil {
+			return result.Error
+		}
+		linked += int(result.RowsAffected)
+	}
+
+	log.Printf("%d produtos vinculados às categorias cadastradas", linked)
+	return nil
+}
+
+// migrateCommissionCategories vincula as regras de comissão com categoria só
+// pelo nome à categoria cadastrada de mesmo nome. Sem uma única categoria
+// correspondente, a regra é desativada, já que sem o vínculo valeria para
+// todos os produtos.
+func migrateCommissionCategories(db *gorm.DB) error {
+	var rules []models.CommissionRule
+	if err := db.Where("category_id IS NULL AND TRIM(category) <> ''").Find(&rules).Error; err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var categories []models.Category
+	if err := db.Find(&categories).Error; err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		slug := models.Slugify(rule.Category)
+		var matches []models.Category
+		for _, category := range categories {
+			if category.Slug == slug || models.Slugify(category.Name) == slug {
+				matches = append(matches, category)
+			}
+		}
+
+		if len(matches) != 1 {
+			log.Printf("Aviso: Regra de comissão %d desativada: categoria %q não encontrada ou ambígua", rule.ID, rule.Category)
+			if err := db.Model(&rule).Update("active", false).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := db.Model(&rule).Update("category_id", matches[0].ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSaleItemCosts registra nos itens de vendas anteriores ao custo
+// unitário o custo atual do produto, a melhor estimativa disponível
+func migrateSaleItemCosts(db *gorm.DB) error {
+	result := db.Exec(`UPDATE sale_items SET unit_cost = COALESCE(products.cost_price, 0)
+		FROM products WHERE products.id = sale_items.product_id AND sale_items.unit_cost IS NULL`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("%d itens de venda com o custo atual do produto registrado", result.RowsAffected)
+	}
+	return nil
+}
+
+// migrateBarcodeSequence cria a sequência dos códigos de barras internos e a
+// posiciona após o maior código interno já cadastrado
+func migrateBarcodeSequence(db *gorm.DB) error {
+	if err := db.Exec(fmt.Sprintf(`CREATE SEQUENCE IF NOT EXISTS product_barcode_seq MINVALUE 1 MAXVALUE %d`,
+		barcode.MaxInternalSequence)).Error; err != nil {
+		return err
+	}
+	return db.Exec(`SELECT setval('product_barcode_seq', s.max)
+		FROM (SELECT MAX(SUBSTRING(barcode FROM 4 FOR 9)::bigint) AS max FROM products WHERE barcode ~ ?) s
+		WHERE s.max > (SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM product_barcode_seq)`,
+		"^"+barcode.InternalPrefix+"[0-9]{10}$").Error
+}
+
+// migratePriceHistory registra o preço e o custo atuais dos produtos ainda
+// sem histórico, como vigentes desde o cadastro
+func migratePriceHistory(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO price_histories (product_id, price, cost_price, source, changed_at)
+		SELECT p.id, p.price, COALESCE(p.cost_price, 0), ?, p.created_at
+		FROM products p
+		WHERE NOT EXISTS (SELECT 1 FROM price_histories h WHERE h.product_id = p.id)`,
+		models.PriceSourceInitial).Error
+}
+
+// permissionRow lê as permissões de qualquer tabela que as incorpore
+type permissionRow struct {
+	ID          uint
+	Permissions models.UserPermissions `gorm:"embedded"`
+}
+
+// migrateCapabilities converte os flags de módulo em capacidades nos
+// registros criados antes da existência das capacidades
+func migrateCapabilities(db *gorm.DB) error {
+	for _, table := range []string{"users", "invitations", "api_keys"} {
+		var rows []permissionRow
+		if err := db.Table(table).Where("capabilities IS NULL").Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if err := row.Permissions.Normalize(); err != nil {
+				return err
+			}
+			if err := db.Table(table).Where("id = ?", row.ID).Update("capabilities", row.Permissions.Capabilities).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(rows) > 0 {
+			log.Printf("Permissões de %d registros em %s convertidas para capacidades", len(rows), table)
+		}
+	}
+	return nil
+}
+
+// migrateStores cria a loja principal na primeira execução com lojas e
+// atribui a ela o estoque, os movimentos, as vendas e os usuários existentes
+func migrateStores(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Store{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		store := models.Store{Name: "Loja Principal", Code: "principal", Active: true}
+		if err := tx.Create(&store).Error; err != nil {
+			return err
+		}
+
+		for _, table := range []string{"inventory_items", "inventory_movements", "sales"} {
+			if err := tx.Table(table).Where("store_id IS NULL OR store_id = 0").Update("store_id", store.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec(
+			"INSERT INTO user_stores (user_id, store_id) SELECT id, ? FROM users WHERE deleted_at IS NULL AND role <> ?",
+			store.ID, "admin",
+		).Error; err != nil {
+			return err
+		}
+
+		log.Printf("Loja principal criada e dados existentes atribuídos a ela")
+		return nil
+	})
+}
+
+// productSearchStatements criam a busca textual de produtos: o vetor em
+// português, sem acentos, com pesos por campo (A: nome e SKU, B: categoria e
+// marca, C: material, D: descrição) e o índice de trigramas do nome para
+// tolerar erros de digitação. unaccent não é IMMUTABLE e por isso é envolvida
+// em immutable_unaccent para poder ser usada na coluna gerada e nos índices.
+var productSearchStatements = []string{
+	`CREATE EXTENSION IF NOT EXISTS unaccent`,
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text
+		AS $$ SELECT public.unaccent('public.unaccent', $1) $$
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(name, ''))), 'A') ||
+			setweight(to_tsvector('simple', coalesce(sku, '')), 'A') ||
+			setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(category, '') || ' ' || coalesce(brand, ''))), 'B') ||
+			setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(material, ''))), 'C') ||
+			setweight(to_tsvector('portuguese', immutable_unaccent(coalesce(description, ''))), 'D')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (immutable_unaccent(lower(name)) gin_trgm_ops)`,
+}
+
+// migrateProductSearch cria as extensões, a coluna e os índices da busca de produtos
+func migrateProductSearch(db *gorm.DB) error {
+	for _, statement := range productSearchStatements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // CreateDefaultAdmin cria o usuário admin padrão se não existir
@@ -67,6 +446,9 @@ func CreateDefaultAdmin(db *gorm.DB) error {
 			Users:     true,
 		},
 	}
+	if err := admin.Permissions.Normalize(); err != nil {
+		return err
+	}
 
 	if err := db.Create(&admin).Error; err != nil {
 		return err
@@ -0,0 +1,284 @@
+// Package dbtest abre um *gorm.DB sobre um banco falso, para testar handlers
+// e serviços sem um PostgreSQL. O SQL gerado pelo GORM não é interpretado:
+// cada comando é registrado e respondido pela primeira regra cujo trecho
+// aparece no SQL.
+//
+// Sem regra correspondente, um SELECT não retorna linhas, um INSERT com
+// RETURNING retorna IDs sequenciais para os registros sem ID e os demais
+// comandos afetam uma linha.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Query é um comando recebido pelo banco falso
+type Query struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// Rule define a resposta aos comandos que contêm um trecho de SQL
+type Rule struct {
+	fragment string
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+	once     bool
+	used     bool
+}
+
+// DB é o banco falso: guarda as regras e os comandos recebidos
+type DB struct {
+	mu      sync.Mutex
+	rules   []*Rule
+	queries []Query
+	nextID  int64
+}
+
+// Open cria o banco falso e o *gorm.DB que o utiliza
+func Open(t testing.TB) (*gorm.DB, *DB) {
+	t.Helper()
+
+	fake := &DB{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fake)}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+// On registra uma regra para os comandos que contêm fragment. Sem Rows,
+// Affect ou Fail, a regra responde como um comando sem regra.
+func (db *DB) On(fragment string) *Rule {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	rule := &Rule{fragment: fragment, affected: 1}
+	db.rules = append(db.rules, rule)
+	return rule
+}
+
+// Rows define as linhas retornadas. Os valores passam pela mesma conversão
+// dos argumentos, então tipos com driver.Valuer podem ser usados.
+func (r *Rule) Rows(columns []string, rows ...[]interface{}) *Rule {
+	r.columns = columns
+	r.rows = make([][]driver.Value, len(rows))
+	for i, row := range rows {
+		r.rows[i] = make([]driver.Value, len(row))
+		for j, value := range row {
+			converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+			if err != nil {
+				panic(fmt.Sprintf("dbtest: valor %v da coluna %s: %v", value, columns[j], err))
+			}
+			r.rows[i][j] = converted
+		}
+	}
+	return r
+}
+
+// Affect define quantas linhas o comando afeta
+func (r *Rule) Affect(n int64) *Rule {
+	r.affected = n
+	return r
+}
+
+// Fail faz o comando retornar err
+func (r *Rule) Fail(err error) *Rule {
+	r.err = err
+	return r
+}
+
+// Once faz a regra responder a um único comando; as seguintes seguem para
+// as próximas regras
+func (r *Rule) Once() *Rule {
+	r.once = true
+	return r
+}
+
+// Queries retorna os comandos recebidos que contêm fragment, na ordem
+func (db *DB) Queries(fragment string) []Query {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var found []Query
+	for _, query := range db.queries {
+		if strings.Contains(query.SQL, fragment) {
+			found = append(found, query)
+		}
+	}
+	return found
+}
+
+// Executed indica se algum comando recebido contém fragment
+func (db *DB) Executed(fragment string) bool {
+	return len(db.Queries(fragment)) > 0
+}
+
+// respond registra o comando e escolhe a regra que o responde
+func (db *DB) respond(query string, args []driver.NamedValue) *Rule {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	db.queries = append(db.queries, Query{SQL: query, Args: values})
+
+	for _, rule := range db.rules {
+		if rule.used || !strings.Contains(query, rule.fragment) {
+			continue
+		}
+		if rule.once {
+			rule.used = true
+		}
+		if rule.columns == nil && rule.err == nil && isInsertReturning(query) {
+			return db.insertedIDs(query)
+		}
+		return rule
+	}
+
+	if isInsertReturning(query) {
+		return db.insertedIDs(query)
+	}
+	return &Rule{affected: 1}
+}
+
+// insertedIDs responde a um INSERT ... RETURNING com um ID novo para cada
+// linha inserida. Registros que já têm ID, como as associações que o GORM
+// grava junto, mantêm o seu.
+func (db *DB) insertedIDs(query string) *Rule {
+	rule := &Rule{columns: []string{"id"}}
+	columns, _, _ := strings.Cut(query, " VALUES ")
+	if strings.Contains(columns, `"id"`) {
+		return rule
+	}
+	for range strings.Count(query, "),(") + 1 {
+		db.nextID++
+		rule.rows = append(rule.rows, []driver.Value{db.nextID})
+	}
+	rule.affected = int64(len(rule.rows))
+	return rule
+}
+
+func isInsertReturning(query string) bool {
+	return strings.HasPrefix(query, "INSERT") && strings.Contains(query, `RETURNING "id"`)
+}
+
+// Connect implementa driver.Connector
+func (db *DB) Connect(context.Context) (driver.Conn, error) {
+	return &conn{db: db}, nil
+}
+
+// Driver implementa driver.Connector
+func (db *DB) Driver() driver.Driver {
+	return fakeDriver{db}
+}
+
+type fakeDriver struct{ db *DB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &conn{db: d.db}, nil
+}
+
+type conn struct{ db *DB }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.respond("BEGIN", nil)
+	return tx{c.db}, nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rule := c.db.respond(query, args)
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return &rows{columns: rule.columns, values: rule.rows}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rule := c.db.respond(query, args)
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return driver.RowsAffected(rule.affected), nil
+}
+
+type tx struct{ db *DB }
+
+func (t tx) Commit() error {
+	t.db.respond("COMMIT", nil)
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.db.respond("ROLLBACK", nil)
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
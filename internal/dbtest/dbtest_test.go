@@ -0,0 +1,61 @@
+package dbtest
+
+import (
+	"errors"
+	"testing"
+)
+
+type item struct {
+	ID   uint
+	Name string
+}
+
+func TestRules(t *testing.T) {
+	db, fake := Open(t)
+	// A primeira regra correspondente responde: a do DELETE vem antes da
+	// mais genérica
+	fake.On(`DELETE FROM "items"`).Fail(errors.New("falha"))
+	fake.On(`FROM "items"`).Once().Rows([]string{"id", "name"}, []interface{}{1, "Camiseta"})
+	fake.On(`FROM "items"`).Rows([]string{"id", "name"}, []interface{}{2, "Calça"})
+
+	var first, second item
+	if err := db.First(&first).Error; err != nil || first.Name != "Camiseta" {
+		t.Fatalf("primeira busca = %+v, %v", first, err)
+	}
+	if err := db.First(&second).Error; err != nil || second.Name != "Calça" {
+		t.Fatalf("segunda busca = %+v, %v; Once deve passar às próximas regras", second, err)
+	}
+	if err := db.Delete(&item{ID: 1}).Error; err == nil {
+		t.Error("Fail deve retornar o erro")
+	}
+	if n := len(fake.Queries(`FROM "items"`)); n != 3 {
+		t.Errorf("%d comandos registrados, esperados 3", n)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	db, fake := Open(t)
+
+	var found item
+	if err := db.Where("name = ?", "Meia").First(&found).Error; err == nil {
+		t.Error("SELECT sem regra não deve retornar linhas")
+	}
+
+	items := []item{{Name: "Camiseta"}, {Name: "Calça"}}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if items[0].ID != 1 || items[1].ID != 2 {
+		t.Errorf("IDs = %d e %d, esperados 1 e 2", items[0].ID, items[1].ID)
+	}
+
+	result := db.Model(&item{}).Where("id = ?", 1).Update("name", "Regata")
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Errorf("UPDATE sem regra = %d linhas, %v; esperada 1", result.RowsAffected, result.Error)
+	}
+
+	query := fake.Queries(`UPDATE "items"`)[0]
+	if len(query.Args) != 2 || query.Args[0] != "Regata" || query.Args[1] != int64(1) {
+		t.Errorf("argumentos = %v", query.Args)
+	}
+}
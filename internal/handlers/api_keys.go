@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prefixo que identifica as chaves de API geradas pela aplicação
+const apiKeyPrefix = "lok_"
+
+// GetAPIKeys retorna as chaves de API
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+
+	query := h.DB.Preload("ServiceAccount").Order("created_at DESC")
+	if serviceAccountID := c.Query("service_account_id"); serviceAccountID != "" {
+		query = query.Where("service_account_id = ?", serviceAccountID)
+	}
+	if c.Query("active") == "true" {
+		query = query.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+	}
+
+	if err := query.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar chaves de API"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateAPIKey cria uma chave de API para uma conta de serviço. A chave
+// completa só é retornada nesta resposta.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var input models.APIKeyCreate
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Permissions.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data de expiração deve estar no futuro"})
+		return
+	}
+
+	var account models.User
+	if err := h.DB.First(&account, input.ServiceAccountID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conta de serviço não encontrada"})
+		return
+	}
+	if account.Role != models.RoleService {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chaves de API só podem ser vinculadas a usuários com papel \"service\""})
+		return
+	}
+	if !input.Permissions.IsSubsetOf(account.Permissions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A chave não pode ter permissões que a conta de serviço não possui"})
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar chave"})
+		return
+	}
+	key := apiKeyPrefix + token
+
+	createdByID, _ := middleware.UserIDFromContext(c)
+	apiKey := models.APIKey{
+		Name:             input.Name,
+		Prefix:           key[:len(apiKeyPrefix)+8],
+		KeyHash:          hashToken(key),
+		ServiceAccountID: account.ID,
+		Permissions:      input.Permissions,
+		ExpiresAt:        input.ExpiresAt,
+		CreatedByID:      createdByID,
+	}
+
+	if err := h.DB.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar chave de API"})
+		return
+	}
+	apiKey.ServiceAccount = account
+
+	h.recordAudit(c, auditEntityAPIKey, apiKey.ID, models.AuditActionCreate, nil, apiKey)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+// RevokeAPIKey revoga uma chave de API
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := h.DB.Where("revoked_at IS NULL").First(&apiKey, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chave de API não encontrada ou já revogada"})
+		return
+	}
+
+	before := apiKey
+	if err := h.DB.Model(&apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar chave de API"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityAPIKey, apiKey.ID, models.AuditActionUpdate, before, apiKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chave de API revogada com sucesso"})
+}
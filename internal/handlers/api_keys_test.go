@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/dbtest"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceAccount responde à busca da conta 9 com as capacidades informadas
+func serviceAccount(fake *dbtest.DB, role string, capabilities ...string) {
+	fake.On(`FROM "users"`).Rows([]string{"id", "role", "active", "capabilities"},
+		[]interface{}{9, role, true, models.Capabilities(capabilities)})
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	h, fake := newTestHandler(t)
+	serviceAccount(fake, models.RoleService, models.CapProductsRead, models.CapProductsWrite)
+
+	w := serve(h.CreateAPIKey, http.MethodPost, "/api-keys", gin.H{
+		"name":               "ERP",
+		"service_account_id": 9,
+		"permissions":        gin.H{"capabilities": []string{models.CapProductsRead}},
+	}, asUser(1, 1, "admin"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, esperado 201: %s", w.Code, w.Body.String())
+	}
+
+	key, _ := decode(t, w)["key"].(string)
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) != len(apiKeyPrefix)+64 {
+		t.Fatalf("chave = %q, esperado o prefixo %s e 32 bytes em hexadecimal", key, apiKeyPrefix)
+	}
+
+	inserts := fake.Queries(`INSERT INTO "api_keys"`)
+	if len(inserts) != 1 {
+		t.Fatalf("%d inserções de chave, esperada 1", len(inserts))
+	}
+	if !contains(inserts[0].Args, hashToken(key)) {
+		t.Error("deve ser gravado o hash da chave")
+	}
+	if contains(inserts[0].Args, key) {
+		t.Error("a chave em texto puro não deve ser gravada")
+	}
+	if !contains(inserts[0].Args, key[:len(apiKeyPrefix)+8]) {
+		t.Error("deve ser gravado o início da chave, para identificação")
+	}
+	if !fake.Executed(`INSERT INTO "audit_logs"`) {
+		t.Error("a criação deve ser registrada na auditoria")
+	}
+}
+
+func TestCreateAPIKeyRejected(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		role         string
+		capabilities []string
+		expiresAt    *time.Time
+		want         int
+	}{
+		{"capacidade que a conta não tem", models.RoleService, []string{models.CapProductsRead, models.CapProductsDelete}, nil, http.StatusBadRequest},
+		{"módulo que a conta não tem", models.RoleService, []string{models.CapSalesRead}, nil, http.StatusBadRequest},
+		{"conta que não é de serviço", "admin", []string{models.CapProductsRead}, nil, http.StatusBadRequest},
+		{"expiração no passado", models.RoleService, []string{models.CapProductsRead}, &past, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		serviceAccount(fake, tt.role, models.CapProductsRead, models.CapProductsWrite)
+
+		w := serve(h.CreateAPIKey, http.MethodPost, "/api-keys", gin.H{
+			"name":               "ERP",
+			"service_account_id": 9,
+			"permissions":        gin.H{"capabilities": tt.capabilities},
+			"expires_at":         tt.expiresAt,
+		}, asUser(1, 1, "admin"))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, esperado %d", tt.name, w.Code, tt.want)
+		}
+		if fake.Executed(`INSERT INTO "api_keys"`) {
+			t.Errorf("%s: a chave não deve ser criada", tt.name)
+		}
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		found  bool
+		status int
+	}{
+		{"chave ativa", true, http.StatusOK},
+		{"inexistente ou já revogada", false, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		if tt.found {
+			fake.On(`FROM "api_keys"`).Rows([]string{"id", "name", "service_account_id"}, []interface{}{4, "ERP", 9})
+		}
+
+		w := serve(func(c *gin.Context) {
+			c.Params = gin.Params{{Key: "id", Value: "4"}}
+			h.RevokeAPIKey(c)
+		}, http.MethodDelete, "/api-keys/4", nil, asUser(1, 1, "admin"))
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, esperado %d", tt.name, w.Code, tt.status)
+		}
+
+		lookup := fake.Queries(`FROM "api_keys"`)
+		if len(lookup) != 1 || !strings.Contains(lookup[0].SQL, "revoked_at IS NULL") {
+			t.Errorf("%s: a busca deve ignorar chaves revogadas: %v", tt.name, lookup)
+		}
+		revokes := fake.Queries(`UPDATE "api_keys" SET "revoked_at"`)
+		if revoked := len(revokes) == 1 && contains(revokes[0].Args, int64(4)); revoked != tt.found {
+			t.Errorf("%s: revogada = %v, esperado %v", tt.name, revoked, tt.found)
+		}
+		if audited := fake.Executed(`INSERT INTO "audit_logs"`); audited != tt.found {
+			t.Errorf("%s: auditado = %v, esperado %v", tt.name, audited, tt.found)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"loja-online/internal/audit"
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Entidades registradas na auditoria
+const (
+	auditEntityProduct    = "product"
+	auditEntityCustomer   = "customer"
+	auditEntitySale       = "sale"
+	auditEntityInventory  = "inventory_item"
+	auditEntityUser       = "user"
+	auditEntityInvitation = "invitation"
+	auditEntityAPIKey     = "api_key"
+	auditEntityStore      = "store"
+	auditEntityCommission = "commission_rule"
+	auditEntityPeriod     = "commission_period"
+	auditEntityGoal       = "goal"
+	auditEntityImage      = "product_image"
+	auditEntitySchedule   = "scheduled_price"
+	auditEntityPromotion  = "promotion"
+	auditEntityCategory   = "category"
+	auditEntitySession    = "session"
+)
+
+// recordAudit grava a alteração na trilha de auditoria com o autor da
+// requisição. Falhas são apenas registradas no log para não desfazer a
+// operação principal, que já foi concluída.
+func (h *Handler) recordAudit(c *gin.Context, entity string, entityID uint, action string, before, after interface{}) {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		log.Printf("Erro ao calcular auditoria de %s #%d: %v", entity, entityID, err)
+		return
+	}
+
+	// Atualizações sem mudança efetiva não geram registro
+	if action == models.AuditActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("Erro ao serializar auditoria de %s #%d: %v", entity, entityID, err)
+		return
+	}
+
+	entry := models.AuditLog{
+		IPAddress: c.ClientIP(),
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Changes:   models.JSON(data),
+	}
+	if userID, ok := middleware.UserIDFromContext(c); ok {
+		entry.UserID = &userID
+	}
+	if value, exists := c.Get("api_key_id"); exists {
+		if apiKeyID, ok := value.(uint); ok {
+			entry.APIKeyID = &apiKeyID
+		}
+	}
+
+	if err := h.DB.Create(&entry).Error; err != nil {
+		log.Printf("Erro ao gravar auditoria de %s #%d: %v", entity, entityID, err)
+	}
+}
+
+// GetAuditLogs retorna a trilha de auditoria com filtros opcionais
+func (h *Handler) GetAuditLogs(c *gin.Context) {
+	var logs []models.AuditLog
+	var total int64
+
+	query := h.DB.Model(&models.AuditLog{})
+
+	// Filtros opcionais
+	if entity := c.Query("entity"); entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		query = query.Where("created_at >= ?", startDate)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		query = query.Where("created_at <= ?", endDate)
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	query.Count(&total)
+
+	if err := query.Preload("User").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar auditoria"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": logs,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
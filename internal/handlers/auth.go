@@ -1,14 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"loja-online/internal/middleware"
 	"loja-online/internal/models"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -24,37 +25,49 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	// Busca o usuário
+	// Busca o usuário. Email desconhecido também passa pelo bcrypt para que o
+	// tempo de resposta não revele quais contas existem.
 	var user models.User
-	if err := h.DB.Where("email = ? AND active = ?", loginData.Email, true).First(&user).Error; err != nil {
+	if err := h.DB.Where("email = ? AND active = ? AND role <> ?", loginData.Email, true, models.RoleService).First(&user).Error; err != nil {
+		bcrypt.CompareHashAndPassword(unknownUserHash, []byte(loginData.Password))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
 		return
 	}
 
-	// Verifica a senha
+	// Verifica a senha antes do bloqueio: senha errada tem sempre a mesma
+	// resposta, exista a conta ou não, esteja ela bloqueada ou não
+	locked := user.IsLocked(time.Now())
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password)); err != nil {
+		if !locked {
+			h.registerLoginFailure(&user)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
 		return
 	}
 
-	// Cria o token JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// Conta bloqueada por falhas consecutivas
+	if locked {
+		respondLocked(c, *user.LockedUntil)
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(h.Config.JWTSecret))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+	// Com 2FA ativo, o login só é concluído em /auth/login/2fa
+	if user.TwoFactorEnabled {
+		challenge, err := h.newTwoFactorChallenge(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"user":  user,
-	})
+	// Cria a sessão e emite os tokens
+	h.resetLoginFailures(&user)
+	h.startSession(c, &user)
 }
 
 // Me retorna informações do usuário logado
@@ -77,26 +90,35 @@ func (h *Handler) Me(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-// Register registra um novo usuário
+// Register registra um novo usuário quando o cadastro público está habilitado.
+// O usuário criado sempre recebe o papel "user" sem permissões; papéis e
+// permissões são concedidos por convite ou por um administrador.
 func (h *Handler) Register(c *gin.Context) {
-	var user models.User
+	if !h.Config.AllowPublicRegistration {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cadastro público desabilitado. Solicite um convite ao administrador"})
+		return
+	}
+
+	var input models.UserRegister
 
-	if err := c.ShouldBindJSON(&user); err != nil {
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Hash da senha
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 		return
 	}
-	user.Password = string(hashedPassword)
 
-	// Define role padrão se não especificada
-	if user.Role == "" {
-		user.Role = "user"
+	user := models.User{
+		Name:     input.Name,
+		Email:    input.Email,
+		Password: string(hashedPassword),
+		Role:     "user",
+		Active:   true,
 	}
 
 	if err := h.DB.Create(&user).Error; err != nil {
@@ -104,6 +126,8 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionCreate, nil, user)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Usuário criado com sucesso",
 		"user":    user,
@@ -114,7 +138,7 @@ func (h *Handler) Register(c *gin.Context) {
 func (h *Handler) GetUsers(c *gin.Context) {
 	var users []models.User
 
-	if err := h.DB.Find(&users).Error; err != nil {
+	if err := h.DB.Preload("Stores").Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuários"})
 		return
 	}
@@ -124,15 +148,41 @@ func (h *Handler) GetUsers(c *gin.Context) {
 
 // CreateUser cria um novo usuário
 func (h *Handler) CreateUser(c *gin.Context) {
-	var user models.User
+	var input models.UserCreate
 
-	if err := c.ShouldBindJSON(&user); err != nil {
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Hash da senha
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err := input.Permissions.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := checkGrant(c, input.Role, input.Permissions, models.UserPermissions{}); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := models.User{
+		Name:        input.Name,
+		Email:       input.Email,
+		Role:        input.Role,
+		Active:      input.Active == nil || *input.Active,
+		Permissions: input.Permissions,
+	}
+
+	// Hash da senha; contas de serviço recebem uma senha aleatória, nunca usada
+	password := input.Password
+	if password == "" {
+		token, err := generateToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+			return
+		}
+		password = token
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 		return
@@ -143,10 +193,22 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar usuário"})
 		return
 	}
+	// O default do banco prevalece sobre o false na criação
+	if !user.Active {
+		h.DB.Model(&user).Update("active", false)
+	}
+
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionCreate, nil, user)
 
 	c.JSON(http.StatusCreated, gin.H{"user": user})
 }
 
+// userAudit acrescenta à auditoria a troca de senha, que não aparece no JSON do usuário
+type userAudit struct {
+	models.User
+	PasswordChanged bool `json:"password_changed,omitempty"`
+}
+
 // UpdateUser atualiza um usuário existente
 func (h *Handler) UpdateUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -161,15 +223,53 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var updateData models.User
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var input models.UserUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Administradores e gerentes só são alterados por administradores, para
+	// que ninguém assuma a conta deles trocando a senha
+	if status, err := checkManageUser(c, &user); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := ""
+	if input.Role != nil && *input.Role != user.Role {
+		role = *input.Role
+	}
+	permissions := user.Permissions
+	if input.Permissions != nil {
+		if err := input.Permissions.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		permissions = *input.Permissions
+	}
+	if status, err := checkGrant(c, role, permissions, user.Permissions); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Updates ignora campos com valor zero; a desativação e as permissões
+	// são gravadas à parte
+	var updateData models.User
+	if input.Name != nil {
+		updateData.Name = *input.Name
+	}
+	if input.Email != nil {
+		updateData.Email = *input.Email
+	}
+	updateData.Role = role
+	if input.Active != nil && *input.Active {
+		updateData.Active = true
+	}
+
 	// Se a senha foi fornecida, faz o hash
-	if updateData.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updateData.Password), bcrypt.DefaultCost)
+	if input.Password != nil {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*input.Password), bcrypt.DefaultCost)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 			return
@@ -177,14 +277,71 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		updateData.Password = string(hashedPassword)
 	}
 
+	before := user
 	if err := h.DB.Model(&user).Updates(updateData).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
 		return
 	}
 
+	// Permissões são substituídas por completo, inclusive as revogadas
+	if input.Permissions != nil {
+		if err := h.DB.Model(&user).
+			Select("products", "customers", "inventory", "sales", "reports", "users", "capabilities").
+			Updates(&models.User{Permissions: *input.Permissions}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar permissões"})
+			return
+		}
+	}
+
+	// Usuário desativado perde todas as sessões imediatamente
+	if input.Active != nil && !*input.Active {
+		if err := h.DB.Model(&user).Update("active", false).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar usuário"})
+			return
+		}
+		if err := revokeUserSessions(h.DB, user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessões do usuário"})
+			return
+		}
+	}
+
+	h.DB.First(&user, id)
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate, before, userAudit{
+		User:            user,
+		PasswordChanged: updateData.Password != "",
+	})
+
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+// checkGrant verifica se o usuário autenticado pode atribuir o papel e as
+// permissões: os papéis admin e manager só são concedidos por
+// administradores, e ninguém concede capacidades que não possui. As
+// permissões em current, que o usuário alterado já tem, podem ser mantidas.
+// Retorna o status HTTP do erro.
+func checkGrant(c *gin.Context, role string, requested, current models.UserPermissions) (int, error) {
+	if models.IsPrivilegedRole(role) && !middleware.IsAdmin(c) {
+		return http.StatusForbidden, errors.New("Somente administradores concedem os papéis admin e manager")
+	}
+	for _, capabilities := range models.ModuleCapabilities {
+		for _, capability := range capabilities {
+			if requested.Can(capability) && !current.Can(capability) && !middleware.Can(c, capability) {
+				return http.StatusForbidden, errors.New("Você não pode conceder a capacidade " + capability + ", que não possui")
+			}
+		}
+	}
+	return 0, nil
+}
+
+// checkManageUser impede que quem não é administrador altere ou remova
+// administradores e gerentes. Retorna o status HTTP do erro.
+func checkManageUser(c *gin.Context, user *models.User) (int, error) {
+	if user.IsPrivileged() && !middleware.IsAdmin(c) {
+		return http.StatusForbidden, errors.New("Somente administradores alteram administradores e gerentes")
+	}
+	return 0, nil
+}
+
 // DeleteUser deleta um usuário
 func (h *Handler) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -193,10 +350,28 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Delete(&models.User{}, id).Error; err != nil {
+	var user models.User
+	if err := h.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if status, err := checkManageUser(c, &user); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Delete(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao deletar usuário"})
 		return
 	}
 
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionDelete, user, nil)
+
+	if err := revokeUserSessions(h.DB, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessões do usuário"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Usuário deletado com sucesso"})
 }
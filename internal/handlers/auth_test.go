@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gerente de equipe que não é administrador: gerencia usuários e vê produtos
+var teamLead = &models.User{ID: 5, Role: "user", Active: true, Permissions: models.UserPermissions{
+	Products:     true,
+	Users:        true,
+	Capabilities: models.Capabilities{models.CapProductsRead, models.CapUsersRead, models.CapUsersWrite},
+}}
+
+var adminUser = &models.User{ID: 1, Role: "admin", Active: true}
+
+// actingAs autentica a requisição como user, já carregado como faria o
+// middleware de permissões
+func actingAs(user *models.User, keyPermissions *models.UserPermissions) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Set("user_id", float64(user.ID))
+		c.Set("current_user", user)
+		if keyPermissions != nil {
+			c.Set("api_key_permissions", *keyPermissions)
+		}
+	}
+}
+
+func permissions(capabilities ...string) models.UserPermissions {
+	return models.UserPermissions{Capabilities: capabilities}
+}
+
+func TestCheckGrant(t *testing.T) {
+	key := permissions(models.CapUsersWrite)
+
+	tests := []struct {
+		name      string
+		granter   *models.User
+		key       *models.UserPermissions
+		role      string
+		requested models.UserPermissions
+		current   models.UserPermissions
+		want      int
+	}{
+		{"capacidade que possui", teamLead, nil, "user", permissions(models.CapProductsRead), permissions(), 0},
+		{"capacidade que não possui", teamLead, nil, "user", permissions(models.CapSalesCancel), permissions(), http.StatusForbidden},
+		{"mantém capacidade que o usuário já tinha", teamLead, nil, "", permissions(models.CapSalesCancel, models.CapProductsRead), permissions(models.CapSalesCancel), 0},
+		{"papel manager", teamLead, nil, "manager", permissions(), permissions(), http.StatusForbidden},
+		{"papel admin", teamLead, nil, "admin", permissions(), permissions(), http.StatusForbidden},
+		{"admin concede qualquer capacidade", adminUser, nil, "manager", permissions(models.CapSalesCancel, models.CapUsersDelete), permissions(), 0},
+		{"chave de API limita o admin", adminUser, &key, "user", permissions(models.CapSalesCancel), permissions(), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		actingAs(tt.granter, tt.key)(c)
+
+		status, err := checkGrant(c, tt.role, tt.requested, tt.current)
+		if status != tt.want || (err != nil) != (tt.want != 0) {
+			t.Errorf("%s: status %d (%v), esperado %d", tt.name, status, err, tt.want)
+		}
+	}
+}
+
+func TestCheckManageUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   *models.User
+		target  string
+		allowed bool
+	}{
+		{"não admin altera usuário comum", teamLead, "user", true},
+		{"não admin altera gerente", teamLead, "manager", false},
+		{"não admin altera admin", teamLead, "admin", false},
+		{"admin altera admin", adminUser, "admin", true},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		actingAs(tt.actor, nil)(c)
+
+		status, err := checkManageUser(c, &models.User{ID: 8, Role: tt.target, Active: true})
+		if allowed := err == nil; allowed != tt.allowed || (!allowed && status != http.StatusForbidden) {
+			t.Errorf("%s: status %d (%v), permitido esperado %v", tt.name, status, err, tt.allowed)
+		}
+	}
+}
+
+func TestCreateInvitationChecksGrant(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         string
+		capabilities []string
+		want         int
+	}{
+		{"capacidades que possui", "user", []string{models.CapProductsRead}, http.StatusCreated},
+		{"capacidade que não possui", "user", []string{models.CapProductsRead, models.CapProductsDelete}, http.StatusForbidden},
+		{"papel manager", "manager", []string{models.CapProductsRead}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+
+		w := serve(h.CreateInvitation, http.MethodPost, "/invitations", gin.H{
+			"email":       "bia@loja.test",
+			"role":        tt.role,
+			"permissions": gin.H{"capabilities": tt.capabilities},
+		}, actingAs(teamLead, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, esperado %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+		if created := fake.Executed(`INSERT INTO "invitations"`); created != (tt.want == http.StatusCreated) {
+			t.Errorf("%s: convite criado = %v", tt.name, created)
+		}
+	}
+}
+
+func TestUpdateUserChecksManage(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         string
+		capabilities []string
+		want         int
+	}{
+		{"usuário comum", "user", []string{models.CapProductsRead}, http.StatusOK},
+		{"gerente", "manager", []string{models.CapProductsRead}, http.StatusForbidden},
+		{"concede capacidade que não possui", "user", []string{models.CapSalesCancel}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		fake.On(`FROM "users"`).Rows([]string{"id", "name", "role", "active"}, []interface{}{8, "Bia", tt.role, true})
+
+		w := serve(func(c *gin.Context) {
+			c.Params = gin.Params{{Key: "id", Value: "8"}}
+			h.UpdateUser(c)
+		}, http.MethodPut, "/users/8", gin.H{
+			"name":        "Beatriz",
+			"permissions": gin.H{"capabilities": tt.capabilities},
+		}, actingAs(teamLead, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, esperado %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+		if updated := fake.Executed(`UPDATE "users"`); updated != (tt.want == http.StatusOK) {
+			t.Errorf("%s: usuário alterado = %v", tt.name, updated)
+		}
+	}
+}
+
+func TestRegisterAudit(t *testing.T) {
+	h, fake := newTestHandler(t)
+	h.Config.AllowPublicRegistration = true
+
+	w := serve(h.Register, http.MethodPost, "/register", gin.H{"name": "Ana", "email": "ana@loja.com", "password": "segredo123"}, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, esperado 201: %s", w.Code, w.Body.String())
+	}
+
+	entries := fake.Queries(`INSERT INTO "audit_logs"`)
+	if len(entries) != 1 || !contains(entries[0].Args, auditEntityUser) || !contains(entries[0].Args, models.AuditActionCreate) {
+		t.Errorf("o cadastro deve ser auditado: %v", entries)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"loja-online/internal/barcode"
+	"loja-online/internal/labels"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// checkBarcode normaliza o código de barras informado e verifica se ele já
+// pertence a outro produto, inclusive removido. Retorna o status HTTP do erro.
+func (h *Handler) checkBarcode(code string, productID uint) (string, int, error) {
+	if strings.TrimSpace(code) == "" {
+		return "", 0, nil
+	}
+	code, err := barcode.Normalize(code)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+
+	var owner models.Product
+	err = h.DB.Unscoped().Select("id, sku").Where("barcode = ? AND id <> ?", code, productID).First(&owner).Error
+	if err == nil {
+		return "", http.StatusConflict, errors.New("Código de barras já usado pelo produto " + owner.SKU)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", http.StatusInternalServerError, errors.New("Erro ao verificar código de barras")
+	}
+	return code, 0, nil
+}
+
+// nextBarcode gera o próximo código de barras interno, pulando os que já
+// tenham sido cadastrados manualmente
+func nextBarcode(tx *gorm.DB) (string, error) {
+	for {
+		var seq int64
+		if err := tx.Raw("SELECT nextval('product_barcode_seq')").Scan(&seq).Error; err != nil {
+			return "", err
+		}
+		code, err := barcode.Internal(seq)
+		if err != nil {
+			return "", err
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.Product{}).Where("barcode = ?", code).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+}
+
+// GetProductByBarcode busca o produto pelo código de barras lido no balcão,
+// com o estoque da loja ativa
+func (h *Handler) GetProductByBarcode(c *gin.Context) {
+	code := strings.TrimSpace(c.Param("code"))
+	if normalized, err := barcode.Normalize(code); err == nil {
+		code = normalized
+	}
+
+	var product models.Product
+	if err := h.DB.Where("barcode = ?", code).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
+		return
+	}
+	products := []models.Product{product}
+	h.attachStock(c, products)
+
+	c.JSON(http.StatusOK, gin.H{"product": products[0]})
+}
+
+// GenerateBarcode atribui um código de barras interno ao produto. Em um
+// estilo, atribui às variações que ainda não têm código.
+func (h *Handler) GenerateBarcode(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	var targets []models.Product
+	if product.HasVariants {
+		if err := h.DB.Where("parent_id = ? AND COALESCE(barcode, '') = ''", product.ID).Order("id").Find(&targets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar código de barras"})
+			return
+		}
+	} else if product.Barcode == "" {
+		targets = []models.Product{*product}
+	}
+	if len(targets) == 0 {
+		message := "O produto já tem código de barras"
+		if product.HasVariants {
+			message = "Todas as variações já têm código de barras"
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": message})
+		return
+	}
+
+	befores := make([]models.Product, len(targets))
+	copy(befores, targets)
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range targets {
+			code, err := nextBarcode(tx)
+			if err != nil {
+				return err
+			}
+			targets[i].Barcode = code
+			if err := tx.Model(&targets[i]).Update("barcode", code).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar código de barras"})
+		return
+	}
+
+	for i := range targets {
+		h.recordAudit(c, auditEntityProduct, targets[i].ID, models.AuditActionUpdate, befores[i], targets[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": targets})
+}
+
+// PrintLabels gera as etiquetas dos produtos em PDF (folha A4 de 3 x 8) ou
+// ZPL (etiqueta térmica de 50 x 30 mm)
+func (h *Handler) PrintLabels(c *gin.Context) {
+	var input models.ProductLabelsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]uint, len(input.Items))
+	for i, item := range input.Items {
+		ids[i] = item.ProductID
+	}
+	var products []models.Product
+	if err := h.DB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
+		return
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	var sheet []labels.Label
+	var missing []string
+	add := func(product models.Product, quantity int) {
+		if product.Barcode == "" || !barcode.Valid(product.Barcode) {
+			missing = append(missing, product.SKU)
+			return
+		}
+		sheet = append(sheet, labels.Label{
+			Name:     product.Name,
+			Size:     product.Size,
+			Color:    product.Color,
+			Price:    product.Price,
+			Barcode:  product.Barcode,
+			Quantity: quantity,
+		})
+	}
+	for _, item := range input.Items {
+		product, ok := byID[item.ProductID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Produto não encontrado: %d", item.ProductID)})
+			return
+		}
+		if !product.HasVariants {
+			add(product, item.Quantity)
+			continue
+		}
+		for _, variant := range product.Variants {
+			add(variant, item.Quantity)
+		}
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Produtos sem código de barras EAN-13 válido", "skus": missing})
+		return
+	}
+
+	var out bytes.Buffer
+	if err := labels.Write(&out, input.Format, sheet, labels.SheetA4); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar etiquetas"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="etiquetas.`+input.Format+`"`)
+	c.Data(http.StatusOK, labels.ContentType(input.Format), out.Bytes())
+}
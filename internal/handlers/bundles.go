@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateBundleComponents substitui a composição de um kit. Um produto simples
+// sem estoque passa a ser kit; vendas já registradas mantêm os componentes
+// da época.
+func (h *Handler) UpdateBundleComponents(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var product models.Product
+	if err := h.DB.Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
+		return
+	}
+	if product.HasVariants || product.IsVariant() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estilos e variações não podem ser kits"})
+		return
+	}
+
+	var input models.BundleComponentsUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !product.IsBundle {
+		var stocked int64
+		h.DB.Model(&models.InventoryItem{}).Where("product_id = ? AND quantity <> 0", product.ID).Count(&stocked)
+		if stocked > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Produto com estoque não pode virar kit; zere o estoque ou cadastre um novo produto"})
+			return
+		}
+		if skus := h.bundlesUsing([]uint{product.ID}); len(skus) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "O produto é componente dos kits " + strings.Join(skus, ", ")})
+			return
+		}
+	}
+
+	components := make([]models.BundleComponent, len(input.Components))
+	for i, component := range input.Components {
+		components[i] = models.BundleComponent{ComponentID: component.ComponentID, Quantity: component.Quantity}
+	}
+	if status, err := h.checkBundleComponents(product.ID, components); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := product
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if !product.IsBundle {
+			if err := tx.Model(&product).Update("is_bundle", true).Error; err != nil {
+				return err
+			}
+		}
+		return replaceBundleComponents(tx, product.ID, components)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar componentes do kit"})
+		return
+	}
+
+	h.DB.Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Components.Component").First(&product, product.ID)
+	h.recordAudit(c, auditEntityProduct, product.ID, models.AuditActionUpdate, before, product)
+
+	products := []models.Product{product}
+	h.attachStock(c, products)
+
+	c.JSON(http.StatusOK, gin.H{"product": products[0]})
+}
+
+// checkBundleComponents valida a composição de um kit: componentes
+// cadastrados, vendáveis (nem estilo nem outro kit), sem repetição e
+// diferentes do próprio kit. Retorna o status HTTP do erro.
+func (h *Handler) checkBundleComponents(bundleID uint, components []models.BundleComponent) (int, error) {
+	if len(components) == 0 {
+		return http.StatusBadRequest, errors.New("Informe os componentes do kit")
+	}
+
+	ids := make([]uint, len(components))
+	seen := make(map[uint]bool, len(components))
+	for i, component := range components {
+		if component.ComponentID == 0 || component.Quantity <= 0 {
+			return http.StatusBadRequest, errors.New("Cada componente precisa de component_id e quantity maior que zero")
+		}
+		if bundleID != 0 && component.ComponentID == bundleID {
+			return http.StatusBadRequest, errors.New("O kit não pode ser componente de si mesmo")
+		}
+		if seen[component.ComponentID] {
+			return http.StatusBadRequest, errors.New("Componente repetido: " + strconv.Itoa(int(component.ComponentID)) + "; some as quantidades")
+		}
+		seen[component.ComponentID] = true
+		ids[i] = component.ComponentID
+	}
+
+	var products []models.Product
+	if err := h.DB.Select("id, sku, has_variants, is_bundle").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return http.StatusInternalServerError, errors.New("Erro ao buscar componentes")
+	}
+	if len(products) != len(ids) {
+		return http.StatusBadRequest, errors.New("Componente não encontrado")
+	}
+	for _, product := range products {
+		if product.HasVariants {
+			return http.StatusBadRequest, errors.New("O produto " + product.SKU + " é um estilo; use uma de suas variações")
+		}
+		if product.IsBundle {
+			return http.StatusBadRequest, errors.New("O produto " + product.SKU + " é um kit; kits não podem conter outros kits")
+		}
+	}
+	return 0, nil
+}
+
+// replaceBundleComponents grava a composição do kit no lugar da anterior
+func replaceBundleComponents(tx *gorm.DB, bundleID uint, components []models.BundleComponent) error {
+	if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.BundleComponent{}).Error; err != nil {
+		return err
+	}
+	for i := range components {
+		components[i].ID = 0
+		components[i].BundleID = bundleID
+		components[i].Component = nil
+	}
+	return tx.Create(&components).Error
+}
+
+// bundlesUsing retorna os SKUs dos kits que têm algum dos produtos como
+// componente
+func (h *Handler) bundlesUsing(productIDs []uint) []string {
+	var skus []string
+	h.DB.Model(&models.Product{}).
+		Joins("JOIN bundle_components ON bundle_components.bundle_id = products.id").
+		Where("bundle_components.component_id IN ?", productIDs).
+		Distinct().Order("products.sku").Pluck("products.sku", &skus)
+	return skus
+}
+
+// loadBundleComponents carrega os componentes dos kits, com os produtos,
+// agrupados pelo kit
+func (h *Handler) loadBundleComponents(bundleIDs []uint) (map[uint][]models.BundleComponent, error) {
+	var components []models.BundleComponent
+	if err := h.DB.Preload("Component").Where("bundle_id IN ?", bundleIDs).Order("id").Find(&components).Error; err != nil {
+		return nil, err
+	}
+	byBundle := make(map[uint][]models.BundleComponent, len(bundleIDs))
+	for _, component := range components {
+		byBundle[component.BundleID] = append(byBundle[component.BundleID], component)
+	}
+	return byBundle, nil
+}
+
+// splitBundleItem distribui o item de kit vendido entre os componentes: a
+// quantidade de cada um que sai do estoque e a receita do item, rateada pelo
+// preço de tabela dos componentes
+func splitBundleItem(item *models.SaleItem, components []models.BundleComponent) {
+	weights := make([]money.Amount, len(components))
+	for i, component := range components {
+		weights[i] = component.Component.Price.Mul(component.Quantity)
+	}
+	revenues := item.TotalPrice.Allocate(weights)
+
+	item.Components = make([]models.SaleItemComponent, len(components))
+	for i, component := range components {
+		item.Components[i] = models.SaleItemComponent{
+			ProductID: component.ComponentID,
+			Quantity:  component.Quantity * item.Quantity,
+			Revenue:   revenues[i],
+		}
+	}
+}
+
+// attachBundleStock preenche o estoque dos kits: em cada loja, quantos kits
+// os componentes permitem montar, somado nas lojas visíveis quando não há
+// loja ativa
+func (h *Handler) attachBundleStock(c *gin.Context, products []models.Product) {
+	var bundleIDs []uint
+	for _, product := range products {
+		if product.IsBundle {
+			bundleIDs = append(bundleIDs, product.ID)
+		}
+	}
+	if len(bundleIDs) == 0 {
+		return
+	}
+
+	var components []models.BundleComponent
+	if err := h.DB.Where("bundle_id IN ?", bundleIDs).Find(&components).Error; err != nil {
+		return
+	}
+	byBundle := make(map[uint][]models.BundleComponent, len(bundleIDs))
+	componentIDs := make([]uint, 0, len(components))
+	for _, component := range components {
+		byBundle[component.BundleID] = append(byBundle[component.BundleID], component)
+		componentIDs = append(componentIDs, component.ComponentID)
+	}
+
+	var rows []struct {
+		StoreID   uint
+		ProductID uint
+		Quantity  int
+	}
+	if len(componentIDs) > 0 {
+		if err := scopeStore(c, h.DB.Model(&models.InventoryItem{}), "store_id").
+			Select("store_id, product_id, quantity").
+			Where("product_id IN ?", componentIDs).
+			Scan(&rows).Error; err != nil {
+			return
+		}
+	}
+	byStore := make(map[uint]map[uint]int)
+	for _, row := range rows {
+		if byStore[row.StoreID] == nil {
+			byStore[row.StoreID] = make(map[uint]int)
+		}
+		byStore[row.StoreID][row.ProductID] += row.Quantity
+	}
+
+	for i := range products {
+		if !products[i].IsBundle {
+			continue
+		}
+		total := 0
+		for _, stock := range byStore {
+			total += buildableBundles(byBundle[products[i].ID], stock)
+		}
+		products[i].Stock = &total
+	}
+}
+
+// buildableBundles retorna quantos kits o estoque de uma loja permite montar
+func buildableBundles(components []models.BundleComponent, stock map[uint]int) int {
+	if len(components) == 0 {
+		return 0
+	}
+	buildable := -1
+	for _, component := range components {
+		available := max(stock[component.ComponentID], 0) / component.Quantity
+		if buildable < 0 || available < buildable {
+			buildable = available
+		}
+	}
+	return buildable
+}
+
+// soldLines retorna os itens das vendas filtradas com cada kit substituído
+// pelos componentes, com a quantidade e a receita rateada, nas colunas de
+// sale_items usadas pelos relatórios
+func (h *Handler) soldLines(sales *gorm.DB) *gorm.DB {
+	items := h.DB.Table("sale_items").
+		Select("sale_items.sale_id, sale_items.product_id, sale_items.quantity, sale_items.total_price").
+		Where("sale_items.sale_id IN (?)", sales.Select("sales.id")).
+		Where("NOT EXISTS (SELECT 1 FROM sale_item_components WHERE sale_item_components.sale_item_id = sale_items.id)")
+	components := h.DB.Table("sale_item_components").
+		Select("sale_items.sale_id, sale_item_components.product_id, sale_item_components.quantity, sale_item_components.revenue AS total_price").
+		Joins("JOIN sale_items ON sale_items.id = sale_item_components.sale_item_id").
+		Where("sale_items.sale_id IN (?)", sales.Select("sales.id"))
+	return h.DB.Raw("? UNION ALL ?", items, components)
+}
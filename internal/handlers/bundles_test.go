@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"loja-online/internal/dbtest"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildableBundles(t *testing.T) {
+	components := []models.BundleComponent{{ComponentID: 1, Quantity: 2}, {ComponentID: 2, Quantity: 1}}
+
+	tests := []struct {
+		name       string
+		components []models.BundleComponent
+		stock      map[uint]int
+		want       int
+	}{
+		{"limitado pelo componente mais escasso", components, map[uint]int{1: 5, 2: 4}, 2},
+		{"componente sem estoque", components, map[uint]int{1: 10}, 0},
+		{"estoque negativo", components, map[uint]int{1: 10, 2: -3}, 0},
+		{"kit sem componentes", nil, map[uint]int{1: 10}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := buildableBundles(tt.components, tt.stock); got != tt.want {
+			t.Errorf("%s: kits = %d, esperado %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitBundleItem(t *testing.T) {
+	component := func(id uint, price int64, quantity int) models.BundleComponent {
+		return models.BundleComponent{ComponentID: id, Quantity: quantity, Component: &models.Product{ID: id, Price: money.FromCents(price)}}
+	}
+
+	tests := []struct {
+		name       string
+		total      money.Amount
+		components []models.BundleComponent
+		quantities []int
+		revenues   []money.Amount
+	}{
+		{
+			name:       "rateio pelo preço de tabela",
+			total:      money.FromCents(200_00),
+			components: []models.BundleComponent{component(1, 30_00, 2), component(2, 20_00, 1)},
+			quantities: []int{4, 2},
+			revenues:   []money.Amount{money.FromCents(150_00), money.FromCents(50_00)},
+		},
+		{
+			name:       "sobra de centavos",
+			total:      money.FromCents(100_00),
+			components: []models.BundleComponent{component(1, 10_00, 1), component(2, 10_00, 1), component(3, 10_00, 1)},
+			quantities: []int{2, 2, 2},
+			revenues:   []money.Amount{money.FromCents(33_34), money.FromCents(33_33), money.FromCents(33_33)},
+		},
+	}
+
+	for _, tt := range tests {
+		item := models.SaleItem{ProductID: 10, Quantity: 2, TotalPrice: tt.total}
+		splitBundleItem(&item, tt.components)
+
+		var sum money.Amount
+		for i, got := range item.Components {
+			if got.ProductID != tt.components[i].ComponentID || got.Quantity != tt.quantities[i] || got.Revenue != tt.revenues[i] {
+				t.Errorf("%s: componente %d = %+v, esperado %d peças e %s", tt.name, i, got, tt.quantities[i], tt.revenues[i])
+			}
+			sum += got.Revenue
+		}
+		if sum != tt.total {
+			t.Errorf("%s: receita rateada soma %s, esperado %s", tt.name, sum, tt.total)
+		}
+	}
+}
+
+// O estoque do kit em cada loja é o número de kits que os componentes
+// permitem montar, somado entre as lojas
+func TestAttachBundleStock(t *testing.T) {
+	h, fake := newTestHandler(t)
+	fake.On(`FROM "bundle_components"`).Rows([]string{"bundle_id", "component_id", "quantity"},
+		[]interface{}{10, 1, 2},
+		[]interface{}{10, 2, 1})
+	fake.On(`FROM "inventory_items"`).Rows([]string{"store_id", "product_id", "quantity"},
+		[]interface{}{1, 1, 5},
+		[]interface{}{1, 2, 4},
+		[]interface{}{2, 1, 2},
+		[]interface{}{2, 2, 0})
+
+	stock := 7
+	products := []models.Product{{ID: 10, IsBundle: true}, {ID: 1, Stock: &stock}}
+	c, _ := gin.CreateTestContext(nil)
+	h.attachBundleStock(c, products)
+
+	if products[0].Stock == nil || *products[0].Stock != 2 {
+		t.Errorf("estoque do kit = %v, esperado 2", products[0].Stock)
+	}
+	if *products[1].Stock != 7 {
+		t.Errorf("estoque do produto simples foi alterado para %d", *products[1].Stock)
+	}
+}
+
+// bundleSale responde às consultas da venda de um kit 10 (100,00) formado
+// por 2 peças do produto 1 (30,00) e 1 do produto 2 (20,00)
+func bundleSale(fake *dbtest.DB, stock1, stock2 int) {
+	fake.On("WHERE id IN").Rows([]string{"id", "sku", "price", "is_bundle"},
+		[]interface{}{10, "KIT", money.FromCents(100_00), true})
+	fake.On(`FROM "bundle_components"`).Rows([]string{"id", "bundle_id", "component_id", "quantity"},
+		[]interface{}{1, 10, 1, 2},
+		[]interface{}{2, 10, 2, 1})
+	fake.On(`"products"."id" IN`).Rows([]string{"id", "sku", "price", "cost_price"},
+		[]interface{}{1, "CAM", money.FromCents(30_00), money.FromCents(10_00)},
+		[]interface{}{2, "MEIA", money.FromCents(20_00), money.FromCents(5_00)})
+	fake.On(`FROM "inventory_items"`).Once().Rows([]string{"id", "store_id", "product_id", "quantity"}, []interface{}{31, 2, 1, stock1})
+	fake.On(`FROM "inventory_items"`).Once().Rows([]string{"id", "store_id", "product_id", "quantity"}, []interface{}{32, 2, 2, stock2})
+}
+
+// sellBundle vende dois kits na loja 2 e retorna o status da resposta
+func sellBundle(h *Handler) int {
+	w := serve(h.CreateSale, http.MethodPost, "/sales", gin.H{
+		"payment_method": "pix",
+		"sale_items":     []gin.H{{"product_id": 10, "quantity": 2}},
+	}, func(c *gin.Context) {
+		c.Set("user_id", float64(3))
+		c.Set("store_id", uint(2))
+	})
+	return w.Code
+}
+
+func TestCreateSaleConsumesBundleComponents(t *testing.T) {
+	h, fake := newTestHandler(t)
+	bundleSale(fake, 10, 5)
+
+	if status := sellBundle(h); status != http.StatusCreated {
+		t.Fatalf("status = %d, esperado 201", status)
+	}
+
+	// O estoque baixado é o dos componentes, nunca o do kit
+	lookups := fake.Queries(`FROM "inventory_items"`)
+	// Argumentos: loja, produto e limite
+	if len(lookups) != 2 || lookups[0].Args[1] != int64(1) || lookups[1].Args[1] != int64(2) {
+		t.Fatalf("devem ser baixados os componentes 1 e 2: %v", lookups)
+	}
+	updates := fake.Queries(`UPDATE "inventory_items"`)
+	if len(updates) != 2 || !contains(updates[0].Args, int64(6)) || !contains(updates[1].Args, int64(3)) {
+		t.Errorf("estoques devem ficar em 6 e 3: %v", updates)
+	}
+
+	components := fake.Queries(`INSERT INTO "sale_item_components"`)
+	if len(components) != 1 {
+		t.Fatalf("%d inserções de componentes vendidos, esperada 1", len(components))
+	}
+	for _, value := range []interface{}{"150.00", "50.00", int64(4), int64(2)} {
+		if !contains(components[0].Args, value) {
+			t.Errorf("os componentes vendidos devem registrar %v: %v", value, components[0].Args)
+		}
+	}
+}
+
+func TestCreateSaleBundleWithoutComponentStock(t *testing.T) {
+	h, fake := newTestHandler(t)
+	bundleSale(fake, 10, 1)
+
+	if status := sellBundle(h); status != http.StatusBadRequest {
+		t.Fatalf("status = %d, esperado 400", status)
+	}
+	if !fake.Executed("ROLLBACK") || fake.Executed("COMMIT") {
+		t.Error("a venda deve ser desfeita")
+	}
+}
@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// categoryTree é o conjunto de categorias carregado para montar a árvore e
+// resolver nomes e caminhos
+type categoryTree struct {
+	list []models.Category
+	byID map[uint]*models.Category
+}
+
+// loadCategories carrega todas as categorias, na ordem de exibição
+func loadCategories(db *gorm.DB) (*categoryTree, error) {
+	tree := &categoryTree{}
+	if err := db.Order("depth, position, name, id").Find(&tree.list).Error; err != nil {
+		return nil, err
+	}
+	tree.byID = make(map[uint]*models.Category, len(tree.list))
+	for i := range tree.list {
+		tree.byID[tree.list[i].ID] = &tree.list[i]
+	}
+	return tree, nil
+}
+
+// ancestors retorna a categoria e seus ancestrais, da raiz até ela
+func (t *categoryTree) ancestors(id uint) []*models.Category {
+	category, ok := t.byID[id]
+	if !ok {
+		return nil
+	}
+	var chain []*models.Category
+	for _, part := range strings.Split(strings.Trim(category.Path, "/"), "/") {
+		ancestorID, _ := strconv.Atoi(part)
+		if ancestor, ok := t.byID[uint(ancestorID)]; ok {
+			chain = append(chain, ancestor)
+		}
+	}
+	return chain
+}
+
+// names retorna os nomes da categoria e de seus ancestrais, da raiz até ela
+func (t *categoryTree) names(id uint) []string {
+	chain := t.ancestors(id)
+	names := make([]string, len(chain))
+	for i, category := range chain {
+		names[i] = category.Name
+	}
+	return names
+}
+
+// chains retorna, para cada categoria, os IDs dela e de seus ancestrais
+func (t *categoryTree) chains() map[uint][]uint {
+	chains := make(map[uint][]uint, len(t.list))
+	for _, category := range t.list {
+		chain := t.ancestors(category.ID)
+		ids := make([]uint, len(chain))
+		for i, ancestor := range chain {
+			ids[i] = ancestor.ID
+		}
+		chains[category.ID] = ids
+	}
+	return chains
+}
+
+// pathName retorna o caminho legível da categoria: Masculino > Bermudas > Surf
+func (t *categoryTree) pathName(id uint) string {
+	return strings.Join(t.names(id), models.CategoryPathSeparator)
+}
+
+// resolve encontra a categoria pelo caminho ("Masculino > Bermudas"), pelo
+// slug ou pelo nome, sem distinguir maiúsculas nem acentos. Um nome repetido
+// em ramos diferentes exige o caminho.
+func (t *categoryTree) resolve(value string) (*models.Category, error) {
+	parts := strings.Split(value, ">")
+	if len(parts) > 1 {
+		var parent *models.Category
+		for _, part := range parts {
+			slug := models.Slugify(part)
+			var next *models.Category
+			for i := range t.list {
+				candidate := &t.list[i]
+				sameParent := (parent == nil && candidate.ParentID == nil) ||
+					(parent != nil && candidate.ParentID != nil && *candidate.ParentID == parent.ID)
+				if sameParent && models.Slugify(candidate.Name) == slug {
+					next = candidate
+					break
+				}
+			}
+			if next == nil {
+				return nil, errors.New("Categoria não cadastrada: " + strings.TrimSpace(value))
+			}
+			parent = next
+		}
+		return parent, nil
+	}
+
+	slug := models.Slugify(value)
+	var matches []*models.Category
+	for i := range t.list {
+		if t.list[i].Slug == slug {
+			return &t.list[i], nil
+		}
+		if models.Slugify(t.list[i].Name) == slug {
+			matches = append(matches, &t.list[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, errors.New("Categoria não cadastrada: " + strings.TrimSpace(value))
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("Categoria %q existe em mais de um ramo; informe o caminho, como %q",
+		strings.TrimSpace(value), t.pathName(matches[0].ID))
+}
+
+// nested monta a árvore a partir das categorias da lista
+func (t *categoryTree) nested() []models.Category {
+	children := make(map[uint][]models.Category)
+	var build func(category models.Category) models.Category
+	build = func(category models.Category) models.Category {
+		category.Children = nil
+		for _, child := range children[category.ID] {
+			category.Children = append(category.Children, build(child))
+		}
+		return category
+	}
+
+	var roots []models.Category
+	for _, category := range t.list {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+	for i := range roots {
+		roots[i] = build(roots[i])
+	}
+	return roots
+}
+
+// assignCategory resolve a categoria do produto por category_id ou, sem ele,
+// pelo nome ou caminho em category, e preenche os dois campos. Retorna o
+// status HTTP do erro.
+func (h *Handler) assignCategory(product *models.Product) (int, error) {
+	if product.CategoryID == nil && strings.TrimSpace(product.Category) == "" {
+		return 0, nil
+	}
+
+	tree, err := loadCategories(h.DB)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Erro ao buscar categorias")
+	}
+
+	var category *models.Category
+	if product.CategoryID != nil {
+		var ok bool
+		if category, ok = tree.byID[*product.CategoryID]; !ok {
+			return http.StatusBadRequest, errors.New("Categoria não encontrada")
+		}
+	} else if category, err = tree.resolve(product.Category); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if !category.Active {
+		return http.StatusBadRequest, errors.New("Categoria inativa: " + tree.pathName(category.ID))
+	}
+
+	product.CategoryID = &category.ID
+	product.Category = category.Name
+	return 0, nil
+}
+
+// categorySubtree retorna a subconsulta dos IDs das categorias informadas e
+// de todas as suas descendentes
+func (h *Handler) categorySubtree(ids []uint) *gorm.DB {
+	return h.DB.Table("categories AS c").
+		Select("c.id").
+		Joins("JOIN categories AS a ON c.path LIKE a.path || '%'").
+		Where("a.id IN ? AND c.deleted_at IS NULL", ids)
+}
+
+// GetCategories retorna a árvore de categorias. Com flat=true, retorna a
+// lista em ordem de exibição, com o caminho de cada uma; active=true omite as
+// inativas e suas descendentes.
+func (h *Handler) GetCategories(c *gin.Context) {
+	tree, err := loadCategories(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar categorias"})
+		return
+	}
+
+	if c.Query("active") == "true" {
+		var visible []models.Category
+		for _, category := range tree.list {
+			active := true
+			for _, ancestor := range tree.ancestors(category.ID) {
+				active = active && ancestor.Active
+			}
+			if active {
+				visible = append(visible, category)
+			}
+		}
+		tree.list = visible
+	}
+
+	if c.Query("flat") == "true" {
+		type flatCategory struct {
+			models.Category
+			PathName string `json:"path_name"`
+		}
+		flat := make([]flatCategory, len(tree.list))
+		for i, category := range tree.list {
+			flat[i] = flatCategory{Category: category, PathName: tree.pathName(category.ID)}
+		}
+		c.JSON(http.StatusOK, gin.H{"categories": flat})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree.nested()})
+}
+
+// GetCategory retorna a categoria com as subcategorias diretas, o caminho
+// desde a raiz e o número de produtos na subárvore
+func (h *Handler) GetCategory(c *gin.Context) {
+	category, ok := h.findCategory(c)
+	if !ok {
+		return
+	}
+
+	tree, err := loadCategories(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar categorias"})
+		return
+	}
+	for _, candidate := range tree.list {
+		if candidate.ParentID != nil && *candidate.ParentID == category.ID {
+			category.Children = append(category.Children, candidate)
+		}
+	}
+
+	var productCount int64
+	if err := h.DB.Model(&models.Product{}).
+		Where("category_id IN (?) AND parent_id IS NULL", h.categorySubtree([]uint{category.ID})).
+		Count(&productCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar categoria"})
+		return
+	}
+
+	var breadcrumb []gin.H
+	for _, ancestor := range tree.ancestors(category.ID) {
+		breadcrumb = append(breadcrumb, gin.H{"id": ancestor.ID, "name": ancestor.Name, "slug": ancestor.Slug})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category":      category,
+		"path":          breadcrumb,
+		"product_count": productCount,
+	})
+}
+
+// CreateCategory cria uma categoria, na raiz ou sob parent_id
+func (h *Handler) CreateCategory(c *gin.Context) {
+	var input models.CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := models.Category{Active: true}
+	var parent *models.Category
+	if input.ParentID != nil {
+		parent = &models.Category{}
+		if err := h.DB.First(parent, *input.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Categoria pai não encontrada"})
+			return
+		}
+	}
+	if status, err := h.applyCategoryInput(&category, parent, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		// O caminho inclui o próprio ID, conhecido só depois de criar
+		category.SetParent(parent)
+		updates := map[string]interface{}{"path": category.Path, "depth": category.Depth}
+		if !category.Active {
+			updates["active"] = false
+		}
+		return tx.Model(&category).Updates(updates).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar categoria"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityCategory, category.ID, models.AuditActionCreate, nil, category)
+
+	c.JSON(http.StatusCreated, gin.H{"category": category})
+}
+
+// UpdateCategory substitui os dados da categoria; sem parent_id ela passa a
+// ser raiz. Mover leva junto toda a subárvore, e o novo nome é replicado nos
+// produtos da categoria.
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	category, ok := h.findCategory(c)
+	if !ok {
+		return
+	}
+
+	var input models.CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var parent *models.Category
+	if input.ParentID != nil {
+		parent = &models.Category{}
+		if err := h.DB.First(parent, *input.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Categoria pai não encontrada"})
+			return
+		}
+		if parent.IsDescendantOf(category) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A categoria não pode ficar sob ela mesma ou uma de suas subcategorias"})
+			return
+		}
+	}
+
+	before := *category
+	if status, err := h.applyCategoryInput(category, parent, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveCategory(tx, category, parent); err != nil {
+			return err
+		}
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		if category.Name != before.Name {
+			return tx.Unscoped().Model(&models.Product{}).Where("category_id = ?", category.ID).
+				Update("category", category.Name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar categoria"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityCategory, category.ID, models.AuditActionUpdate, before, *category)
+
+	c.JSON(http.StatusOK, gin.H{"category": category})
+}
+
+// DeleteCategory remove a categoria. Com produtos ou subcategorias, exige
+// move_to: os produtos passam para a categoria indicada e as subcategorias
+// ficam sob ela, o que também serve para unificar categorias duplicadas.
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	category, ok := h.findCategory(c)
+	if !ok {
+		return
+	}
+
+	var productCount, childCount int64
+	h.DB.Unscoped().Model(&models.Product{}).Where("category_id = ?", category.ID).Count(&productCount)
+	h.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&childCount)
+
+	var target *models.Category
+	if moveTo := c.Query("move_to"); moveTo != "" {
+		target = &models.Category{}
+		if err := h.DB.First(target, moveTo).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Categoria de destino não encontrada"})
+			return
+		}
+		if target.IsDescendantOf(category) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "O destino não pode ser a própria categoria ou uma de suas subcategorias"})
+			return
+		}
+	} else if productCount > 0 || childCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Categoria com produtos ou subcategorias; informe move_to",
+			"product_count":  productCount,
+			"children_count": childCount,
+		})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if target != nil {
+			if err := tx.Unscoped().Model(&models.Product{}).Where("category_id = ?", category.ID).
+				Updates(map[string]interface{}{"category_id": target.ID, "category": target.Name}).Error; err != nil {
+				return err
+			}
+
+			var children []models.Category
+			if err := tx.Where("parent_id = ?", category.ID).Find(&children).Error; err != nil {
+				return err
+			}
+			for i := range children {
+				if err := moveCategory(tx, &children[i], target); err != nil {
+					return err
+				}
+				if err := tx.Save(&children[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Delete(category).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover categoria"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityCategory, category.ID, models.AuditActionDelete, *category, nil)
+
+	response := gin.H{"message": "Categoria removida com sucesso"}
+	if target != nil {
+		response["moved_products"] = productCount
+		response["moved_children"] = childCount
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// moveCategory posiciona a categoria sob parent e corrige o caminho e o
+// nível de todas as descendentes
+func moveCategory(tx *gorm.DB, category *models.Category, parent *models.Category) error {
+	oldPath, oldDepth := category.Path, category.Depth
+	category.SetParent(parent)
+	if category.Path == oldPath {
+		return nil
+	}
+	return tx.Model(&models.Category{}).Unscoped().
+		Where("path LIKE ? AND id <> ?", oldPath+"%", category.ID).
+		Updates(map[string]interface{}{
+			"path":  gorm.Expr("CAST(? AS text) || SUBSTRING(path FROM ?)", category.Path, len(oldPath)+1),
+			"depth": gorm.Expr("depth + ?", category.Depth-oldDepth),
+		}).Error
+}
+
+// findCategory carrega a categoria do parâmetro id, respondendo com o erro
+// quando não encontrada
+func (h *Handler) findCategory(c *gin.Context) (*models.Category, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+
+	var category models.Category
+	if err := h.DB.First(&category, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Categoria não encontrada"})
+		return nil, false
+	}
+	return &category, true
+}
+
+// applyCategoryInput valida os dados de entrada e os copia para a categoria.
+// Sem slug, ele é gerado a partir do slug do pai e do nome. Retorna o status
+// HTTP do erro.
+func (h *Handler) applyCategoryInput(category *models.Category, parent *models.Category, input *models.CategoryInput) (int, error) {
+	name := strings.TrimSpace(input.Name)
+	if models.Slugify(name) == "" {
+		return http.StatusBadRequest, errors.New("Nome inválido")
+	}
+	if strings.Contains(name, ">") {
+		return http.StatusBadRequest, errors.New("O nome não pode conter \">\", usado nos caminhos de categoria")
+	}
+
+	slug := models.Slugify(input.Slug)
+	if slug == "" {
+		slug = models.Slugify(name)
+		if parent != nil {
+			slug = parent.Slug + "-" + slug
+		}
+	}
+
+	var count int64
+	h.DB.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, category.ID).Count(&count)
+	if count > 0 {
+		return http.StatusConflict, errors.New("Slug já usado por outra categoria: " + slug)
+	}
+
+	// Irmãs com o mesmo nome tornariam os caminhos ambíguos
+	siblings := h.DB.Model(&models.Category{}).Where("id <> ?", category.ID)
+	if parent != nil {
+		siblings = siblings.Where("parent_id = ?", parent.ID)
+	} else {
+		siblings = siblings.Where("parent_id IS NULL")
+	}
+	var names []string
+	siblings.Pluck("name", &names)
+	for _, sibling := range names {
+		if models.Slugify(sibling) == models.Slugify(name) {
+			return http.StatusConflict, errors.New("Já existe a categoria " + sibling + " neste nível")
+		}
+	}
+
+	category.Name = name
+	category.Slug = slug
+	category.Position = input.Position
+	if input.Active != nil {
+		category.Active = *input.Active
+	}
+	return 0, nil
+}
@@ -0,0 +1,604 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"loja-online/internal/commission"
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Formato das datas de período nas rotas de comissão
+const periodDateLayout = "2006-01-02"
+
+// parsePeriod converte as datas inclusivas AAAA-MM-DD no intervalo
+// [início, fim) usado nas consultas
+func parsePeriod(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(periodDateLayout, startDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("start_date deve estar no formato AAAA-MM-DD")
+	}
+	end, err := time.ParseInLocation(periodDateLayout, endDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("end_date deve estar no formato AAAA-MM-DD")
+	}
+	end = end.AddDate(0, 0, 1)
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("end_date deve ser posterior a start_date")
+	}
+	return start, end, nil
+}
+
+// inClosedCommissionPeriod indica se a data pertence a um período de comissões fechado
+func (h *Handler) inClosedCommissionPeriod(at time.Time) bool {
+	var count int64
+	h.DB.Model(&models.CommissionPeriod{}).Where("start_date <= ? AND end_date > ?", at, at).Count(&count)
+	return count > 0
+}
+
+// closedPeriodOverlaps indica se o intervalo [from, to) alcança algum período
+// de comissões fechado; limites nulos são abertos
+func (h *Handler) closedPeriodOverlaps(from, to *time.Time) bool {
+	query := h.DB.Model(&models.CommissionPeriod{})
+	if to != nil {
+		query = query.Where("start_date < ?", *to)
+	}
+	if from != nil {
+		query = query.Where("end_date > ?", *from)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// ruleChangedRanges retorna os intervalos de datas cujas comissões mudam ao
+// trocar a regra before por after. Passe nil em before para criações e em
+// after para exclusões; regras inativas contam como ausentes. Se só a
+// vigência muda, são afetados apenas os dias que entram ou saem dela;
+// qualquer outra mudança afeta toda a vigência.
+func ruleChangedRanges(before, after *models.CommissionRule) [][2]*time.Time {
+	if before != nil && !before.Active {
+		before = nil
+	}
+	if after != nil && !after.Active {
+		after = nil
+	}
+	if before == nil && after == nil {
+		return nil
+	}
+	if before == nil {
+		return [][2]*time.Time{{after.ValidFrom, after.ValidTo}}
+	}
+	if after == nil {
+		return [][2]*time.Time{{before.ValidFrom, before.ValidTo}}
+	}
+
+	a, b := *before, *after
+	a.ValidFrom, a.ValidTo, b.ValidFrom, b.ValidTo = nil, nil, nil, nil
+	a.UpdatedAt, b.UpdatedAt = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(a, b) {
+		return [][2]*time.Time{{before.ValidFrom, before.ValidTo}, {after.ValidFrom, after.ValidTo}}
+	}
+
+	var ranges [][2]*time.Time
+	if !sameBound(before.ValidFrom, after.ValidFrom) {
+		ranges = append(ranges, boundRange(before.ValidFrom, after.ValidFrom, false))
+	}
+	if !sameBound(before.ValidTo, after.ValidTo) {
+		ranges = append(ranges, boundRange(before.ValidTo, after.ValidTo, true))
+	}
+	return ranges
+}
+
+// sameBound compara limites de vigência, em que nil é ilimitado
+func sameBound(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// boundRange retorna o intervalo entre dois limites de vigência. Um limite
+// nulo é ilimitado: para o início vale como o passado mais distante e para o
+// fim, como o futuro mais distante.
+func boundRange(a, b *time.Time, isEnd bool) [2]*time.Time {
+	if a == nil {
+		a, b = b, a
+	}
+	if b == nil {
+		if isEnd {
+			return [2]*time.Time{a, nil}
+		}
+		return [2]*time.Time{nil, a}
+	}
+	if b.Before(*a) {
+		a, b = b, a
+	}
+	return [2]*time.Time{a, b}
+}
+
+// checkRuleChange recusa mudanças de regra que alterariam comissões de
+// períodos fechados, retornando o status HTTP do erro
+func (h *Handler) checkRuleChange(before, after *models.CommissionRule) (int, error) {
+	for _, r := range ruleChangedRanges(before, after) {
+		if h.closedPeriodOverlaps(r[0], r[1]) {
+			return http.StatusConflict, errors.New("A alteração alcança um período de comissões fechado; encerre a regra com valid_to e crie outra a partir do período aberto")
+		}
+	}
+	return 0, nil
+}
+
+// calculateCommissions calcula os lançamentos do período a partir das vendas,
+// incluindo estornos de vendas de períodos fechados anulados neste período
+func (h *Handler) calculateCommissions(start, end time.Time, userID *uint) ([]models.CommissionEntry, error) {
+	var rules []models.CommissionRule
+	if err := h.DB.Where("active = ?", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	voided := []string{models.SaleStatusCancelled, models.SaleStatusReturned}
+
+	salesQuery := h.DB.Where("sale_date >= ? AND sale_date < ? AND status NOT IN ?", start, end, voided)
+	if userID != nil {
+		salesQuery = salesQuery.Where("user_id = ?", *userID)
+	}
+
+	var sales []models.Sale
+	if err := salesQuery.Preload("User").Preload("SaleItems.Product").Order("sale_date, id").Find(&sales).Error; err != nil {
+		return nil, err
+	}
+	categories, err := loadCategories(h.DB)
+	if err != nil {
+		return nil, err
+	}
+	entries := commission.Calculate(rules, categories.chains(), sales)
+
+	voidedSales := h.DB.Model(&models.Sale{}).Select("id").
+		Where("cancelled_at >= ? AND cancelled_at < ? AND status IN ?", start, end, voided)
+	closedQuery := h.DB.Where("period_id IS NOT NULL AND type = ? AND sale_id IN (?)", models.CommissionEntrySale, voidedSales)
+	if userID != nil {
+		closedQuery = closedQuery.Where("user_id = ?", *userID)
+	}
+
+	var closed []models.CommissionEntry
+	if err := closedQuery.Order("id").Find(&closed).Error; err != nil {
+		return nil, err
+	}
+
+	return append(entries, commission.Deductions(closed)...), nil
+}
+
+// commissionStatement monta o extrato do intervalo: os dias de períodos
+// fechados vêm dos lançamentos gravados no fechamento e os demais são
+// calculados com as regras atuais. closed indica que todo o intervalo
+// pertence a períodos fechados.
+func (h *Handler) commissionStatement(start, end time.Time, userID *uint) ([]models.CommissionEntry, []models.CommissionPeriod, bool, error) {
+	var periods []models.CommissionPeriod
+	if err := h.DB.Where("start_date < ? AND end_date > ?", end, start).Order("start_date").Find(&periods).Error; err != nil {
+		return nil, nil, false, err
+	}
+
+	var entries []models.CommissionEntry
+	closed := true
+	live := func(from, to time.Time) error {
+		calculated, err := h.calculateCommissions(from, to, userID)
+		entries = append(entries, calculated...)
+		closed = false
+		return err
+	}
+
+	cursor := start
+	for _, period := range periods {
+		if period.StartDate.After(cursor) {
+			if err := live(cursor, period.StartDate); err != nil {
+				return nil, nil, false, err
+			}
+		}
+
+		query := h.DB.Where("period_id = ?", period.ID)
+		if period.StartDate.Before(start) || period.EndDate.After(end) {
+			// Período só em parte no intervalo: vendas pela data da venda e
+			// estornos pela data do cancelamento
+			sold := h.DB.Unscoped().Model(&models.Sale{}).Select("id").Where("sale_date >= ? AND sale_date < ?", start, end)
+			voided := h.DB.Unscoped().Model(&models.Sale{}).Select("id").Where("cancelled_at >= ? AND cancelled_at < ?", start, end)
+			query = query.Where("(type = ? AND sale_id IN (?)) OR (type = ? AND sale_id IN (?))",
+				models.CommissionEntrySale, sold, models.CommissionEntryDeduction, voided)
+		}
+		if userID != nil {
+			query = query.Where("user_id = ?", *userID)
+		}
+
+		var stored []models.CommissionEntry
+		if err := query.Order("id").Find(&stored).Error; err != nil {
+			return nil, nil, false, err
+		}
+		entries = append(entries, stored...)
+		cursor = period.EndDate
+	}
+	if end.After(cursor) {
+		if err := live(cursor, end); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return entries, periods, closed, nil
+}
+
+// GetCommissionStatement retorna o extrato de comissões do período. Dias de
+// períodos fechados usam os lançamentos gravados no fechamento; os demais são
+// calculados na hora. Sem a capacidade reports:commissions, o usuário vê
+// apenas o próprio extrato.
+func (h *Handler) GetCommissionStatement(c *gin.Context) {
+	start, end, err := parsePeriod(c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUserID, _ := middleware.UserIDFromContext(c)
+	var userID *uint
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id inválido"})
+			return
+		}
+		requested := uint(id)
+		userID = &requested
+	}
+	if !middleware.Can(c, models.CapReportsCommissions) {
+		if userID != nil && *userID != currentUserID {
+			middleware.ForbiddenCapability(c, models.CapReportsCommissions)
+			return
+		}
+		userID = &currentUserID
+	}
+
+	entries, periods, closed, err := h.commissionStatement(start, end, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular comissões"})
+		return
+	}
+
+	// Respeita a loja ativa e as lojas visíveis ao usuário
+	entries = filterVisibleStores(c, entries)
+
+	sellers, total := h.groupCommissionsBySeller(entries)
+
+	response := gin.H{
+		"start_date": start.Format(periodDateLayout),
+		"end_date":   end.AddDate(0, 0, -1).Format(periodDateLayout),
+		"closed":     closed,
+		"periods":    periods,
+		"sellers":    sellers,
+		"total":      total,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// commissionSeller agrupa o extrato de um vendedor
+type commissionSeller struct {
+	UserID     uint                     `json:"user_id"`
+	Name       string                   `json:"name"`
+	Revenue    money.Amount             `json:"revenue"`
+	Commission money.Amount             `json:"commission"`
+	Deductions money.Amount             `json:"deductions"`
+	Total      money.Amount             `json:"total"`
+	Entries    []models.CommissionEntry `json:"entries"`
+}
+
+func (h *Handler) groupCommissionsBySeller(entries []models.CommissionEntry) ([]*commissionSeller, money.Amount) {
+	bySeller := make(map[uint]*commissionSeller)
+	var order []uint
+	for _, entry := range entries {
+		seller, ok := bySeller[entry.UserID]
+		if !ok {
+			seller = &commissionSeller{UserID: entry.UserID}
+			bySeller[entry.UserID] = seller
+			order = append(order, entry.UserID)
+		}
+		seller.Entries = append(seller.Entries, entry)
+		if entry.Type == models.CommissionEntryDeduction {
+			seller.Deductions += entry.Amount
+		} else {
+			seller.Revenue += entry.BaseAmount
+			seller.Commission += entry.Amount
+		}
+	}
+
+	var users []models.User
+	if len(order) > 0 {
+		h.DB.Select("id, name").Where("id IN ?", order).Find(&users)
+	}
+	for _, user := range users {
+		if seller, ok := bySeller[user.ID]; ok {
+			seller.Name = user.Name
+		}
+	}
+
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	sellers := make([]*commissionSeller, 0, len(order))
+	var total money.Amount
+	for _, id := range order {
+		seller := bySeller[id]
+		seller.Total = seller.Commission + seller.Deductions
+		total += seller.Total
+		sellers = append(sellers, seller)
+	}
+	return sellers, total
+}
+
+// filterVisibleStores mantém os lançamentos da loja ativa ou das lojas visíveis
+func filterVisibleStores(c *gin.Context, entries []models.CommissionEntry) []models.CommissionEntry {
+	storeID, active := middleware.StoreIDFromContext(c)
+	visible := middleware.StoreIDsFromContext(c)
+	if !active && visible == nil {
+		return entries
+	}
+
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if (active && entry.StoreID == storeID) || (!active && containsStoreID(visible, entry.StoreID)) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// GetCommissionPeriods retorna os períodos fechados
+func (h *Handler) GetCommissionPeriods(c *gin.Context) {
+	var periods []models.CommissionPeriod
+
+	if err := h.DB.Order("start_date DESC").Find(&periods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar períodos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"periods": periods})
+}
+
+// CloseCommissionPeriod fecha o período: os lançamentos calculados são
+// gravados e passam a ser o extrato definitivo. Vendas do período não podem
+// mais ter valores alterados; cancelamentos posteriores geram estornos.
+func (h *Handler) CloseCommissionPeriod(c *gin.Context) {
+	var input models.CommissionPeriodClose
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, end, err := parsePeriod(input.StartDate, input.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if end.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O período só pode ser fechado após o seu término"})
+		return
+	}
+
+	var overlapping int64
+	h.DB.Model(&models.CommissionPeriod{}).Where("start_date < ? AND end_date > ?", end, start).Count(&overlapping)
+	if overlapping > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "O período se sobrepõe a um período já fechado"})
+		return
+	}
+
+	entries, err := h.calculateCommissions(start, end, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular comissões"})
+		return
+	}
+
+	closedByID, _ := middleware.UserIDFromContext(c)
+	period := models.CommissionPeriod{
+		StartDate:  start,
+		EndDate:    end,
+		ClosedByID: closedByID,
+		ClosedAt:   time.Now(),
+	}
+	for _, entry := range entries {
+		period.TotalAmount += entry.Amount
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&period).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		for i := range entries {
+			entries[i].PeriodID = &period.ID
+		}
+		return tx.CreateInBatches(&entries, 500).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao fechar período"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityPeriod, period.ID, models.AuditActionCreate, nil, period)
+
+	sellers, total := h.groupCommissionsBySeller(entries)
+	c.JSON(http.StatusCreated, gin.H{
+		"period":  period,
+		"sellers": sellers,
+		"total":   total,
+	})
+}
+
+// GetCommissionRules retorna as regras de comissão
+func (h *Handler) GetCommissionRules(c *gin.Context) {
+	var rules []models.CommissionRule
+
+	query := h.DB.Order("id")
+	if c.Query("active") == "true" {
+		query = query.Where("active = ?", true)
+	}
+
+	if err := query.Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar regras de comissão"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateCommissionRule cria uma regra de comissão
+func (h *Handler) CreateCommissionRule(c *gin.Context) {
+	var input models.CommissionRuleInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.CommissionRule{Active: true}
+	if status, err := h.applyCommissionRuleInput(&rule, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := h.checkRuleChange(nil, &rule); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar regra de comissão"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityCommission, rule.ID, models.AuditActionCreate, nil, rule)
+
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+// UpdateCommissionRule substitui os dados de uma regra de comissão. A mudança
+// não pode alcançar períodos já fechados.
+func (h *Handler) UpdateCommissionRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var rule models.CommissionRule
+	if err := h.DB.First(&rule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regra de comissão não encontrada"})
+		return
+	}
+
+	var input models.CommissionRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := rule
+	if status, err := h.applyCommissionRuleInput(&rule, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := h.checkRuleChange(&before, &rule); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar regra de comissão"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityCommission, rule.ID, models.AuditActionUpdate, before, rule)
+
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+// DeleteCommissionRule remove uma regra de comissão
+func (h *Handler) DeleteCommissionRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var rule models.CommissionRule
+	if err := h.DB.First(&rule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regra de comissão não encontrada"})
+		return
+	}
+	if status, err := h.checkRuleChange(&rule, nil); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover regra de comissão"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityCommission, rule.ID, models.AuditActionDelete, rule, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Regra de comissão removida com sucesso"})
+}
+
+// applyCommissionRuleInput copia e valida os dados de entrada na regra,
+// retornando o status HTTP do erro
+func (h *Handler) applyCommissionRuleInput(rule *models.CommissionRule, input *models.CommissionRuleInput) (int, error) {
+	if input.ValidFrom != nil && input.ValidTo != nil && !input.ValidTo.After(*input.ValidFrom) {
+		return http.StatusBadRequest, errors.New("valid_to deve ser posterior a valid_from")
+	}
+	if input.Rate == 0 && len(input.Tiers) == 0 {
+		return http.StatusBadRequest, errors.New("Informe rate ou tiers")
+	}
+
+	rule.CategoryID, rule.Category = nil, ""
+	if input.CategoryID != nil || strings.TrimSpace(input.Category) != "" {
+		tree, err := loadCategories(h.DB)
+		if err != nil {
+			return http.StatusInternalServerError, errors.New("Erro ao buscar categorias")
+		}
+
+		var category *models.Category
+		if input.CategoryID != nil {
+			var ok bool
+			if category, ok = tree.byID[*input.CategoryID]; !ok {
+				return http.StatusBadRequest, errors.New("Categoria não encontrada")
+			}
+		} else if category, err = tree.resolve(input.Category); err != nil {
+			return http.StatusBadRequest, err
+		}
+		rule.CategoryID = &category.ID
+		rule.Category = tree.pathName(category.ID)
+	}
+
+	rule.Name = input.Name
+	rule.UserID = input.UserID
+	rule.Role = input.Role
+	rule.Brand = input.Brand
+	rule.ProductID = input.ProductID
+	rule.StoreID = input.StoreID
+	rule.Base = input.Base
+	rule.Rate = input.Rate
+	rule.Tiers = nil
+	if len(input.Tiers) > 0 {
+		rule.Tiers = models.CommissionTiers(input.Tiers)
+	}
+	rule.ValidFrom = input.ValidFrom
+	rule.ValidTo = input.ValidTo
+	if input.Active != nil {
+		rule.Active = *input.Active
+	}
+	return 0, nil
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+func TestRuleChangedRanges(t *testing.T) {
+	day := func(d int) *time.Time {
+		at := time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC)
+		return &at
+	}
+	rule := models.CommissionRule{ID: 1, Name: "Geral", Base: models.CommissionBaseRevenue, Rate: money.BasisPoints(5_00), ValidFrom: day(1), ValidTo: day(20), Active: true}
+
+	tests := []struct {
+		name   string
+		before *models.CommissionRule
+		after  func(r models.CommissionRule) *models.CommissionRule
+		want   [][2]*time.Time
+	}{
+		{
+			name:   "criação afeta a vigência",
+			before: nil,
+			after:  func(r models.CommissionRule) *models.CommissionRule { return &r },
+			want:   [][2]*time.Time{{day(1), day(20)}},
+		},
+		{
+			name:   "exclusão afeta a vigência",
+			before: &rule,
+			after:  func(models.CommissionRule) *models.CommissionRule { return nil },
+			want:   [][2]*time.Time{{day(1), day(20)}},
+		},
+		{
+			name:   "percentual afeta a vigência antiga e a nova",
+			before: &rule,
+			after: func(r models.CommissionRule) *models.CommissionRule {
+				r.Rate = money.BasisPoints(7_00)
+				r.ValidTo = day(25)
+				return &r
+			},
+			want: [][2]*time.Time{{day(1), day(20)}, {day(1), day(25)}},
+		},
+		{
+			name:   "encerrar a regra afeta só os dias retirados",
+			before: &rule,
+			after:  func(r models.CommissionRule) *models.CommissionRule { r.ValidTo = day(10); return &r },
+			want:   [][2]*time.Time{{day(10), day(20)}},
+		},
+		{
+			name:   "fim ilimitado",
+			before: &rule,
+			after:  func(r models.CommissionRule) *models.CommissionRule { r.ValidTo = nil; return &r },
+			want:   [][2]*time.Time{{day(20), nil}},
+		},
+		{
+			name:   "início ilimitado",
+			before: &rule,
+			after:  func(r models.CommissionRule) *models.CommissionRule { r.ValidFrom = nil; return &r },
+			want:   [][2]*time.Time{{nil, day(1)}},
+		},
+		{
+			name:   "desativar afeta a vigência",
+			before: &rule,
+			after:  func(r models.CommissionRule) *models.CommissionRule { r.Active = false; return &r },
+			want:   [][2]*time.Time{{day(1), day(20)}},
+		},
+		{
+			name:   "regra inativa não afeta nada",
+			before: &models.CommissionRule{ID: 1, Rate: money.BasisPoints(5_00)},
+			after: func(models.CommissionRule) *models.CommissionRule {
+				return &models.CommissionRule{ID: 1, Rate: money.BasisPoints(9_00)}
+			},
+			want: nil,
+		},
+		{
+			name:   "sem mudança",
+			before: &rule,
+			after:  func(r models.CommissionRule) *models.CommissionRule { r.UpdatedAt = time.Now(); return &r },
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		got := ruleChangedRanges(tt.before, tt.after(rule))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: intervalos = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
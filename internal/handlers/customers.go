@@ -3,22 +3,67 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"loja-online/internal/models"
+	"loja-online/internal/pagination"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// GetCustomers retorna todos os clientes
+// customerSort são os campos pelos quais a listagem de clientes pode ser ordenada
+var customerSort = &pagination.Spec[models.Customer]{
+	DefaultSort: "id",
+	Fields: map[string]pagination.Field[models.Customer]{
+		"id":         {Column: "id", Value: func(c *models.Customer) any { return c.ID }},
+		"name":       {Column: "name", Value: func(c *models.Customer) any { return c.Name }},
+		"email":      {Column: "COALESCE(email, '')", Value: func(c *models.Customer) any { return c.Email }},
+		"cpf":        {Column: "COALESCE(cpf, '')", Value: func(c *models.Customer) any { return c.CPF }},
+		"gender":     {Column: "COALESCE(gender, '')", Value: func(c *models.Customer) any { return c.Gender }},
+		"active":     {Column: "COALESCE(active, false)", Value: func(c *models.Customer) any { return c.Active }},
+		"created_at": {Column: "created_at", Value: func(c *models.Customer) any { return c.CreatedAt }},
+		"updated_at": {Column: "updated_at", Value: func(c *models.Customer) any { return c.UpdatedAt }},
+	},
+}
+
+// GetCustomers retorna os clientes, filtrados, ordenados e paginados. search
+// filtra por nome, e-mail, CPF ou telefone.
 func (h *Handler) GetCustomers(c *gin.Context) {
 	var customers []models.Customer
+	var total int64
+
+	page, ok := listPage(c, customerSort)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Model(&models.Customer{})
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR email ILIKE ? OR cpf ILIKE ? OR phone ILIKE ?", pattern, pattern, pattern, pattern)
+	}
+	query = filterValues(c, query, "gender", "gender")
 
-	if err := h.DB.Preload("Addresses").Find(&customers).Error; err != nil {
+	query, err := filterBool(c, query, "active", "active")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar clientes"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"customers": customers})
+	if err := page.Apply(query).Preload("Addresses").Find(&customers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar clientes"})
+		return
+	}
+	customers = page.Trim(customers)
+
+	c.JSON(http.StatusOK, listResponse("customers", customers, page.Meta(customers, total)))
 }
 
 // GetCustomer retorna um cliente específico
@@ -52,6 +97,8 @@ func (h *Handler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
+	h.recordAudit(c, auditEntityCustomer, customer.ID, models.AuditActionCreate, nil, customer)
+
 	c.JSON(http.StatusCreated, gin.H{"customer": customer})
 }
 
@@ -75,11 +122,15 @@ func (h *Handler) UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	before := customer
 	if err := h.DB.Model(&customer).Updates(updateData).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar cliente"})
 		return
 	}
 
+	h.DB.First(&customer, id)
+	h.recordAudit(c, auditEntityCustomer, customer.ID, models.AuditActionUpdate, before, customer)
+
 	c.JSON(http.StatusOK, gin.H{"customer": customer})
 }
 
@@ -91,10 +142,18 @@ func (h *Handler) DeleteCustomer(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Delete(&models.Customer{}, id).Error; err != nil {
+	var customer models.Customer
+	if err := h.DB.First(&customer, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cliente não encontrado"})
+		return
+	}
+
+	if err := h.DB.Delete(&customer).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao deletar cliente"})
 		return
 	}
 
+	h.recordAudit(c, auditEntityCustomer, customer.ID, models.AuditActionDelete, customer, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Cliente deletado com sucesso"})
 }
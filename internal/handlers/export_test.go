@@ -0,0 +1,8 @@
+package handlers
+
+import "loja-online/internal/models"
+
+// SendPasswordReset expõe o envio do email de redefinição aos testes
+func (h *Handler) SendPasswordReset(user models.User, token string) error {
+	return h.sendPasswordReset(user, token)
+}
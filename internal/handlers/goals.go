@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// goalProgress é o acompanhamento de uma meta até o momento da consulta.
+// Current, Remaining e Projected estão na unidade da meta: reais ou peças.
+type goalProgress struct {
+	Goal           models.Goal  `json:"goal"`
+	Current        goalValue    `json:"current"`
+	Percent        float64      `json:"percent"`
+	Remaining      goalValue    `json:"remaining"`
+	ElapsedPercent float64      `json:"elapsed_percent"`
+	Projected      goalValue    `json:"projected"` // Valor esperado ao fim do período no ritmo atual
+	OnTrack        bool         `json:"on_track"`
+	SalesCount     int64        `json:"sales_count"`
+	Revenue        money.Amount `json:"revenue"`
+	Units          int64        `json:"units"`
+}
+
+// goalValue é um valor na unidade da meta: centavos nas metas em reais, que
+// vão para o JSON como reais, ou peças na meta de unidades
+type goalValue struct {
+	value int64
+	units bool
+}
+
+// MarshalJSON grava reais com duas casas e peças como número inteiro
+func (v goalValue) MarshalJSON() ([]byte, error) {
+	if v.units {
+		return []byte(strconv.FormatInt(v.value, 10)), nil
+	}
+	return money.FromCents(v.value).MarshalJSON()
+}
+
+// GetGoalProgress retorna o progresso das metas vigentes na data (hoje, por
+// padrão), calculado a partir das vendas. Sem a capacidade reports:goals, o
+// usuário vê as próprias metas e as das suas lojas.
+func (h *Handler) GetGoalProgress(c *gin.Context) {
+	at := time.Now()
+	if value := c.Query("date"); value != "" {
+		date, err := time.ParseInLocation(periodDateLayout, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date deve estar no formato AAAA-MM-DD"})
+			return
+		}
+		// Considera o dia inteiro informado
+		at = date.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	query := h.DB.Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id, name") }).
+		Preload("Store").
+		Where("start_date <= ? AND end_date > ?", at, at)
+
+	if !middleware.Can(c, models.CapReportsGoals) {
+		userID, _ := middleware.UserIDFromContext(c)
+		if visible := middleware.StoreIDsFromContext(c); visible != nil {
+			query = query.Where("user_id = ? OR (user_id IS NULL AND store_id IN ?)", userID, visible)
+		} else {
+			query = query.Where("user_id = ? OR user_id IS NULL", userID)
+		}
+	} else if visible := middleware.StoreIDsFromContext(c); visible != nil {
+		query = query.Where("store_id IS NULL OR store_id IN ?", visible)
+	}
+
+	// Com loja ativa, mostra as metas da loja e as metas gerais dos vendedores
+	if storeID, ok := middleware.StoreIDFromContext(c); ok {
+		query = query.Where("store_id IS NULL OR store_id = ?", storeID)
+	}
+	if value := c.Query("user_id"); value != "" {
+		query = query.Where("user_id = ?", value)
+	}
+
+	var goals []models.Goal
+	if err := query.Order("end_date, id").Find(&goals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar metas"})
+		return
+	}
+
+	progress := make([]goalProgress, 0, len(goals))
+	for _, goal := range goals {
+		item, err := h.goalProgress(goal, at)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular progresso das metas"})
+			return
+		}
+		progress = append(progress, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":  at.Format(periodDateLayout),
+		"goals": progress,
+	})
+}
+
+// goalProgress soma as vendas válidas do período da meta até at
+func (h *Handler) goalProgress(goal models.Goal, at time.Time) (goalProgress, error) {
+	progress := goalProgress{Goal: goal}
+
+	sales := h.DB.Model(&models.Sale{}).
+		Where("sale_date >= ? AND sale_date < ?", goal.StartDate, goal.EndDate).
+		Where("status NOT IN ?", []string{models.SaleStatusCancelled, models.SaleStatusReturned})
+	if goal.UserID != nil {
+		sales = sales.Where("user_id = ?", *goal.UserID)
+	}
+	if goal.StoreID != nil {
+		sales = sales.Where("store_id = ?", *goal.StoreID)
+	}
+	sales = sales.Session(&gorm.Session{})
+
+	if err := sales.Select("COUNT(*), COALESCE(SUM(final_amount), 0)").Row().
+		Scan(&progress.SalesCount, &progress.Revenue); err != nil {
+		return progress, err
+	}
+	if err := h.DB.Model(&models.SaleItem{}).
+		Where("sale_id IN (?)", sales.Select("id")).
+		Select("COALESCE(SUM(quantity), 0)").Row().
+		Scan(&progress.Units); err != nil {
+		return progress, err
+	}
+
+	// O ticket médio não acumula ao longo do período; o atual já é a projeção
+	elapsed, total := goal.Elapsed(at)
+	var current, target, projected int64
+	switch goal.Metric {
+	case models.GoalMetricRevenue:
+		current, target = progress.Revenue.Cents(), goal.Target.Cents()
+		projected = current
+		if elapsed > 0 {
+			projected = progress.Revenue.Fraction(int64(total), int64(elapsed)).Cents()
+		}
+	case models.GoalMetricUnits:
+		current, target = progress.Units, goal.TargetUnits
+		projected = current
+		if elapsed > 0 {
+			projected = int64(math.Round(float64(current) * float64(total) / float64(elapsed)))
+		}
+	case models.GoalMetricAverageTicket:
+		if progress.SalesCount > 0 {
+			current = progress.Revenue.Fraction(1, progress.SalesCount).Cents()
+		}
+		target, projected = goal.Target.Cents(), current
+	}
+
+	units := goal.Metric == models.GoalMetricUnits
+	progress.Current = goalValue{current, units}
+	progress.Projected = goalValue{projected, units}
+	progress.Remaining = goalValue{max(target-current, 0), units}
+	if target > 0 {
+		progress.Percent = round2(float64(current) / float64(target) * 100)
+	}
+	progress.ElapsedPercent = round2(goal.ElapsedFraction(at) * 100)
+	progress.OnTrack = projected >= target
+	return progress, nil
+}
+
+// GetGoals retorna as metas cadastradas. Filtros: user_id, store_id e
+// date (metas vigentes na data).
+func (h *Handler) GetGoals(c *gin.Context) {
+	var goals []models.Goal
+
+	query := h.DB.Order("start_date DESC, id")
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if storeID := c.Query("store_id"); storeID != "" {
+		query = query.Where("store_id = ?", storeID)
+	}
+	if value := c.Query("date"); value != "" {
+		date, err := time.ParseInLocation(periodDateLayout, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date deve estar no formato AAAA-MM-DD"})
+			return
+		}
+		query = query.Where("start_date <= ? AND end_date > ?", date, date)
+	}
+	if visible := middleware.StoreIDsFromContext(c); visible != nil {
+		query = query.Where("store_id IS NULL OR store_id IN ?", visible)
+	}
+
+	if err := query.Find(&goals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar metas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"goals": goals})
+}
+
+// CreateGoal cria uma meta
+func (h *Handler) CreateGoal(c *gin.Context) {
+	var input models.GoalInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var goal models.Goal
+	if status, err := h.applyGoalInput(c, &goal, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	goal.CreatedByID, _ = middleware.UserIDFromContext(c)
+
+	if err := h.DB.Create(&goal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar meta"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityGoal, goal.ID, models.AuditActionCreate, nil, goal)
+
+	c.JSON(http.StatusCreated, gin.H{"goal": goal})
+}
+
+// UpdateGoal substitui os dados de uma meta
+func (h *Handler) UpdateGoal(c *gin.Context) {
+	goal, ok := h.findGoal(c)
+	if !ok {
+		return
+	}
+
+	var input models.GoalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := goal
+	if status, err := h.applyGoalInput(c, &goal, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(&goal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar meta"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityGoal, goal.ID, models.AuditActionUpdate, before, goal)
+
+	c.JSON(http.StatusOK, gin.H{"goal": goal})
+}
+
+// DeleteGoal remove uma meta
+func (h *Handler) DeleteGoal(c *gin.Context) {
+	goal, ok := h.findGoal(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(&goal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover meta"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityGoal, goal.ID, models.AuditActionDelete, goal, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meta removida com sucesso"})
+}
+
+// findGoal carrega a meta do parâmetro :id, respeitando as lojas visíveis
+func (h *Handler) findGoal(c *gin.Context) (models.Goal, bool) {
+	var goal models.Goal
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return goal, false
+	}
+
+	query := h.DB
+	if visible := middleware.StoreIDsFromContext(c); visible != nil {
+		query = query.Where("store_id IS NULL OR store_id IN ?", visible)
+	}
+	if err := query.First(&goal, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meta não encontrada"})
+		return goal, false
+	}
+	return goal, true
+}
+
+// applyGoalInput valida os dados de entrada e os copia para a meta,
+// retornando o status HTTP do erro
+func (h *Handler) applyGoalInput(c *gin.Context, goal *models.Goal, input *models.GoalInput) (int, error) {
+	var start, end time.Time
+	if input.Month != "" {
+		month, err := time.ParseInLocation("2006-01", input.Month, time.Local)
+		if err != nil {
+			return http.StatusBadRequest, errors.New("month deve estar no formato AAAA-MM")
+		}
+		start, end = month, month.AddDate(0, 1, 0)
+	} else {
+		var err error
+		if start, end, err = parsePeriod(input.StartDate, input.EndDate); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	if input.UserID != nil {
+		var count int64
+		h.DB.Model(&models.User{}).Where("id = ?", *input.UserID).Count(&count)
+		if count == 0 {
+			return http.StatusBadRequest, errors.New("Usuário não encontrado")
+		}
+	}
+	if input.StoreID != nil {
+		var count int64
+		h.DB.Model(&models.Store{}).Where("id = ?", *input.StoreID).Count(&count)
+		if count == 0 {
+			return http.StatusBadRequest, errors.New("Loja não encontrada")
+		}
+		if visible := middleware.StoreIDsFromContext(c); visible != nil && !containsStoreID(visible, *input.StoreID) {
+			return http.StatusForbidden, errors.New("Acesso à loja negado")
+		}
+	}
+
+	// A meta de unidades é em peças; as demais, em reais
+	goal.Target, goal.TargetUnits = 0, 0
+	if input.Metric == models.GoalMetricUnits {
+		if input.TargetUnits <= 0 {
+			return http.StatusBadRequest, errors.New("Informe target_units, a meta em peças")
+		}
+		goal.TargetUnits = input.TargetUnits
+	} else {
+		if input.Target <= 0 {
+			return http.StatusBadRequest, errors.New("Informe target, a meta em reais")
+		}
+		goal.Target = input.Target
+	}
+
+	goal.Name = input.Name
+	goal.UserID = input.UserID
+	goal.StoreID = input.StoreID
+	goal.Metric = input.Metric
+	goal.StartDate = start
+	goal.EndDate = end
+	return 0, nil
+}
+
+// round2 arredonda os percentuais da meta para duas casas decimais
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/dbtest"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+// salesTotals responde às somas de vendas e de peças do progresso da meta
+func salesTotals(fake *dbtest.DB, count int64, revenue string, units int64) {
+	fake.On("COALESCE(SUM(final_amount), 0)").Rows([]string{"count", "sum"}, []interface{}{count, revenue})
+	fake.On("COALESCE(SUM(quantity), 0)").Rows([]string{"sum"}, []interface{}{units})
+}
+
+func TestGoalProgress(t *testing.T) {
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	month := models.Goal{StartDate: start, EndDate: start.AddDate(0, 1, 0)}
+	// Metade do período de 30 dias
+	at := start.AddDate(0, 0, 15)
+
+	tests := []struct {
+		name      string
+		metric    string
+		target    money.Amount
+		units     int64
+		current   string
+		percent   float64
+		remaining string
+		projected string
+		onTrack   bool
+	}{
+		{"faturamento abaixo do ritmo", models.GoalMetricRevenue, money.FromCents(3000_00), 0, "1200.00", 40, "1800.00", "2400.00", false},
+		{"peças acima do ritmo", models.GoalMetricUnits, 0, 15, "10", 66.67, "5", "20", true},
+		{"ticket médio acima da meta", models.GoalMetricAverageTicket, money.FromCents(250_00), 0, "300.00", 120, "0.00", "300.00", true},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		salesTotals(fake, 4, "1200.00", 10)
+
+		goal := month
+		goal.Metric, goal.Target, goal.TargetUnits = tt.metric, tt.target, tt.units
+		progress, err := h.goalProgress(goal, at)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		values := map[string]goalValue{"atual": progress.Current, "restante": progress.Remaining, "projetado": progress.Projected}
+		wants := map[string]string{"atual": tt.current, "restante": tt.remaining, "projetado": tt.projected}
+		for label, value := range values {
+			data, _ := json.Marshal(value)
+			if string(data) != wants[label] {
+				t.Errorf("%s: %s = %s, esperado %s", tt.name, label, data, wants[label])
+			}
+		}
+		if progress.Percent != tt.percent {
+			t.Errorf("%s: percentual = %v, esperado %v", tt.name, progress.Percent, tt.percent)
+		}
+		if progress.ElapsedPercent != 50 {
+			t.Errorf("%s: período decorrido = %v%%, esperado 50%%", tt.name, progress.ElapsedPercent)
+		}
+		if progress.OnTrack != tt.onTrack {
+			t.Errorf("%s: no ritmo = %v, esperado %v", tt.name, progress.OnTrack, tt.onTrack)
+		}
+		if progress.SalesCount != 4 || progress.Revenue != money.FromCents(1200_00) || progress.Units != 10 {
+			t.Errorf("%s: totais = %d vendas, %s, %d peças", tt.name, progress.SalesCount, progress.Revenue, progress.Units)
+		}
+	}
+}
+
+func TestGoalProgressWithoutSales(t *testing.T) {
+	h, fake := newTestHandler(t)
+	salesTotals(fake, 0, "0", 0)
+
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	goal := models.Goal{Metric: models.GoalMetricAverageTicket, Target: money.FromCents(250_00), StartDate: start, EndDate: start.AddDate(0, 1, 0)}
+	progress, err := h.goalProgress(goal, start.AddDate(0, 0, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Current.value != 0 || progress.Percent != 0 || progress.OnTrack {
+		t.Errorf("sem vendas: atual %d, percentual %v, no ritmo %v", progress.Current.value, progress.Percent, progress.OnTrack)
+	}
+}
+
+// Vendas canceladas e devolvidas não contam, nem no valor nem nas peças, e
+// a meta só considera o vendedor e a loja dela
+func TestGoalProgressFilters(t *testing.T) {
+	h, fake := newTestHandler(t)
+	salesTotals(fake, 0, "0", 0)
+
+	userID, storeID := uint(3), uint(2)
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	goal := models.Goal{Metric: models.GoalMetricUnits, TargetUnits: 10, UserID: &userID, StoreID: &storeID, StartDate: start, EndDate: start.AddDate(0, 1, 0)}
+	if _, err := h.goalProgress(goal, start.AddDate(0, 0, 15)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fragment := range []string{"SUM(final_amount)", "SUM(quantity)"} {
+		queries := fake.Queries(fragment)
+		if len(queries) != 1 {
+			t.Fatalf("%s: %d consultas, esperada 1", fragment, len(queries))
+		}
+		query := queries[0]
+		for _, condition := range []string{"status NOT IN ($3,$4)", "user_id = $5", "store_id = $6", "sale_date >= $1 AND sale_date < $2"} {
+			if !strings.Contains(query.SQL, condition) {
+				t.Errorf("%s: falta a condição %q em %s", fragment, condition, query.SQL)
+			}
+		}
+		want := []interface{}{start, goal.EndDate, models.SaleStatusCancelled, models.SaleStatusReturned, int64(3), int64(2)}
+		for i, arg := range want {
+			if i >= len(query.Args) || query.Args[i] != arg {
+				t.Errorf("%s: argumento %d = %v, esperado %v", fragment, i+1, query.Args, arg)
+				break
+			}
+		}
+	}
+}
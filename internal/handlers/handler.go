@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"strings"
+
 	"loja-online/internal/config"
+	"loja-online/internal/jwtkeys"
+	"loja-online/internal/mailer"
+	"loja-online/internal/oidc"
+	"loja-online/internal/storage"
 
 	"gorm.io/gorm"
 )
@@ -10,12 +16,38 @@ import (
 type Handler struct {
 	DB     *gorm.DB
 	Config *config.Config
+	Mailer mailer.Mailer
+	// Chaves que assinam os tokens de acesso
+	Keys *jwtkeys.Manager
+	// Cliente do login único; nil quando OIDC não está configurado
+	OIDC *oidc.Provider
+	// Armazenamento das imagens de produtos
+	Storage storage.Storage
 }
 
 // New cria uma nova instância do Handler
-func New(db *gorm.DB, config *config.Config) *Handler {
-	return &Handler{
-		DB:     db,
-		Config: config,
+func New(db *gorm.DB, config *config.Config, keys *jwtkeys.Manager) *Handler {
+	h := &Handler{
+		DB:      db,
+		Config:  config,
+		Mailer:  mailer.New(config),
+		Keys:    keys,
+		Storage: storage.New(config),
+	}
+
+	if config.OIDCIssuer != "" {
+		redirectURL := config.OIDCRedirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimSuffix(config.AppBaseURL, "/") + "/api/v1/auth/oidc/callback"
+		}
+		h.OIDC = oidc.NewProvider(oidc.Config{
+			Issuer:       config.OIDCIssuer,
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       strings.Fields(config.OIDCScopes),
+		})
 	}
+
+	return h
 }
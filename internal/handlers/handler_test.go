@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loja-online/internal/config"
+	"loja-online/internal/dbtest"
+	"loja-online/internal/jwtkeys"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyStore guarda as chaves de assinatura em memória
+type keyStore struct {
+	keys []models.SigningKey
+}
+
+func (s *keyStore) List() ([]models.SigningKey, error) {
+	return s.keys, nil
+}
+
+func (s *keyStore) Replace(previous string, key *models.SigningKey, at time.Time) (bool, error) {
+	s.keys = append([]models.SigningKey{*key}, s.keys...)
+	return true, nil
+}
+
+func (s *keyStore) DeleteRetiredBefore(time.Time) error {
+	return nil
+}
+
+// newTestHandler cria o Handler sobre o banco falso do dbtest
+func newTestHandler(t *testing.T) (*Handler, *dbtest.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, fake := dbtest.Open(t)
+	keys, err := jwtkeys.NewManager(&keyStore{}, jwtkeys.Options{
+		Algorithm:        jwtkeys.AlgorithmEdDSA,
+		RotationInterval: 24 * time.Hour,
+		RetiredKeyTTL:    time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+		MailDriver:      "log",
+		MailLogFile:     filepath.Join(t.TempDir(), "emails.log"),
+		RateLimitStore:  "memory",
+	}
+	return New(db, cfg, keys), fake
+}
+
+// serve executa o handler com body em JSON; setup prepara o contexto, como
+// faria o middleware de autenticação
+func serve(handler gin.HandlerFunc, method, target string, body interface{}, setup func(c *gin.Context)) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			panic(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, &payload)
+	c.Request.Header.Set("Content-Type", "application/json")
+	if setup != nil {
+		setup(c)
+	}
+	handler(c)
+	return w
+}
+
+// asUser autentica a requisição como o usuário e a sessão informados, com
+// os IDs em float64 como chegam nas claims do JWT
+func asUser(userID, sessionID uint, role string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Set("user_id", float64(userID))
+		c.Set("session_id", float64(sessionID))
+		c.Set("role", role)
+	}
+}
+
+// decode lê a resposta JSON
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("resposta não é JSON: %s", w.Body.String())
+	}
+	return body
+}
+
+// contains indica se value está entre os argumentos do comando
+func contains(args []driver.Value, value driver.Value) bool {
+	for _, arg := range args {
+		if arg == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/imaging"
+	"loja-online/internal/models"
+	"loja-online/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Máximo de imagens por envio
+const maxImagesPerUpload = 10
+
+// GetProductImages retorna as imagens do produto na ordem de exibição
+func (h *Handler) GetProductImages(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	var images []models.ProductImage
+	if err := h.DB.Where("product_id = ?", product.ID).Order("position, id").Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar imagens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"images": images})
+}
+
+// UploadProductImages recebe imagens em multipart/form-data no campo
+// "images" (um ou mais arquivos). O tipo é validado pelo conteúdo, e cada
+// imagem é gravada no Storage com as miniaturas configuradas. Com
+// primary=true, a primeira imagem enviada passa a ser a principal.
+func (h *Handler) UploadProductImages(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	maxSize := int64(h.Config.ImageMaxSize)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize*maxImagesPerUpload+1<<20)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Envio acima do tamanho máximo"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envie as imagens em multipart/form-data no campo images"})
+		return
+	}
+	files := form.File["images"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nenhuma imagem enviada no campo images"})
+		return
+	}
+	if len(files) > maxImagesPerUpload {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Envie no máximo %d imagens por vez", maxImagesPerUpload)})
+		return
+	}
+
+	// Valida todos os arquivos antes de gravar qualquer um
+	type upload struct {
+		name string
+		data []byte
+	}
+	uploads := make([]upload, 0, len(files))
+	for _, file := range files {
+		if file.Size > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":    fmt.Sprintf("Imagem acima do tamanho máximo de %d bytes", maxSize),
+				"filename": file.Filename,
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler imagem", "filename": file.Filename})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+		f.Close()
+		if err != nil || int64(len(data)) > maxSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Imagem acima do tamanho máximo", "filename": file.Filename})
+			return
+		}
+		uploads = append(uploads, upload{name: file.Filename, data: data})
+	}
+
+	var position int
+	var count int64
+	h.DB.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).Count(&count)
+	h.DB.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).
+		Select("COALESCE(MAX(position), -1) + 1").Row().Scan(&position)
+
+	images := make([]models.ProductImage, 0, len(uploads))
+	var stored []string
+	for i, item := range uploads {
+		image, keys, err := h.storeImage(c, product.ID, item.data)
+		stored = append(stored, keys...)
+		if err != nil {
+			h.deleteStoredFiles(c, stored)
+			if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error(), "filename": item.name})
+				return
+			}
+			log.Printf("Erro ao gravar imagem do produto %d: %v", product.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gravar imagem", "filename": item.name})
+			return
+		}
+		image.Position = position + i
+		images = append(images, *image)
+	}
+
+	makePrimary := count == 0 || c.PostForm("primary") == "true"
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&images).Error; err != nil {
+			return err
+		}
+		if makePrimary {
+			return setPrimaryImage(tx, product, &images[0])
+		}
+		return nil
+	})
+	if err != nil {
+		h.deleteStoredFiles(c, stored)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar imagens"})
+		return
+	}
+
+	for i := range images {
+		images[i].FillURLs()
+		h.recordAudit(c, auditEntityImage, images[i].ID, models.AuditActionCreate, nil, images[i])
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"images": images})
+}
+
+// storeImage decodifica a imagem, gera as miniaturas e grava tudo no Storage.
+// Retorna as chaves gravadas, mesmo em caso de erro, para a limpeza.
+func (h *Handler) storeImage(c *gin.Context, productID uint, data []byte) (*models.ProductImage, []string, error) {
+	img, contentType, err := imaging.Decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix := fmt.Sprintf("products/%d/%s/", productID, token[:16])
+
+	image := &models.ProductImage{
+		ProductID:   productID,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Files:       models.ImageFiles{},
+	}
+
+	var stored []string
+	ctx := c.Request.Context()
+
+	key := prefix + models.ImageFileOriginal + imaging.Extension(contentType)
+	if err := h.Storage.Put(ctx, key, data, contentType); err != nil {
+		return nil, stored, err
+	}
+	stored = append(stored, key)
+	image.Files[models.ImageFileOriginal] = key
+
+	for _, size := range h.thumbnailSizes() {
+		thumbnail, thumbnailType, ext, err := imaging.Encode(imaging.Fit(img, size), contentType)
+		if err != nil {
+			return nil, stored, err
+		}
+		key := prefix + strconv.Itoa(size) + ext
+		if err := h.Storage.Put(ctx, key, thumbnail, thumbnailType); err != nil {
+			return nil, stored, err
+		}
+		stored = append(stored, key)
+		image.Files[strconv.Itoa(size)] = key
+	}
+
+	return image, stored, nil
+}
+
+// thumbnailSizes lê as dimensões das miniaturas configuradas
+func (h *Handler) thumbnailSizes() []int {
+	var sizes []int
+	for _, value := range strings.Split(h.Config.ImageThumbnailSizes, ",") {
+		if size, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+// deleteStoredFiles remove arquivos do Storage; falhas só são registradas
+func (h *Handler) deleteStoredFiles(c *gin.Context, keys []string) {
+	for _, key := range keys {
+		if err := h.Storage.Delete(c.Request.Context(), key); err != nil {
+			log.Printf("Erro ao remover arquivo %s: %v", key, err)
+		}
+	}
+}
+
+// ReorderProductImages define a ordem de exibição a partir da lista de IDs,
+// que deve conter todas as imagens do produto
+func (h *Handler) ReorderProductImages(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	var input models.ProductImageOrder
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var images []models.ProductImage
+	h.DB.Where("product_id = ?", product.ID).Find(&images)
+	ids := uniqueIDs(input.ImageIDs)
+	if len(ids) != len(input.ImageIDs) || len(ids) != len(images) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe todas as imagens do produto, sem repetição"})
+		return
+	}
+	owned := make(map[uint]bool, len(images))
+	for _, image := range images {
+		owned[image.ID] = true
+	}
+	for _, id := range ids {
+		if !owned[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Imagem não pertence ao produto", "image_id": id})
+			return
+		}
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(&models.ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ordenar imagens"})
+		return
+	}
+
+	befores := make(map[uint]models.ProductImage, len(images))
+	for _, image := range images {
+		befores[image.ID] = image
+	}
+	h.DB.Where("product_id = ?", product.ID).Order("position, id").Find(&images)
+	// Imagens que não mudaram de posição não geram registro
+	for _, image := range images {
+		h.recordAudit(c, auditEntityImage, image.ID, models.AuditActionUpdate, befores[image.ID], image)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"images": images})
+}
+
+// SetPrimaryProductImage define a imagem principal do produto
+func (h *Handler) SetPrimaryProductImage(c *gin.Context) {
+	product, image, ok := h.findProductImage(c)
+	if !ok {
+		return
+	}
+
+	before := *product
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return setPrimaryImage(tx, product, image)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao definir imagem principal"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityProduct, product.ID, models.AuditActionUpdate, before, *product)
+
+	c.JSON(http.StatusOK, gin.H{"image": image})
+}
+
+// DeleteProductImage remove a imagem e seus arquivos. Se era a principal, a
+// próxima na ordem assume o lugar.
+func (h *Handler) DeleteProductImage(c *gin.Context) {
+	product, image, ok := h.findProductImage(c)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(image).Error; err != nil {
+			return err
+		}
+		if !image.IsPrimary {
+			return nil
+		}
+
+		var next models.ProductImage
+		err := tx.Where("product_id = ?", product.ID).Order("position, id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Model(product).Update("image_url", "").Error
+		}
+		if err != nil {
+			return err
+		}
+		return setPrimaryImage(tx, product, &next)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover imagem"})
+		return
+	}
+
+	keys := make([]string, 0, len(image.Files))
+	for _, key := range image.Files {
+		keys = append(keys, key)
+	}
+	h.deleteStoredFiles(c, keys)
+
+	h.recordAudit(c, auditEntityImage, image.ID, models.AuditActionDelete, *image, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Imagem removida com sucesso"})
+}
+
+// setPrimaryImage marca a imagem como principal e copia a URL para o produto
+func setPrimaryImage(tx *gorm.DB, product *models.Product, image *models.ProductImage) error {
+	if err := tx.Model(&models.ProductImage{}).
+		Where("product_id = ? AND id <> ?", product.ID, image.ID).
+		Update("is_primary", false).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(image).Update("is_primary", true).Error; err != nil {
+		return err
+	}
+
+	image.FillURLs()
+	product.ImageURL = image.URL
+	return tx.Model(product).Update("image_url", image.URL).Error
+}
+
+// ServeMedia entrega um arquivo do Storage. As chaves são únicas por envio,
+// então o conteúdo pode ficar em cache indefinidamente.
+func (h *Handler) ServeMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	object, err := h.Storage.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arquivo não encontrado"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao ler arquivo %s: %v", key, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Erro ao ler arquivo"})
+		return
+	}
+	defer object.Body.Close()
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, nil)
+}
+
+// findProduct carrega o produto do parâmetro :id
+func (h *Handler) findProduct(c *gin.Context) (*models.Product, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+
+	var product models.Product
+	if err := h.DB.First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
+		return nil, false
+	}
+	return &product, true
+}
+
+// findProductImage carrega o produto de :id e a imagem de :image_id
+func (h *Handler) findProductImage(c *gin.Context) (*models.Product, *models.ProductImage, bool) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	imageID, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da imagem inválido"})
+		return nil, nil, false
+	}
+
+	var image models.ProductImage
+	if err := h.DB.Where("product_id = ?", product.ID).First(&image, imageID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Imagem não encontrada"})
+		return nil, nil, false
+	}
+	return product, &image, true
+}
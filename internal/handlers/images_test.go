@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Só as imagens que mudaram de posição entram na auditoria
+func TestReorderProductImagesAudit(t *testing.T) {
+	h, fake := newTestHandler(t)
+	fake.On(`FROM "products"`).Rows([]string{"id"}, []interface{}{5})
+	fake.On(`FROM "product_images"`).Once().Rows([]string{"id", "product_id", "position"},
+		[]interface{}{1, 5, 0},
+		[]interface{}{2, 5, 1},
+		[]interface{}{3, 5, 2})
+	fake.On(`FROM "product_images"`).Rows([]string{"id", "product_id", "position"},
+		[]interface{}{2, 5, 0},
+		[]interface{}{1, 5, 1},
+		[]interface{}{3, 5, 2})
+
+	w := serve(func(c *gin.Context) {
+		c.Params = gin.Params{{Key: "id", Value: "5"}}
+		h.ReorderProductImages(c)
+	}, http.MethodPut, "/products/5/images/order", gin.H{"image_ids": []uint{2, 1, 3}}, asUser(2, 1, "admin"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body.String())
+	}
+
+	entries := fake.Queries(`INSERT INTO "audit_logs"`)
+	if len(entries) != 2 {
+		t.Fatalf("%d registros de auditoria, esperados 2", len(entries))
+	}
+	for i, id := range []int64{2, 1} {
+		if !contains(entries[i].Args, auditEntityImage) || !contains(entries[i].Args, id) || !contains(entries[i].Args, models.AuditActionUpdate) {
+			t.Errorf("registro %d deve ser da imagem %d: %v", i, id, entries[i].Args)
+		}
+	}
+}
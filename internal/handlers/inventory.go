@@ -3,25 +3,87 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
+	"loja-online/internal/middleware"
 	"loja-online/internal/models"
+	"loja-online/internal/pagination"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// GetInventory retorna todos os itens do inventário
+// inventorySort são os campos pelos quais a listagem do inventário pode ser ordenada
+var inventorySort = &pagination.Spec[models.InventoryItem]{
+	DefaultSort: "id",
+	Fields: map[string]pagination.Field[models.InventoryItem]{
+		"id":         {Column: "id", Value: func(i *models.InventoryItem) any { return i.ID }},
+		"product":    {Column: "(SELECT name FROM products WHERE products.id = inventory_items.product_id)", Value: func(i *models.InventoryItem) any { return i.Product.Name }},
+		"sku":        {Column: "(SELECT sku FROM products WHERE products.id = inventory_items.product_id)", Value: func(i *models.InventoryItem) any { return i.Product.SKU }},
+		"quantity":   {Column: "quantity", Value: func(i *models.InventoryItem) any { return i.Quantity }},
+		"min_stock":  {Column: "COALESCE(min_stock, 0)", Value: func(i *models.InventoryItem) any { return i.MinStock }},
+		"max_stock":  {Column: "COALESCE(max_stock, 0)", Value: func(i *models.InventoryItem) any { return i.MaxStock }},
+		"location":   {Column: "COALESCE(location, '')", Value: func(i *models.InventoryItem) any { return i.Location }},
+		"updated_at": {Column: "updated_at", Value: func(i *models.InventoryItem) any { return i.UpdatedAt }},
+	},
+}
+
+// GetInventory retorna os itens do inventário da loja ativa (ou de todas as
+// lojas visíveis, sem loja ativa), filtrados, ordenados e paginados. search
+// filtra pelo nome, SKU ou código de barras do produto.
 func (h *Handler) GetInventory(c *gin.Context) {
 	var inventoryItems []models.InventoryItem
+	var total int64
+
+	page, ok := listPage(c, inventorySort)
+	if !ok {
+		return
+	}
+
+	query := scopeStore(c, h.DB.Model(&models.InventoryItem{}), "store_id")
+	if productID := c.Query("product_id"); productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		pattern := "%" + search + "%"
+		products := h.DB.Model(&models.Product{}).Select("id").
+			Where("name ILIKE ? OR sku ILIKE ? OR barcode = ?", pattern, pattern, search)
+		query = query.Where("product_id IN (?)", products)
+	}
+	query = filterValues(c, query, "location", "location")
+
+	lowStock, set, err := queryBool(c, "low_stock")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if set && lowStock {
+		query = query.Where("quantity <= min_stock")
+	} else if set {
+		query = query.Where("quantity > min_stock")
+	}
+	query, err = filterRange(c, query, "min_quantity", "max_quantity", "quantity")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar inventário"})
+		return
+	}
 
-	if err := h.DB.Preload("Product").Find(&inventoryItems).Error; err != nil {
+	if err := page.Apply(query).Preload("Product").Find(&inventoryItems).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar inventário"})
 		return
 	}
+	inventoryItems = page.Trim(inventoryItems)
 
-	c.JSON(http.StatusOK, gin.H{"inventory": inventoryItems})
+	c.JSON(http.StatusOK, listResponse("inventory", inventoryItems, page.Meta(inventoryItems, total)))
 }
 
-// AdjustInventory ajusta a quantidade de um produto no estoque
+// AdjustInventory ajusta a quantidade de um produto no estoque da loja ativa
 func (h *Handler) AdjustInventory(c *gin.Context) {
 	var adjustment models.InventoryAdjustment
 
@@ -30,11 +92,33 @@ func (h *Handler) AdjustInventory(c *gin.Context) {
 		return
 	}
 
+	storeID, ok := requireActiveStore(c)
+	if !ok {
+		return
+	}
+
+	// O estoque de um estilo é controlado nas variações e o de um kit nos
+	// componentes
+	var product models.Product
+	if err := h.DB.Select("id, has_variants, is_bundle").First(&product, adjustment.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
+		return
+	}
+	if product.HasVariants {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ajuste o estoque nas variações do produto"})
+		return
+	}
+	if product.IsBundle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O estoque de um kit vem dos componentes; ajuste o estoque deles"})
+		return
+	}
+
 	// Busca o item no inventário
 	var inventoryItem models.InventoryItem
-	if err := h.DB.Where("product_id = ?", adjustment.ProductID).First(&inventoryItem).Error; err != nil {
+	if err := h.DB.Where("store_id = ? AND product_id = ?", storeID, adjustment.ProductID).First(&inventoryItem).Error; err != nil {
 		// Se não existir, cria um novo
 		inventoryItem = models.InventoryItem{
+			StoreID:   storeID,
 			ProductID: adjustment.ProductID,
 			Quantity:  0,
 		}
@@ -45,6 +129,7 @@ func (h *Handler) AdjustInventory(c *gin.Context) {
 	}
 
 	// Salva quantidade anterior
+	before := inventoryItem
 	previousQuantity := inventoryItem.Quantity
 
 	// Atualiza quantidade
@@ -65,6 +150,7 @@ func (h *Handler) AdjustInventory(c *gin.Context) {
 	}
 
 	movement := models.InventoryMovement{
+		StoreID:       storeID,
 		ProductID:     adjustment.ProductID,
 		MovementType:  movementType,
 		Quantity:      adjustment.NewQuantity - previousQuantity,
@@ -73,11 +159,9 @@ func (h *Handler) AdjustInventory(c *gin.Context) {
 		Reason:        adjustment.Reason,
 	}
 
-	// Adiciona usuário se disponível no contexto
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(float64); ok {
-			movement.UserID = uint(uid)
-		}
+	// Adiciona usuário se disponível no contexto, seja do token ou da chave de API
+	if userID, ok := middleware.UserIDFromContext(c); ok {
+		movement.UserID = userID
 	}
 
 	if err := h.DB.Create(&movement).Error; err != nil {
@@ -85,13 +169,16 @@ func (h *Handler) AdjustInventory(c *gin.Context) {
 		return
 	}
 
+	h.recordAudit(c, auditEntityInventory, inventoryItem.ID, models.AuditActionUpdate, before, inventoryItem)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Inventário ajustado com sucesso",
 		"item":    inventoryItem,
 	})
 }
 
-// GetInventoryMovements retorna o histórico de movimentos de um produto
+// GetInventoryMovements retorna o histórico de movimentos de um produto nas
+// lojas visíveis
 func (h *Handler) GetInventoryMovements(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("product_id"))
 	if err != nil {
@@ -100,7 +187,7 @@ func (h *Handler) GetInventoryMovements(c *gin.Context) {
 	}
 
 	var movements []models.InventoryMovement
-	if err := h.DB.Where("product_id = ?", productID).
+	if err := scopeStore(c, h.DB, "store_id").Where("product_id = ?", productID).
 		Preload("Product").
 		Preload("User").
 		Order("created_at DESC").
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// O movimento de estoque registra o autor tanto com token, em que o ID vem
+// das claims como float64, quanto com chave de API, em que vem como uint
+func TestAdjustInventoryRecordsUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID interface{}
+	}{
+		{"token", float64(5)},
+		{"chave de API", uint(5)},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		fake.On(`FROM "products"`).Rows([]string{"id"}, []interface{}{12})
+		fake.On(`FROM "inventory_items"`).Rows([]string{"id", "store_id", "product_id", "quantity"}, []interface{}{30, 2, 12, 4})
+
+		w := serve(h.AdjustInventory, http.MethodPost, "/inventory/adjust",
+			models.InventoryAdjustment{ProductID: 12, NewQuantity: 10, Reason: "Contagem"},
+			func(c *gin.Context) {
+				c.Set("user_id", tt.userID)
+				c.Set("store_id", uint(2))
+			})
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, esperado 200: %s", tt.name, w.Code, w.Body.String())
+		}
+
+		movements := fake.Queries(`INSERT INTO "inventory_movements"`)
+		if len(movements) != 1 || !contains(movements[0].Args, int64(5)) {
+			t.Errorf("%s: o movimento deve registrar o usuário 5: %v", tt.name, movements)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GetInvitations retorna todos os convites
+func (h *Handler) GetInvitations(c *gin.Context) {
+	var invitations []models.Invitation
+
+	query := h.DB.Preload("CreatedBy").Order("created_at DESC")
+	if c.Query("pending") == "true" {
+		query = query.Where("used_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	if err := query.Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar convites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// CreateInvitation cria um convite de uso único para um novo usuário
+func (h *Handler) CreateInvitation(c *gin.Context) {
+	var input models.InvitationCreate
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.Permissions.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if status, err := checkGrant(c, input.Role, input.Permissions, models.UserPermissions{}); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Não convida e-mails que já possuem conta
+	var count int64
+	h.DB.Model(&models.User{}).Where("email = ?", input.Email).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Já existe um usuário com este email"})
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar convite"})
+		return
+	}
+
+	createdByID, _ := middleware.UserIDFromContext(c)
+	invitation := models.Invitation{
+		Email:       input.Email,
+		Role:        input.Role,
+		Permissions: input.Permissions,
+		TokenHash:   hashToken(token),
+		ExpiresAt:   time.Now().Add(h.Config.InvitationTTL),
+		CreatedByID: createdByID,
+	}
+
+	if err := h.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar convite"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityInvitation, invitation.ID, models.AuditActionCreate, nil, invitation)
+
+	// O token só é exibido neste momento; no banco fica apenas o hash
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"token":      token,
+	})
+}
+
+// DeleteInvitation revoga um convite ainda não utilizado
+func (h *Handler) DeleteInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var invitation models.Invitation
+	if err := h.DB.Where("used_at IS NULL").First(&invitation, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Convite não encontrado ou já utilizado"})
+		return
+	}
+
+	if err := h.DB.Delete(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao revogar convite"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityInvitation, invitation.ID, models.AuditActionDelete, invitation, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Convite revogado com sucesso"})
+}
+
+// AcceptInvitation cria o usuário convidado a partir de um token válido
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	var input models.InvitationAccept
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invitation models.Invitation
+	if err := h.DB.Where("token_hash = ?", hashToken(input.Token)).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Convite inválido ou expirado"})
+		return
+	}
+
+	now := time.Now()
+	if !invitation.IsUsable(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Convite inválido ou expirado"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+		return
+	}
+
+	user := models.User{
+		Name:        input.Name,
+		Email:       invitation.Email,
+		Password:    string(hashedPassword),
+		Role:        invitation.Role,
+		Active:      true,
+		Permissions: invitation.Permissions,
+	}
+
+	tx := h.DB.Begin()
+
+	// Marca o convite como usado de forma condicional para evitar uso duplo
+	result := tx.Model(&models.Invitation{}).
+		Where("id = ? AND used_at IS NULL", invitation.ID).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Convite inválido ou expirado"})
+		return
+	}
+
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Erro ao criar usuário"})
+		return
+	}
+
+	if err := tx.Model(&models.Invitation{}).Where("id = ?", invitation.ID).Update("user_id", user.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao aceitar convite"})
+		return
+	}
+
+	tx.Commit()
+
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionCreate, nil, user)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Convite aceito com sucesso",
+		"user":    user,
+	})
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publica as chaves públicas que validam os tokens de acesso
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
+
+// RotateSigningKey gera uma nova chave de assinatura imediatamente, por
+// exemplo após suspeita de vazamento. A chave anterior continua aceita até
+// que os tokens assinados por ela expirem.
+func (h *Handler) RotateSigningKey(c *gin.Context) {
+	if err := h.Keys.Rotate(); err != nil {
+		log.Printf("Erro ao rotacionar chave de assinatura: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao rotacionar chave de assinatura"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
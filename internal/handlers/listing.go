@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/pagination"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listPage lê a ordenação e a paginação da requisição, respondendo 400
+// quando os parâmetros são inválidos
+func listPage[T any](c *gin.Context, spec *pagination.Spec[T]) (*pagination.Page[T], bool) {
+	page, err := pagination.Parse(c.Request.URL, spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return page, true
+}
+
+// listResponse monta a resposta das listagens paginadas, com os itens na
+// chave do recurso e os dados da página no mesmo nível, como na auditoria
+func listResponse(key string, items any, meta pagination.Meta) gin.H {
+	response := gin.H{
+		key:           items,
+		"total":       meta.Total,
+		"limit":       meta.Limit,
+		"sort":        meta.Sort,
+		"next_cursor": meta.NextCursor,
+		"prev_cursor": meta.PrevCursor,
+		"links":       gin.H{"next": meta.Next, "prev": meta.Prev},
+	}
+	if meta.Offset != nil {
+		response["offset"] = *meta.Offset
+	}
+	return response
+}
+
+// filterValues filtra a coluna por um ou mais valores separados por vírgula,
+// sem diferenciar maiúsculas e minúsculas
+func filterValues(c *gin.Context, query *gorm.DB, param, column string) *gorm.DB {
+	values := queryValues(c, param)
+	if len(values) == 0 {
+		return query
+	}
+	return query.Where("LOWER("+column+") IN ?", values)
+}
+
+// queryValues separa os valores de um filtro, em minúsculas
+func queryValues(c *gin.Context, param string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(param), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, strings.ToLower(value))
+		}
+	}
+	return values
+}
+
+// queryIDs lê um filtro de um ou mais IDs separados por vírgula
+func queryIDs(c *gin.Context, param string) ([]uint, error) {
+	var ids []uint
+	for _, value := range strings.Split(c.Query(param), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return nil, errors.New(param + " deve ter IDs separados por vírgula")
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// filterBool filtra a coluna pelo valor booleano do parâmetro, quando informado
+func filterBool(c *gin.Context, query *gorm.DB, param, column string) (*gorm.DB, error) {
+	value, set, err := queryBool(c, param)
+	if err != nil || !set {
+		return query, err
+	}
+	return query.Where(column+" = ?", value), nil
+}
+
+// queryBool lê um parâmetro booleano, indicando se foi informado
+func queryBool(c *gin.Context, param string) (bool, bool, error) {
+	value := c.Query(param)
+	if value == "" {
+		return false, false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false, errors.New(param + " deve ser true ou false")
+	}
+	return parsed, true, nil
+}
+
+// filterRange filtra a coluna numérica pelos parâmetros de mínimo e máximo
+func filterRange(c *gin.Context, query *gorm.DB, minParam, maxParam, column string) (*gorm.DB, error) {
+	for _, bound := range []struct{ param, operator string }{{minParam, " >= ?"}, {maxParam, " <= ?"}} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New(bound.param + " deve ser um número")
+		}
+		query = query.Where(column+bound.operator, parsed)
+	}
+	return query, nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// unknownUserHash é comparado com a senha informada para emails não
+// cadastrados, igualando o custo do bcrypt ao de uma conta existente
+var unknownUserHash, _ = bcrypt.GenerateFromPassword([]byte("usuario-inexistente"), bcrypt.DefaultCost)
+
+// respondLocked informa que a conta está bloqueada e quando tentar novamente
+func respondLocked(c *gin.Context, lockedUntil time.Time) {
+	seconds := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusLocked, gin.H{
+		"error":        "Conta temporariamente bloqueada por excesso de tentativas",
+		"locked_until": lockedUntil,
+		"retry_after":  seconds,
+	})
+}
+
+// lockoutUntil retorna o fim do bloqueio da conta após attempts falhas
+// consecutivas, ou false se o limite não foi atingido. Limite zero desabilita
+// o bloqueio.
+func lockoutUntil(attempts, maxFailures int, duration time.Duration, now time.Time) (time.Time, bool) {
+	if maxFailures <= 0 || attempts < maxFailures {
+		return time.Time{}, false
+	}
+	return now.Add(duration), true
+}
+
+// registerLoginFailure contabiliza uma falha de login e bloqueia a conta ao
+// atingir o limite configurado. Retorna o fim do bloqueio, se houver.
+func (h *Handler) registerLoginFailure(user *models.User) *time.Time {
+	if err := h.DB.Model(user).UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error; err != nil {
+		return nil
+	}
+
+	var attempts int
+	h.DB.Model(&models.User{}).Where("id = ?", user.ID).Select("failed_login_attempts").Scan(&attempts)
+	user.FailedLoginAttempts = attempts
+
+	lockedUntil, locked := lockoutUntil(attempts, h.Config.LoginMaxFailures, h.Config.LoginLockoutDuration, time.Now())
+	if !locked {
+		return nil
+	}
+
+	h.DB.Model(user).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          lockedUntil,
+	})
+	user.LockedUntil = &lockedUntil
+	return &lockedUntil
+}
+
+// resetLoginFailures zera o contador de falhas após um login bem-sucedido
+func (h *Handler) resetLoginFailures(user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	h.DB.Model(user).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	})
+}
+
+// UnlockUser remove o bloqueio temporário de login de um usuário
+func (h *Handler) UnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	before := user
+	if err := h.DB.Model(&user).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao desbloquear usuário"})
+		return
+	}
+
+	h.DB.First(&user, id)
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate, before, user)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Usuário desbloqueado com sucesso"})
+}
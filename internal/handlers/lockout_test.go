@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutUntil(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		attempts    int
+		maxFailures int
+		locked      bool
+	}{
+		{"abaixo do limite", 4, 5, false},
+		{"no limite", 5, 5, true},
+		{"acima do limite", 6, 5, true},
+		{"bloqueio desabilitado", 100, 0, false},
+	}
+
+	for _, tt := range tests {
+		until, locked := lockoutUntil(tt.attempts, tt.maxFailures, 15*time.Minute, now)
+		if locked != tt.locked {
+			t.Errorf("%s: bloqueado = %v, esperado %v", tt.name, locked, tt.locked)
+		}
+		if locked && !until.Equal(now.Add(15*time.Minute)) {
+			t.Errorf("%s: bloqueio até %v, esperado 15 minutos depois", tt.name, until)
+		}
+	}
+}
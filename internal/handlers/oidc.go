@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"loja-online/internal/models"
+	"loja-online/internal/oidc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// Cookie que guarda state, nonce e verificador PKCE durante o redirecionamento
+	oidcStateCookie = "oidc_state"
+	oidcStatePath   = "/api/v1/auth/oidc"
+	// Tempo máximo para o usuário concluir o login no provedor
+	oidcStateTTL = 10 * time.Minute
+)
+
+var (
+	errOIDCEmailNotVerified = errors.New("Email não verificado pelo provedor de identidade")
+	errOIDCDomainNotAllowed = errors.New("Domínio de email não autorizado")
+	errOIDCUserNotFound     = errors.New("Usuário não cadastrado. Solicite um convite ao administrador")
+	errOIDCLinkedElsewhere  = errors.New("Conta já vinculada a outra identidade")
+)
+
+// OIDCLogin redireciona o usuário para o provedor de identidade
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	if h.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Login único não configurado"})
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		token, err := generateToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar login"})
+			return
+		}
+		values[i] = token
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := h.OIDC.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("Erro no login único: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Provedor de identidade indisponível"})
+		return
+	}
+
+	// O cookie é assinado para que o callback confie no state sem guardar nada no banco
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose":  "oidc",
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      time.Now().Add(oidcStateTTL).Unix(),
+	}).SignedString([]byte(h.Config.SessionSecret))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar login"})
+		return
+	}
+
+	h.setOIDCStateCookie(c, cookie, int(oidcStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback recebe o código do provedor, identifica o usuário e emite os
+// tokens da aplicação. O resultado é repassado à página de login no
+// fragmento da URL, que não chega aos logs do servidor.
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if h.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Login único não configurado"})
+		return
+	}
+
+	stateCookie, _ := c.Cookie(oidcStateCookie)
+	h.setOIDCStateCookie(c, "", -1)
+
+	if providerError := c.Query("error"); providerError != "" {
+		h.oidcRedirect(c, url.Values{"error": {"Login cancelado no provedor de identidade"}})
+		return
+	}
+
+	nonce, verifier, ok := h.parseOIDCState(stateCookie, c.Query("state"))
+	if !ok {
+		h.oidcRedirect(c, url.Values{"error": {"Sessão de login expirada. Tente novamente"}})
+		return
+	}
+
+	claims, err := h.OIDC.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		log.Printf("Erro no login único: %v", err)
+		h.oidcRedirect(c, url.Values{"error": {"Não foi possível validar o login no provedor de identidade"}})
+		return
+	}
+
+	user, err := h.findOrProvisionOIDCUser(c, claims)
+	if err != nil {
+		h.oidcRedirect(c, url.Values{"error": {err.Error()}})
+		return
+	}
+
+	if !user.Active || user.Role == models.RoleService {
+		h.oidcRedirect(c, url.Values{"error": {"Usuário inativo"}})
+		return
+	}
+	if user.IsLocked(time.Now()) {
+		h.oidcRedirect(c, url.Values{"error": {"Conta temporariamente bloqueada por excesso de tentativas"}})
+		return
+	}
+
+	// O segundo fator continua exigido, como no login por senha
+	if user.TwoFactorEnabled {
+		challenge, err := h.newTwoFactorChallenge(user)
+		if err != nil {
+			h.oidcRedirect(c, url.Values{"error": {"Erro ao gerar token"}})
+			return
+		}
+		h.oidcRedirect(c, url.Values{
+			"two_factor_required": {"true"},
+			"challenge_token":     {challenge},
+		})
+		return
+	}
+
+	session, refreshToken, err := h.createSession(c, user)
+	if err != nil {
+		h.oidcRedirect(c, url.Values{"error": {"Erro ao criar sessão"}})
+		return
+	}
+	accessToken, err := h.newAccessToken(user, session)
+	if err != nil {
+		h.oidcRedirect(c, url.Values{"error": {"Erro ao gerar token"}})
+		return
+	}
+
+	h.oidcRedirect(c, url.Values{
+		"token":         {accessToken},
+		"expires_in":    {strconv.Itoa(int(h.Config.AccessTokenTTL.Seconds()))},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// findOrProvisionOIDCUser localiza o usuário pela identidade já vinculada ou
+// pelo email verificado, criando-o quando o provisionamento automático está ativo
+func (h *Handler) findOrProvisionOIDCUser(c *gin.Context, claims *oidc.Claims) (*models.User, error) {
+	if !h.oidcDomainAllowed(claims) {
+		return nil, errOIDCDomainNotAllowed
+	}
+
+	var user models.User
+	if err := h.DB.Where("oidc_subject = ?", claims.Subject).First(&user).Error; err == nil {
+		return &user, nil
+	}
+
+	// Só vincula por email quando o provedor garante que o email pertence ao usuário
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errOIDCEmailNotVerified
+	}
+
+	err := h.DB.Where("LOWER(email) = LOWER(?)", claims.Email).First(&user).Error
+	if err == nil {
+		if user.OIDCSubject != nil && *user.OIDCSubject != claims.Subject {
+			return nil, errOIDCLinkedElsewhere
+		}
+		if err := h.DB.Model(&user).Update("oidc_subject", claims.Subject).Error; err != nil {
+			return nil, errors.New("Erro ao vincular usuário")
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("Erro ao buscar usuário")
+	}
+
+	if !h.Config.OIDCAutoProvision {
+		return nil, errOIDCUserNotFound
+	}
+
+	// A senha aleatória nunca é informada; o acesso por senha exige redefinição
+	password, err := generateToken()
+	if err != nil {
+		return nil, errors.New("Erro ao criar usuário")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("Erro ao criar usuário")
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	subject := claims.Subject
+	user = models.User{
+		Name:        name,
+		Email:       strings.ToLower(claims.Email),
+		Password:    string(hashedPassword),
+		Role:        h.Config.OIDCDefaultRole,
+		Active:      true,
+		OIDCSubject: &subject,
+	}
+	if err := user.Permissions.Normalize(); err != nil {
+		return nil, errors.New("Erro ao criar usuário")
+	}
+
+	if err := h.DB.Create(&user).Error; err != nil {
+		return nil, errors.New("Erro ao criar usuário")
+	}
+
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionCreate, nil, user)
+
+	return &user, nil
+}
+
+// oidcDomainAllowed verifica o domínio do email (ou o "hd" do Google Workspace)
+func (h *Handler) oidcDomainAllowed(claims *oidc.Claims) bool {
+	allowed := strings.FieldsFunc(strings.ToLower(h.Config.OIDCAllowedDomains), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(allowed) == 0 {
+		return true
+	}
+
+	domain := strings.ToLower(claims.HostedDomain)
+	if at := strings.LastIndex(claims.Email, "@"); domain == "" && at >= 0 {
+		domain = strings.ToLower(claims.Email[at+1:])
+	}
+	for _, candidate := range allowed {
+		if domain == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOIDCState valida o cookie assinado e o state devolvido pelo provedor
+func (h *Handler) parseOIDCState(cookie, state string) (nonce, verifier string, ok bool) {
+	if cookie == "" || state == "" {
+		return "", "", false
+	}
+
+	token, err := jwt.Parse(cookie, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(h.Config.SessionSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", "", false
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	expected, _ := claims["state"].(string)
+	if claims["purpose"] != "oidc" || subtle.ConstantTimeCompare([]byte(expected), []byte(state)) != 1 {
+		return "", "", false
+	}
+
+	nonce, _ = claims["nonce"].(string)
+	verifier, _ = claims["verifier"].(string)
+	return nonce, verifier, true
+}
+
+func (h *Handler) setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcStatePath, "", h.Config.Environment == "production", true)
+}
+
+// oidcRedirect devolve o navegador à página de login com o resultado no fragmento
+func (h *Handler) oidcRedirect(c *gin.Context, values url.Values) {
+	c.Redirect(http.StatusFound, h.Config.OIDCPostLoginRedirect+"#"+values.Encode())
+}
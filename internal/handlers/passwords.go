@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"loja-online/internal/mailer"
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ChangePassword altera a senha do usuário logado mediante a senha atual.
+// As demais sessões do usuário são encerradas.
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var input models.PasswordChange
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+	sessionID, _ := middleware.SessionIDFromContext(c)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if !user.CheckPassword(input.CurrentPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Senha atual incorreta"})
+		return
+	}
+	previousPassword := user.Password
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+		return
+	}
+
+	if err := h.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao alterar senha"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate,
+		gin.H{"password": previousPassword}, gin.H{"password": string(hashedPassword)})
+
+	if err := h.DB.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, sessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessões do usuário"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Senha alterada com sucesso"})
+}
+
+// ForgotPassword envia por email um link de redefinição de senha. A resposta é
+// sempre a mesma para não revelar quais emails estão cadastrados.
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var input models.PasswordForgot
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "Se o email estiver cadastrado, você receberá as instruções para redefinir a senha"}
+
+	var user models.User
+	if err := h.DB.Where("email = ? AND active = ?", input.Email, true).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		return
+	}
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(h.Config.PasswordResetTTL),
+		RequestIP: c.ClientIP(),
+	}
+	if err := h.DB.Create(&reset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao solicitar redefinição de senha"})
+		return
+	}
+
+	if err := h.sendPasswordReset(user, token); err != nil {
+		log.Printf("Erro ao enviar email de redefinição de senha: %v", err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// sendPasswordReset envia o email com o link da página de redefinição
+func (h *Handler) sendPasswordReset(user models.User, token string) error {
+	link := fmt.Sprintf("%s%s?token=%s", strings.TrimSuffix(h.Config.AppBaseURL, "/"), ResetPasswordPath, url.QueryEscape(token))
+	return h.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Redefinição de senha - Loja Online",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Recebemos uma solicitação para redefinir a sua senha.\n"+
+			"Acesse o link abaixo para cadastrar uma nova senha:\n\n%s\n\n"+
+			"O link expira em %s. Se você não fez esta solicitação, ignore este email.\n",
+			user.Name, link, h.Config.PasswordResetTTL),
+	})
+}
+
+// ResetPassword define uma nova senha a partir de um token de redefinição
+// válido e encerra todas as sessões do usuário
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var input models.PasswordResetConfirm
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reset models.PasswordReset
+	if err := h.DB.Where("token_hash = ?", hashToken(input.Token)).First(&reset).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	now := time.Now()
+	if !reset.IsUsable(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, reset.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+		return
+	}
+
+	tx := h.DB.Begin()
+
+	result := tx.Model(&models.PasswordReset{}).
+		Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token inválido ou expirado"})
+		return
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", string(hashedPassword)).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao redefinir senha"})
+		return
+	}
+
+	if err := revokeUserSessions(tx, reset.UserID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessões do usuário"})
+		return
+	}
+
+	tx.Commit()
+
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate,
+		gin.H{"password": user.Password}, gin.H{"password": string(hashedPassword)})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Senha redefinida com sucesso"})
+}
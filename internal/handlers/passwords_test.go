@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/api"
+	"loja-online/internal/config"
+	"loja-online/internal/handlers"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// O link do email de redefinição precisa abrir a página que envia a nova
+// senha para a API
+func TestPasswordResetLinkResolves(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// As páginas são carregadas de web/templates, relativo à raiz do projeto
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "..", "..")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(dir)
+
+	// Nenhuma consulta é feita: a página não acessa o banco
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mailLog := filepath.Join(t.TempDir(), "emails.log")
+	cfg := &config.Config{
+		AppBaseURL:       "http://loja.test/",
+		PasswordResetTTL: time.Hour,
+		MailDriver:       "log",
+		MailLogFile:      mailLog,
+		RateLimitStore:   "memory",
+	}
+
+	h := handlers.New(db, cfg, nil)
+	token := "abc+/=123"
+	if err := h.SendPasswordReset(models.User{Name: "Ana", Email: "ana@loja.test"}, token); err != nil {
+		t.Fatal(err)
+	}
+
+	email, err := os.ReadFile(mailLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(`http://loja\.test\S+`).Find(email)
+	if match == nil {
+		t.Fatalf("link não encontrado no email:\n%s", email)
+	}
+	link, err := url.Parse(string(match))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := link.Query().Get("token"); got != token {
+		t.Fatalf("token no link = %q, esperado %q", got, token)
+	}
+
+	router := api.SetupRouter(db, cfg, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, esperado 200", link.RequestURI(), w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "/api/v1/auth/password/reset") {
+		t.Fatal("a página não envia a nova senha para /api/v1/auth/password/reset")
+	}
+}
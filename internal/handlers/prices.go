@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPriceHistory retorna as alterações de preço e custo do produto, da mais
+// recente para a mais antiga, e as alterações agendadas pendentes. Com at
+// (AAAA-MM-DD ou data e hora RFC 3339), inclui em price_at o preço vigente
+// naquele momento; uma data sem hora considera o fim do dia.
+func (h *Handler) GetPriceHistory(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Where("product_id = ?", product.ID)
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.ParseInLocation(periodDateLayout, startDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date deve estar no formato AAAA-MM-DD"})
+			return
+		}
+		query = query.Where("changed_at >= ?", start)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.ParseInLocation(periodDateLayout, endDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end_date deve estar no formato AAAA-MM-DD"})
+			return
+		}
+		query = query.Where("changed_at < ?", end.AddDate(0, 0, 1))
+	}
+
+	var history []models.PriceHistory
+	if err := query.Preload("User").Order("changed_at DESC, id DESC").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar histórico de preços"})
+		return
+	}
+
+	var scheduled []models.ScheduledPrice
+	if err := h.DB.Where("product_id = ? AND status = ?", product.ID, models.ScheduledPricePending).
+		Order("effective_at, id").Find(&scheduled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar histórico de preços"})
+		return
+	}
+
+	response := gin.H{
+		"product_id": product.ID,
+		"price":      product.Price,
+		"cost_price": product.CostPrice,
+		"history":    history,
+		"scheduled":  scheduled,
+	}
+
+	if value := c.Query("at"); value != "" {
+		at, err := parseInstant(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entry, err := pricing.PriceAt(h.DB, product.ID, at)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar histórico de preços"})
+			return
+		}
+		response["price_at"] = entry
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseInstant aceita uma data (fim do dia, no fuso local) ou data e hora RFC 3339
+func parseInstant(value string) (time.Time, error) {
+	if day, err := time.ParseInLocation(periodDateLayout, value, time.Local); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("at deve estar no formato AAAA-MM-DD ou RFC 3339")
+	}
+	return at, nil
+}
+
+// GetScheduledPrices retorna as alterações de preço agendadas do produto,
+// pendentes, aplicadas e canceladas
+func (h *Handler) GetScheduledPrices(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Where("product_id = ?", product.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var scheduled []models.ScheduledPrice
+	if err := query.Order("effective_at DESC, id DESC").Find(&scheduled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar preços agendados"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scheduled_prices": scheduled})
+}
+
+// CreateScheduledPrice agenda uma alteração de preço e/ou custo do produto,
+// aplicada automaticamente em effective_at
+func (h *Handler) CreateScheduledPrice(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+
+	var input models.ScheduledPriceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Price == nil && input.CostPrice == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe price e/ou cost_price"})
+		return
+	}
+	if !input.EffectiveAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "effective_at deve ser uma data futura; para alterar agora, atualize o produto"})
+		return
+	}
+	if product.IsVariant() && input.CostPrice != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O custo de uma variação vem do estilo; agende no estilo"})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+	schedule := models.ScheduledPrice{
+		ProductID:   product.ID,
+		Price:       input.Price,
+		CostPrice:   input.CostPrice,
+		EffectiveAt: input.EffectiveAt,
+		Status:      models.ScheduledPricePending,
+		Reason:      input.Reason,
+		CreatedByID: userID,
+	}
+	if err := h.DB.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao agendar preço"})
+		return
+	}
+
+	h.recordAudit(c, auditEntitySchedule, schedule.ID, models.AuditActionCreate, nil, schedule)
+
+	c.JSON(http.StatusCreated, gin.H{"scheduled_price": schedule})
+}
+
+// CancelScheduledPrice cancela uma alteração de preço agendada ainda pendente
+func (h *Handler) CancelScheduledPrice(c *gin.Context) {
+	product, ok := h.findProduct(c)
+	if !ok {
+		return
+	}
+	scheduleID, err := strconv.Atoi(c.Param("schedule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var schedule models.ScheduledPrice
+	if err := h.DB.Where("product_id = ?", product.ID).First(&schedule, scheduleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preço agendado não encontrado"})
+		return
+	}
+
+	// A condição no status evita cancelar uma alteração aplicada em paralelo
+	before := schedule
+	result := h.DB.Model(&schedule).Where("status = ?", models.ScheduledPricePending).
+		Update("status", models.ScheduledPriceCancelled)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao cancelar preço agendado"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Somente alterações pendentes podem ser canceladas"})
+		return
+	}
+
+	h.recordAudit(c, auditEntitySchedule, schedule.ID, models.AuditActionUpdate, before, schedule)
+
+	c.JSON(http.StatusOK, gin.H{"scheduled_price": schedule})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateScheduledPrice(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		variant bool
+		body    gin.H
+		want    int
+	}{
+		{"preço futuro", false, gin.H{"price": 79.9, "effective_at": future}, http.StatusCreated},
+		{"sem preço nem custo", false, gin.H{"effective_at": future}, http.StatusBadRequest},
+		{"data passada", false, gin.H{"price": 79.9, "effective_at": past}, http.StatusBadRequest},
+		{"custo da variação", true, gin.H{"cost_price": 30, "effective_at": future}, http.StatusBadRequest},
+		{"preço da variação", true, gin.H{"price": 79.9, "effective_at": future}, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		var parentID interface{}
+		if tt.variant {
+			parentID = 4
+		}
+		fake.On(`FROM "products"`).Rows([]string{"id", "parent_id"}, []interface{}{5, parentID})
+
+		w := serve(func(c *gin.Context) {
+			c.Params = gin.Params{{Key: "id", Value: "5"}}
+			h.CreateScheduledPrice(c)
+		}, http.MethodPost, "/products/5/scheduled-prices", tt.body, asUser(2, 1, "admin"))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, esperado %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+
+		inserts := fake.Queries(`INSERT INTO "scheduled_prices"`)
+		if created := len(inserts) == 1; created != (tt.want == http.StatusCreated) {
+			t.Errorf("%s: agendado = %v", tt.name, created)
+			continue
+		}
+		if len(inserts) == 1 && (!contains(inserts[0].Args, models.ScheduledPricePending) || !contains(inserts[0].Args, int64(2))) {
+			t.Errorf("%s: o agendamento deve ficar pendente e registrar o autor: %v", tt.name, inserts[0].Args)
+		}
+	}
+}
+
+func TestCancelScheduledPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		found    bool
+		affected int64
+		want     int
+	}{
+		{"pendente", true, 1, http.StatusOK},
+		{"já aplicado ou cancelado", true, 0, http.StatusConflict},
+		{"de outro produto", false, 0, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		if tt.found {
+			fake.On(`FROM "scheduled_prices"`).Rows([]string{"id", "product_id", "status"},
+				[]interface{}{11, 5, models.ScheduledPricePending})
+		}
+		fake.On(`FROM "products"`).Rows([]string{"id"}, []interface{}{5})
+		fake.On(`UPDATE "scheduled_prices"`).Affect(tt.affected)
+
+		w := serve(func(c *gin.Context) {
+			c.Params = gin.Params{{Key: "id", Value: "5"}, {Key: "schedule_id", Value: "11"}}
+			h.CancelScheduledPrice(c)
+		}, http.MethodDelete, "/products/5/scheduled-prices/11", nil, asUser(2, 1, "admin"))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, esperado %d", tt.name, w.Code, tt.want)
+		}
+
+		lookup := fake.Queries(`FROM "scheduled_prices"`)
+		if len(lookup) != 1 || !contains(lookup[0].Args, int64(5)) || !contains(lookup[0].Args, int64(11)) {
+			t.Errorf("%s: a busca deve se restringir ao produto: %v", tt.name, lookup)
+		}
+		if tt.found {
+			cancels := fake.Queries(`UPDATE "scheduled_prices"`)
+			if len(cancels) != 1 || !strings.Contains(cancels[0].SQL, "status = $") ||
+				!contains(cancels[0].Args, models.ScheduledPriceCancelled) || !contains(cancels[0].Args, models.ScheduledPricePending) {
+				t.Errorf("%s: o cancelamento deve ser condicionado ao status pendente: %v", tt.name, cancels)
+			}
+		}
+		if audited := fake.Executed(`INSERT INTO "audit_logs"`); audited != (tt.want == http.StatusOK) {
+			t.Errorf("%s: auditado = %v", tt.name, audited)
+		}
+	}
+}
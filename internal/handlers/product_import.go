@@ -0,0 +1,625 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"loja-online/internal/audit"
+	"loja-online/internal/barcode"
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+	"loja-online/internal/pricing"
+	"loja-online/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxImportFileSize = 10 << 20
+	maxImportRows     = 5000
+)
+
+// Campos que uma variação herda do estilo e que não são importados nela
+var variantSharedColumns = []string{"name", "description", "category", "brand", "cost_price", "material", "gender", "season"}
+
+// importRow é uma linha da planilha com os valores por campo; células
+// vazias não são incluídas e mantêm o valor atual do produto
+type importRow struct {
+	line   int
+	values map[string]string
+}
+
+// importPlan é o que a importação fará com uma linha válida
+type importPlan struct {
+	product models.Product
+	before  *models.Product // nil para produto novo
+	stock   *int
+}
+
+// importResult resume a validação ou a importação
+type importResult struct {
+	DryRun         bool                        `json:"dry_run"`
+	Valid          bool                        `json:"valid"`
+	Rows           int                         `json:"rows"`
+	Created        int                         `json:"created"`
+	Updated        int                         `json:"updated"`
+	Unchanged      int                         `json:"unchanged"`
+	StockEntries   int                         `json:"stock_entries"`
+	IgnoredColumns []string                    `json:"ignored_columns"`
+	Errors         []models.ProductImportIssue `json:"errors"`
+	Warnings       []models.ProductImportIssue `json:"warnings"`
+}
+
+// ImportProducts importa produtos de uma planilha CSV ou XLSX enviada em
+// multipart/form-data no campo "file". A primeira linha é o cabeçalho; as
+// colunas são identificadas pelo nome ou por "mapping" (JSON com coluna do
+// arquivo → campo). Produtos com SKU existente são atualizados e os demais,
+// criados. A coluna stock registra o estoque inicial na loja ativa. Nada é
+// gravado se alguma linha tiver erro; com dry_run=true, apenas valida.
+func (h *Handler) ImportProducts(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+1<<20)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Planilha acima do tamanho máximo"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envie a planilha em multipart/form-data no campo file"})
+		return
+	}
+	if file.Size > maxImportFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Planilha acima do tamanho máximo de %d bytes", maxImportFileSize)})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler planilha"})
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler planilha"})
+		return
+	}
+
+	dryRun := false
+	if value := c.PostForm("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run deve ser true ou false"})
+			return
+		}
+	}
+	var mapping map[string]string
+	if value := c.PostForm("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping deve ser um objeto JSON com coluna do arquivo e campo"})
+			return
+		}
+	}
+
+	sheet, err := spreadsheet.Read(data, spreadsheet.Detect(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(sheet) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A planilha deve ter o cabeçalho e ao menos uma linha"})
+		return
+	}
+
+	columns, ignored, err := importColumns(sheet[0], mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rows := importRows(sheet[1:], columns)
+	if len(rows) > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Importe no máximo %d linhas por vez", maxImportRows)})
+		return
+	}
+
+	// O estoque inicial é lançado na loja ativa
+	var storeID uint
+	for _, row := range rows {
+		if _, ok := row.values["stock"]; ok {
+			if storeID, ok = requireActiveStore(c); !ok {
+				return
+			}
+			break
+		}
+	}
+
+	result := importResult{DryRun: dryRun, Rows: len(rows), IgnoredColumns: ignored}
+	plans, err := h.planImport(c, rows, storeID, &result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao validar planilha"})
+		return
+	}
+	result.Valid = len(result.Errors) == 0
+	if !result.Valid {
+		status := http.StatusUnprocessableEntity
+		if dryRun {
+			status = http.StatusOK
+		}
+		c.JSON(status, result)
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	movements, err := h.applyImport(c, plans, storeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao importar produtos"})
+		return
+	}
+	for _, plan := range plans {
+		if plan.before == nil {
+			h.recordAudit(c, auditEntityProduct, plan.product.ID, models.AuditActionCreate, nil, plan.product)
+		} else {
+			h.recordAudit(c, auditEntityProduct, plan.product.ID, models.AuditActionUpdate, plan.before, plan.product)
+		}
+	}
+	for _, item := range movements {
+		h.recordAudit(c, auditEntityInventory, item.ID, models.AuditActionCreate, nil, item)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// importColumns associa cada coluna do arquivo a um campo, pelo mapping
+// informado ou pelo nome. Retorna também as colunas ignoradas.
+func importColumns(header []string, mapping map[string]string) (map[int]string, []string, error) {
+	byName := make(map[string]string, len(mapping))
+	for column, field := range mapping {
+		if _, ok := models.ProductImportField(field); !ok {
+			return nil, nil, fmt.Errorf("campo desconhecido no mapping: %s", field)
+		}
+		byName[strings.ToLower(strings.TrimSpace(column))] = field
+	}
+
+	columns := make(map[int]string, len(header))
+	seen := make(map[string]bool, len(header))
+	ignored := []string{}
+	for i, name := range header {
+		field, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			field, ok = models.ProductImportField(name)
+		}
+		if !ok {
+			if strings.TrimSpace(name) != "" {
+				ignored = append(ignored, name)
+			}
+			continue
+		}
+		field, _ = models.ProductImportField(field)
+		if seen[field] {
+			return nil, nil, fmt.Errorf("mais de uma coluna para o campo %s", field)
+		}
+		seen[field] = true
+		columns[i] = field
+	}
+
+	if !seen["sku"] {
+		return nil, nil, errors.New("a planilha deve ter a coluna sku")
+	}
+	return columns, ignored, nil
+}
+
+// importRows converte as linhas em valores por campo, ignorando linhas vazias
+func importRows(sheet [][]string, columns map[int]string) []importRow {
+	var rows []importRow
+	for i, cells := range sheet {
+		row := importRow{line: i + 2, values: make(map[string]string)}
+		for index, field := range columns {
+			if index < len(cells) {
+				if value := strings.TrimSpace(cells[index]); value != "" {
+					row.values[field] = value
+				}
+			}
+		}
+		if len(row.values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// planImport valida as linhas contra o banco e monta o que será gravado,
+// acumulando os erros e avisos em result
+func (h *Handler) planImport(c *gin.Context, rows []importRow, storeID uint, result *importResult) ([]importPlan, error) {
+	var skus, barcodes []string
+	for _, row := range rows {
+		skus = append(skus, row.values["sku"])
+		if value := row.values["barcode"]; value != "" {
+			// Códigos válidos são comparados já normalizados
+			if code, err := barcode.Normalize(value); err == nil {
+				row.values["barcode"] = code
+				value = code
+			}
+			barcodes = append(barcodes, value)
+		}
+	}
+
+	var existing []models.Product
+	if err := h.DB.Unscoped().Where("sku IN ?", skus).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	bySKU := make(map[string]models.Product, len(existing))
+	for _, product := range existing {
+		bySKU[product.SKU] = product
+	}
+
+	var barcodeOwners []models.Product
+	if len(barcodes) > 0 {
+		if err := h.DB.Unscoped().Select("id, sku, barcode").Where("barcode IN ?", barcodes).Find(&barcodeOwners).Error; err != nil {
+			return nil, err
+		}
+	}
+	barcodeSKU := make(map[string]string, len(barcodeOwners))
+	for _, product := range barcodeOwners {
+		barcodeSKU[product.Barcode] = product.SKU
+	}
+
+	// Produtos que já têm estoque na loja não recebem o estoque inicial
+	stocked := make(map[uint]bool)
+	if storeID != 0 && len(existing) > 0 {
+		ids := make([]uint, len(existing))
+		for i, product := range existing {
+			ids[i] = product.ID
+		}
+		var productIDs []uint
+		if err := h.DB.Model(&models.InventoryItem{}).Where("store_id = ? AND product_id IN ?", storeID, ids).
+			Pluck("product_id", &productIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range productIDs {
+			stocked[id] = true
+		}
+	}
+
+	categories, err := loadCategories(h.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	canPrice := middleware.Can(c, models.CapProductsPrice)
+	fileSKUs := make(map[string]int, len(rows))
+	fileBarcodes := make(map[string]string, len(rows))
+	var plans []importPlan
+
+	for _, row := range rows {
+		sku := row.values["sku"]
+		fail := func(column, message string) {
+			result.Errors = append(result.Errors, models.ProductImportIssue{Row: row.line, SKU: sku, Column: column, Message: message})
+		}
+		warn := func(column, message string) {
+			result.Warnings = append(result.Warnings, models.ProductImportIssue{Row: row.line, SKU: sku, Column: column, Message: message})
+		}
+		errorCount := len(result.Errors)
+
+		if sku == "" {
+			fail("sku", "SKU obrigatório")
+			continue
+		}
+		if line, ok := fileSKUs[sku]; ok {
+			fail("sku", fmt.Sprintf("SKU repetido na linha %d", line))
+			continue
+		}
+		fileSKUs[sku] = row.line
+
+		var plan importPlan
+		current, found := bySKU[sku]
+		if found && current.DeletedAt.Valid {
+			fail("sku", "SKU pertence a um produto removido")
+			continue
+		}
+		if found {
+			before := current
+			plan.before = &before
+			plan.product = current
+		} else {
+			plan.product = models.Product{SKU: sku, Active: true}
+		}
+		product := &plan.product
+
+		// Os dados comuns de uma variação vêm do estilo
+		if product.IsVariant() {
+			for _, column := range variantSharedColumns {
+				if _, ok := row.values[column]; ok {
+					warn(column, "Campo herdado do estilo; altere-o no produto "+product.Name)
+					delete(row.values, column)
+				}
+			}
+		}
+
+		for _, column := range models.ProductImportColumns {
+			value, ok := row.values[column]
+			if !ok {
+				continue
+			}
+			switch column {
+			case "name":
+				product.Name = value
+			case "description":
+				product.Description = value
+			case "category":
+				category, err := categories.resolve(value)
+				if err != nil {
+					fail(column, err.Error())
+					continue
+				}
+				if !category.Active {
+					fail(column, "Categoria inativa: "+categories.pathName(category.ID))
+					continue
+				}
+				product.CategoryID = &category.ID
+				product.Category = category.Name
+			case "brand":
+				product.Brand = value
+			case "color":
+				product.Color = value
+			case "size":
+				product.Size = value
+			case "material":
+				product.Material = value
+			case "gender":
+				product.Gender = value
+			case "season":
+				product.Season = value
+			case "image_url":
+				product.ImageURL = value
+			case "barcode":
+				if value != current.Barcode && !barcode.Valid(value) {
+					fail(column, barcode.ErrInvalid.Error())
+					continue
+				}
+				product.Barcode = value
+			case "price", "cost_price":
+				amount, err := money.Parse(value)
+				if err != nil || amount < 0 || (column == "price" && amount == 0) {
+					fail(column, "Valor inválido: "+value)
+					continue
+				}
+				if column == "price" {
+					product.Price = amount
+				} else {
+					product.CostPrice = amount
+				}
+			case "active":
+				active, err := parseImportBool(value)
+				if err != nil {
+					fail(column, "Use sim/não ou true/false: "+value)
+					continue
+				}
+				product.Active = active
+			case "stock":
+				quantity, err := strconv.Atoi(value)
+				if err != nil || quantity < 0 {
+					fail(column, "Quantidade inválida: "+value)
+					continue
+				}
+				plan.stock = &quantity
+			}
+		}
+
+		if !found {
+			if product.Name == "" {
+				fail("name", "Nome obrigatório para um produto novo")
+			}
+			if product.Category == "" {
+				fail("category", "Categoria obrigatória para um produto novo")
+			}
+			if _, ok := row.values["price"]; !ok {
+				fail("price", "Preço obrigatório para um produto novo")
+			}
+		} else if (product.Price != current.Price || product.CostPrice != current.CostPrice) && !canPrice {
+			fail("price", "Alterar preço ou custo exige a permissão "+models.CapProductsPrice)
+		}
+
+		if product.Barcode != "" {
+			if owner, ok := barcodeSKU[product.Barcode]; ok && owner != sku {
+				fail("barcode", "Código de barras já usado pelo produto "+owner)
+			} else if other, ok := fileBarcodes[product.Barcode]; ok {
+				fail("barcode", "Código de barras repetido no SKU "+other)
+			}
+			fileBarcodes[product.Barcode] = sku
+		}
+
+		if plan.stock != nil {
+			switch {
+			case product.HasVariants:
+				fail("stock", "O estoque de um estilo é controlado nas variações")
+			case product.IsBundle:
+				fail("stock", "O estoque de um kit vem dos componentes")
+			case found && stocked[current.ID]:
+				warn("stock", "Estoque ignorado: o produto já tem estoque na loja; use o ajuste de estoque")
+				plan.stock = nil
+			}
+		}
+
+		if len(result.Errors) > errorCount {
+			continue
+		}
+		switch {
+		case !found:
+			result.Created++
+		case plan.stock == nil && !productChanged(plan.before, product):
+			result.Unchanged++
+			continue
+		default:
+			result.Updated++
+		}
+		if plan.stock != nil {
+			result.StockEntries++
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// productChanged indica se a importação altera algum campo do produto
+func productChanged(before, after *models.Product) bool {
+	changes, err := audit.Diff(before, after)
+	return err != nil || len(changes) > 0
+}
+
+// applyImport grava os produtos e o estoque inicial em uma única transação.
+// Retorna os itens de inventário criados.
+func (h *Handler) applyImport(c *gin.Context, plans []importPlan, storeID uint) ([]models.InventoryItem, error) {
+	userID, _ := middleware.UserIDFromContext(c)
+	author := priceAuthor(c, models.PriceSourceImport)
+	var items []models.InventoryItem
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range plans {
+			plan := &plans[i]
+			product := &plan.product
+
+			if plan.before == nil {
+				if err := tx.Create(product).Error; err != nil {
+					return err
+				}
+				if err := pricing.RecordChange(tx, nil, product, author); err != nil {
+					return err
+				}
+				// O padrão da coluna prevaleceria sobre o false na criação
+				if !product.Active {
+					if err := tx.Model(product).Update("active", false).Error; err != nil {
+						return err
+					}
+				}
+			} else if productChanged(plan.before, product) {
+				if err := tx.Omit(clause.Associations).Save(product).Error; err != nil {
+					return err
+				}
+				if err := pricing.RecordChange(tx, plan.before, product, author); err != nil {
+					return err
+				}
+				if product.IsVariant() {
+					if err := pricing.SyncPriceOverride(tx, product); err != nil {
+						return err
+					}
+				}
+				if product.HasVariants {
+					if err := syncVariants(tx, plan.before, product, author); err != nil {
+						return err
+					}
+				}
+			}
+
+			if plan.stock == nil {
+				continue
+			}
+			item := models.InventoryItem{StoreID: storeID, ProductID: product.ID, Quantity: *plan.stock}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			items = append(items, item)
+			if *plan.stock == 0 {
+				continue
+			}
+			if err := tx.Create(&models.InventoryMovement{
+				StoreID:       storeID,
+				ProductID:     product.ID,
+				MovementType:  "entry",
+				Quantity:      *plan.stock,
+				PreviousStock: 0,
+				NewStock:      *plan.stock,
+				Reason:        "Estoque inicial (importação de produtos)",
+				UserID:        userID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+// parseImportBool aceita sim/não além dos valores de strconv.ParseBool
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "sim", "s":
+		return true, nil
+	case "não", "nao", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// ExportProducts exporta os produtos com os mesmos filtros da listagem, em
+// CSV (padrão) ou XLSX (format=xlsx), nas colunas aceitas pela importação.
+// O estoque é o da loja ativa ou das lojas visíveis; nos estilos fica vazio.
+func (h *Handler) ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", spreadsheet.FormatCSV)
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format deve ser csv ou xlsx"})
+		return
+	}
+
+	query, err := h.filterProducts(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var products []models.Product
+	if err := query.Order("id").Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
+		return
+	}
+	h.attachStock(c, products)
+
+	categories, err := loadCategories(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar categorias"})
+		return
+	}
+
+	rows := make([][]any, 0, len(products)+1)
+	header := make([]any, len(models.ProductImportColumns))
+	for i, column := range models.ProductImportColumns {
+		header[i] = column
+	}
+	rows = append(rows, header)
+	for _, p := range products {
+		var stock any
+		if p.Stock != nil && !p.HasVariants {
+			stock = *p.Stock
+		}
+		// O caminho completo identifica a categoria na reimportação
+		category := p.Category
+		if p.CategoryID != nil {
+			if path := categories.pathName(*p.CategoryID); path != "" {
+				category = path
+			}
+		}
+		rows = append(rows, []any{
+			p.SKU, p.Name, p.Description, category, p.Brand, p.Price.Float64(), p.CostPrice.Float64(), p.Barcode,
+			p.Color, p.Size, p.Material, p.Gender, p.Season, p.Active, p.ImageURL, stock,
+		})
+	}
+
+	filename := fmt.Sprintf("produtos-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Content-Type", spreadsheet.ContentType(format))
+	c.Status(http.StatusOK)
+	if err := spreadsheet.Write(c.Writer, format, rows); err != nil {
+		log.Printf("Erro ao exportar produtos: %v", err)
+	}
+}
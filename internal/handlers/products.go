@@ -1,24 +1,145 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"loja-online/internal/middleware"
 	"loja-online/internal/models"
+	"loja-online/internal/pagination"
+	"loja-online/internal/pricing"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// GetProducts retorna todos os produtos
+// productSort são os campos pelos quais a listagem de produtos pode ser ordenada
+var productSort = &pagination.Spec[models.Product]{
+	DefaultSort: "id",
+	Fields: map[string]pagination.Field[models.Product]{
+		"id":         {Column: "id", Value: func(p *models.Product) any { return p.ID }},
+		"name":       {Column: "name", Value: func(p *models.Product) any { return p.Name }},
+		"sku":        {Column: "sku", Value: func(p *models.Product) any { return p.SKU }},
+		"category":   {Column: "category", Value: func(p *models.Product) any { return p.Category }},
+		"brand":      {Column: "COALESCE(brand, '')", Value: func(p *models.Product) any { return p.Brand }},
+		"color":      {Column: "COALESCE(color, '')", Value: func(p *models.Product) any { return p.Color }},
+		"size":       {Column: "COALESCE(size, '')", Value: func(p *models.Product) any { return p.Size }},
+		"gender":     {Column: "COALESCE(gender, '')", Value: func(p *models.Product) any { return p.Gender }},
+		"season":     {Column: "COALESCE(season, '')", Value: func(p *models.Product) any { return p.Season }},
+		"price":      {Column: "price", Value: func(p *models.Product) any { return p.Price }},
+		"cost_price": {Column: "COALESCE(cost_price, 0)", Value: func(p *models.Product) any { return p.CostPrice }},
+		"active":     {Column: "COALESCE(active, false)", Value: func(p *models.Product) any { return p.Active }},
+		"created_at": {Column: "created_at", Value: func(p *models.Product) any { return p.CreatedAt }},
+		"updated_at": {Column: "updated_at", Value: func(p *models.Product) any { return p.UpdatedAt }},
+	},
+}
+
+// GetProducts retorna os produtos, filtrados, ordenados e paginados. Com
+// level=style, lista estilos e produtos simples com as variações; com
+// level=variant, apenas os itens vendáveis. search filtra por nome, SKU ou
+// código de barras.
 func (h *Handler) GetProducts(c *gin.Context) {
 	var products []models.Product
+	var total int64
+
+	page, ok := listPage(c, productSort)
+	if !ok {
+		return
+	}
 
-	if err := h.DB.Find(&products).Error; err != nil {
+	query, err := h.filterProducts(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"products": products})
+	if c.Query("level") == "style" {
+		query = query.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		})
+	}
+	if err := page.Apply(query).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
+		return
+	}
+	products = page.Trim(products)
+	h.attachStock(c, products)
+
+	c.JSON(http.StatusOK, listResponse("products", products, page.Meta(products, total)))
+}
+
+// filterProducts aplica os filtros da listagem de produtos (level, search,
+// category, brand, gender, season, color, size, active e faixa de preço)
+func (h *Handler) filterProducts(c *gin.Context) (*gorm.DB, error) {
+	query := h.DB.Model(&models.Product{})
+	search := strings.TrimSpace(c.Query("search"))
+	pattern := "%" + search + "%"
+	level := c.Query("level")
+
+	switch level {
+	case "":
+		if search != "" {
+			query = query.Where("name ILIKE ? OR sku ILIKE ? OR barcode = ?", pattern, pattern, search)
+		}
+	case "style":
+		query = query.Where("parent_id IS NULL")
+		if search != "" {
+			// O estilo aparece quando alguma variação tem o SKU ou código buscado
+			variants := h.DB.Model(&models.Product{}).Select("parent_id").
+				Where("parent_id IS NOT NULL AND (sku ILIKE ? OR barcode = ?)", pattern, search)
+			query = query.Where("name ILIKE ? OR sku ILIKE ? OR barcode = ? OR id IN (?)", pattern, pattern, search, variants)
+		}
+	case "variant":
+		query = query.Where("has_variants = ?", false)
+		if search != "" {
+			query = query.Where("name ILIKE ? OR sku ILIKE ? OR barcode = ?", pattern, pattern, search)
+		}
+	default:
+		return nil, errors.New("level deve ser style ou variant")
+	}
+
+	for _, param := range []string{"category", "brand", "gender", "season"} {
+		query = filterValues(c, query, param, param)
+	}
+	for _, param := range []string{"color", "size"} {
+		if level != "style" {
+			query = filterValues(c, query, param, param)
+			continue
+		}
+		// Cor e tamanho ficam nas variações; o estilo aparece quando alguma tem o valor
+		if values := queryValues(c, param); len(values) > 0 {
+			variants := h.DB.Model(&models.Product{}).Select("parent_id").
+				Where("parent_id IS NOT NULL AND LOWER("+param+") IN ?", values)
+			query = query.Where("LOWER("+param+") IN ? OR id IN (?)", values, variants)
+		}
+	}
+
+	// Uma categoria inclui todas as suas subcategorias
+	categoryIDs, err := queryIDs(c, "category_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(categoryIDs) > 0 {
+		query = query.Where("category_id IN (?)", h.categorySubtree(categoryIDs))
+	}
+
+	query, err = filterBool(c, query, "active", "active")
+	if err != nil {
+		return nil, err
+	}
+	query, err = filterBool(c, query, "is_bundle", "is_bundle")
+	if err != nil {
+		return nil, err
+	}
+	return filterRange(c, query, "min_price", "max_price", "price")
 }
 
 // GetProduct retorna um produto específico
@@ -30,12 +151,20 @@ func (h *Handler) GetProduct(c *gin.Context) {
 	}
 
 	var product models.Product
-	if err := h.DB.First(&product, id).Error; err != nil {
+	if err := h.DB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Preload("Components", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Components.Component").First(&product, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
 		return
 	}
+	products := []models.Product{product}
+	h.attachStock(c, products)
 
-	c.JSON(http.StatusOK, gin.H{"product": product})
+	c.JSON(http.StatusOK, gin.H{"product": products[0]})
 }
 
 // CreateProduct cria um novo produto
@@ -47,11 +176,54 @@ func (h *Handler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Create(&product).Error; err != nil {
+	// Variações são criadas pela grade do estilo
+	product.ParentID = nil
+	product.HasVariants = false
+	product.PriceOverride = false
+	product.Variants = nil
+
+	// Com componentes, o produto é um kit
+	components := product.Components
+	product.Components = nil
+	product.IsBundle = len(components) > 0
+	if product.IsBundle {
+		if status, err := h.checkBundleComponents(0, components); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	code, status, err := h.checkBarcode(product.Barcode, 0)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	product.Barcode = code
+
+	if status, err := h.assignCategory(&product); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if product.IsBundle {
+			if err := replaceBundleComponents(tx, product.ID, components); err != nil {
+				return err
+			}
+			product.Components = components
+		}
+		return pricing.RecordChange(tx, nil, &product, priceAuthor(c, models.PriceSourceInitial))
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar produto"})
 		return
 	}
 
+	h.recordAudit(c, auditEntityProduct, product.ID, models.AuditActionCreate, nil, product)
+
 	c.JSON(http.StatusCreated, gin.H{"product": product})
 }
 
@@ -75,11 +247,77 @@ func (h *Handler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Model(&product).Updates(updateData).Error; err != nil {
+	// A grade só muda pelas rotas de variações e a composição do kit pela
+	// rota de componentes
+	updateData.ParentID = nil
+	updateData.HasVariants = false
+	updateData.PriceOverride = false
+	updateData.IsBundle = false
+	updateData.Variants = nil
+	updateData.Components = nil
+
+	// Os dados comuns de uma variação vêm do estilo
+	if product.IsVariant() {
+		updateData.Name = ""
+		updateData.Description = ""
+		updateData.Category = ""
+		updateData.CategoryID = nil
+		updateData.Brand = ""
+		updateData.CostPrice = 0
+		updateData.Material = ""
+		updateData.Gender = ""
+		updateData.Season = ""
+	}
+
+	if status, err := h.assignCategory(&updateData); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updateData.Barcode != "" {
+		code, status, err := h.checkBarcode(updateData.Barcode, product.ID)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		updateData.Barcode = code
+	}
+
+	// Alterar preço ou custo exige a capacidade específica
+	priceChanged := updateData.Price != 0 && updateData.Price != product.Price
+	costChanged := updateData.CostPrice != 0 && updateData.CostPrice != product.CostPrice
+	if (priceChanged || costChanged) && !middleware.Can(c, models.CapProductsPrice) {
+		middleware.ForbiddenCapability(c, models.CapProductsPrice)
+		return
+	}
+
+	before := product
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&product).Updates(updateData).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&product, id).Error; err != nil {
+			return err
+		}
+		author := priceAuthor(c, models.PriceSourceManual)
+		if err := pricing.RecordChange(tx, &before, &product, author); err != nil {
+			return err
+		}
+		if product.IsVariant() {
+			return pricing.SyncPriceOverride(tx, &product)
+		}
+		if product.HasVariants {
+			return syncVariants(tx, &before, &product, author)
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar produto"})
 		return
 	}
 
+	h.recordAudit(c, auditEntityProduct, product.ID, models.AuditActionUpdate, before, product)
+
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
@@ -91,10 +329,90 @@ func (h *Handler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Delete(&models.Product{}, id).Error; err != nil {
+	var product models.Product
+	if err := h.DB.First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
+		return
+	}
+
+	// Um componente não sai enquanto algum kit depender dele
+	ids := []uint{product.ID}
+	if product.HasVariants {
+		var variantIDs []uint
+		h.DB.Model(&models.Product{}).Where("parent_id = ?", product.ID).Pluck("id", &variantIDs)
+		ids = append(ids, variantIDs...)
+	}
+	if skus := h.bundlesUsing(ids); len(skus) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "O produto é componente dos kits " + strings.Join(skus, ", ")})
+		return
+	}
+
+	// Remover o estilo remove também a grade
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if product.HasVariants {
+			if err := tx.Where("parent_id = ?", product.ID).Delete(&models.Product{}).Error; err != nil {
+				return err
+			}
+		}
+		if product.IsBundle {
+			if err := tx.Where("bundle_id = ?", product.ID).Delete(&models.BundleComponent{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&product).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao deletar produto"})
 		return
 	}
 
+	h.recordAudit(c, auditEntityProduct, product.ID, models.AuditActionDelete, product, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Produto deletado com sucesso"})
 }
+
+// attachStock preenche o estoque dos produtos na loja ativa ou, sem loja
+// ativa, somado nas lojas visíveis ao usuário. O estoque de um estilo é a
+// soma das variações e o de um kit, quantos os componentes permitem montar.
+// As variações pré-carregadas recebem o estoque da mesma consulta.
+func (h *Handler) attachStock(c *gin.Context, products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	var rows []struct {
+		ProductID uint
+		ParentID  *uint
+		Quantity  int
+	}
+	if err := scopeStore(c, h.DB.Model(&models.InventoryItem{}), "inventory_items.store_id").
+		Select("inventory_items.product_id, products.parent_id, SUM(inventory_items.quantity) AS quantity").
+		Joins("JOIN products ON products.id = inventory_items.product_id").
+		Where("inventory_items.product_id IN ? OR products.parent_id IN ?", ids, ids).
+		Group("inventory_items.product_id, products.parent_id").
+		Scan(&rows).Error; err != nil {
+		return
+	}
+
+	stock := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		stock[row.ProductID] += row.Quantity
+		if row.ParentID != nil {
+			stock[*row.ParentID] += row.Quantity
+		}
+	}
+	for i := range products {
+		quantity := stock[products[i].ID]
+		products[i].Stock = &quantity
+		for j := range products[i].Variants {
+			quantity := stock[products[i].Variants[j].ID]
+			products[i].Variants[j].Stock = &quantity
+		}
+	}
+	h.attachBundleStock(c, products)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+// O estoque dos estilos e de todas as variações da página vem de uma única
+// consulta ao estoque
+func TestGetProductsStyleStock(t *testing.T) {
+	h, fake := newTestHandler(t)
+	fake.On(`"parent_id" IN`).Rows([]string{"id", "parent_id", "sku"},
+		[]interface{}{11, 1, "CAM-P"},
+		[]interface{}{12, 1, "CAM-M"},
+		[]interface{}{21, 2, "CAL-38"})
+	fake.On("count(*)").Rows([]string{"count"}, []interface{}{2})
+	fake.On(`FROM "inventory_items"`).Rows([]string{"product_id", "parent_id", "quantity"},
+		[]interface{}{11, 1, 3},
+		[]interface{}{12, 1, 4},
+		[]interface{}{21, 2, 5})
+	fake.On(`FROM "products"`).Rows([]string{"id", "sku", "has_variants"},
+		[]interface{}{1, "CAM", true},
+		[]interface{}{2, "CAL", true})
+
+	w := serve(h.GetProducts, http.MethodGet, "/products?level=style", nil, asUser(2, 1, "admin"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body.String())
+	}
+
+	if lookups := fake.Queries(`FROM "inventory_items"`); len(lookups) != 1 {
+		t.Errorf("%d consultas de estoque, esperada 1", len(lookups))
+	}
+
+	want := map[float64]float64{1: 7, 2: 5, 11: 3, 12: 4, 21: 5}
+	products, _ := decode(t, w)["products"].([]interface{})
+	if len(products) != 2 {
+		t.Fatalf("%d produtos, esperados 2", len(products))
+	}
+	for _, item := range products {
+		product := item.(map[string]interface{})
+		variants, _ := product["variants"].([]interface{})
+		for _, p := range append([]interface{}{product}, variants...) {
+			p := p.(map[string]interface{})
+			if p["stock"] != want[p["id"].(float64)] {
+				t.Errorf("estoque do produto %v = %v, esperado %v", p["id"], p["stock"], want[p["id"].(float64)])
+			}
+			delete(want, p["id"].(float64))
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("produtos ausentes da resposta: %v", want)
+	}
+}
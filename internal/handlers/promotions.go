@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+	"loja-online/internal/promotions"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPromotions retorna as promoções. Com current=true, somente as ativas e
+// vigentes agora.
+func (h *Handler) GetPromotions(c *gin.Context) {
+	var list []models.Promotion
+
+	query := h.DB.Order("priority DESC, id")
+	if c.Query("current") == "true" {
+		now := time.Now()
+		query = query.Where("active = ?", true).
+			Where("starts_at IS NULL OR starts_at <= ?", now).
+			Where("ends_at IS NULL OR ends_at > ?", now)
+	} else if c.Query("active") == "true" {
+		query = query.Where("active = ?", true)
+	}
+
+	if err := query.Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar promoções"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promotions": list})
+}
+
+// CreatePromotion cria uma promoção
+func (h *Handler) CreatePromotion(c *gin.Context) {
+	var input models.PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion := models.Promotion{Active: true}
+	if status, err := h.applyPromotionInput(c, &promotion, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Create(&promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar promoção"})
+		return
+	}
+	// O default do banco prevalece sobre o false na criação
+	if !promotion.Active {
+		h.DB.Model(&promotion).Update("active", false)
+	}
+
+	h.recordAudit(c, auditEntityPromotion, promotion.ID, models.AuditActionCreate, nil, promotion)
+
+	c.JSON(http.StatusCreated, gin.H{"promotion": promotion})
+}
+
+// UpdatePromotion substitui os dados de uma promoção. Vendas já registradas
+// mantêm os descontos calculados.
+func (h *Handler) UpdatePromotion(c *gin.Context) {
+	promotion, ok := h.findPromotion(c)
+	if !ok {
+		return
+	}
+
+	var input models.PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := *promotion
+	if status, err := h.applyPromotionInput(c, promotion, &input); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar promoção"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityPromotion, promotion.ID, models.AuditActionUpdate, before, *promotion)
+
+	c.JSON(http.StatusOK, gin.H{"promotion": promotion})
+}
+
+// DeletePromotion remove uma promoção
+func (h *Handler) DeletePromotion(c *gin.Context) {
+	promotion, ok := h.findPromotion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover promoção"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityPromotion, promotion.ID, models.AuditActionDelete, *promotion, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promoção removida com sucesso"})
+}
+
+// findPromotion carrega a promoção do parâmetro id, respondendo com o erro
+// quando não encontrada
+func (h *Handler) findPromotion(c *gin.Context) (*models.Promotion, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+
+	var promotion models.Promotion
+	if err := h.DB.First(&promotion, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promoção não encontrada"})
+		return nil, false
+	}
+	return &promotion, true
+}
+
+// applyPromotionInput valida os dados de entrada e os copia para a
+// promoção, retornando o status HTTP do erro
+func (h *Handler) applyPromotionInput(c *gin.Context, promotion *models.Promotion, input *models.PromotionInput) (int, error) {
+	switch input.Type {
+	case models.PromotionPercentage:
+		if input.Percent <= 0 || input.Percent > money.BasisPoints(100_00) {
+			return http.StatusBadRequest, errors.New("percent deve ser um percentual entre 0 e 100")
+		}
+	case models.PromotionFixed:
+		if input.Value <= 0 {
+			return http.StatusBadRequest, errors.New("value deve ser o desconto por unidade")
+		}
+	case models.PromotionBuyXPayY:
+		if input.PayQuantity <= 0 || input.BuyQuantity <= input.PayQuantity {
+			return http.StatusBadRequest, errors.New("buy_quantity deve ser maior que pay_quantity, e pay_quantity maior que zero")
+		}
+	case models.PromotionProgressive:
+		if len(input.Tiers) == 0 {
+			return http.StatusBadRequest, errors.New("Informe as faixas (tiers) do desconto progressivo")
+		}
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return http.StatusBadRequest, errors.New("ends_at deve ser posterior a starts_at")
+	}
+	if input.StoreID != nil {
+		var count int64
+		h.DB.Model(&models.Store{}).Where("id = ?", *input.StoreID).Count(&count)
+		if count == 0 {
+			return http.StatusBadRequest, errors.New("Loja não encontrada")
+		}
+		if visible := middleware.StoreIDsFromContext(c); visible != nil && !containsStoreID(visible, *input.StoreID) {
+			return http.StatusForbidden, errors.New("Acesso à loja negado")
+		}
+	}
+
+	promotion.Name = input.Name
+	promotion.Description = input.Description
+	promotion.Type = input.Type
+	promotion.Value, promotion.Percent = 0, 0
+	promotion.BuyQuantity, promotion.PayQuantity = 0, 0
+	promotion.Tiers = nil
+	switch input.Type {
+	case models.PromotionPercentage:
+		promotion.Percent = input.Percent
+	case models.PromotionFixed:
+		promotion.Value = input.Value
+	case models.PromotionBuyXPayY:
+		promotion.BuyQuantity, promotion.PayQuantity = input.BuyQuantity, input.PayQuantity
+	case models.PromotionProgressive:
+		promotion.Tiers = models.PromotionTiers(input.Tiers)
+	}
+	promotion.Categories = scopeList(input.Categories)
+	promotion.Brands = scopeList(input.Brands)
+	promotion.Seasons = scopeList(input.Seasons)
+	promotion.Genders = scopeList(input.Genders)
+	promotion.SKUs = scopeList(input.SKUs)
+	promotion.StoreID = input.StoreID
+	promotion.StartsAt = input.StartsAt
+	promotion.EndsAt = input.EndsAt
+	promotion.Priority = input.Priority
+	promotion.Cumulative = input.Cumulative
+	if input.Active != nil {
+		promotion.Active = *input.Active
+	}
+	return 0, nil
+}
+
+// scopeList remove espaços e valores vazios de uma lista de escopo
+func scopeList(values []string) models.StringList {
+	var list models.StringList
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// priceSale calcula os itens e os totais da venda: o preço unitário vem do
+// cadastro e os descontos das promoções vigentes são aplicados em cada item.
+// Um unit_price diferente do cadastro só é aceito com canOverride. Retorna o
+// status HTTP do erro.
+func (h *Handler) priceSale(sale *models.Sale, canOverride bool) (int, error) {
+	if len(sale.SaleItems) == 0 {
+		return http.StatusBadRequest, errors.New("Informe os itens da venda")
+	}
+
+	ids := make([]uint, 0, len(sale.SaleItems))
+	for _, item := range sale.SaleItems {
+		if item.Quantity <= 0 {
+			return http.StatusBadRequest, errors.New("A quantidade dos itens deve ser maior que zero")
+		}
+		ids = append(ids, item.ProductID)
+	}
+
+	var products []models.Product
+	if err := h.DB.Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return http.StatusInternalServerError, errors.New("Erro ao buscar produtos")
+	}
+	byID := make(map[uint]models.Product, len(products))
+	var parentIDs, bundleIDs []uint
+	for _, product := range products {
+		byID[product.ID] = product
+		if product.IsVariant() {
+			parentIDs = append(parentIDs, *product.ParentID)
+		}
+		if product.IsBundle {
+			bundleIDs = append(bundleIDs, product.ID)
+		}
+	}
+
+	var bundles map[uint][]models.BundleComponent
+	if len(bundleIDs) > 0 {
+		var err error
+		if bundles, err = h.loadBundleComponents(bundleIDs); err != nil {
+			return http.StatusInternalServerError, errors.New("Erro ao buscar componentes dos kits")
+		}
+	}
+
+	// SKU dos estilos, para as promoções por SKU valerem para as variações
+	styleSKUs := make(map[uint]string)
+	if len(parentIDs) > 0 {
+		var styles []models.Product
+		if err := h.DB.Unscoped().Select("id, sku").Where("id IN ?", parentIDs).Find(&styles).Error; err != nil {
+			return http.StatusInternalServerError, errors.New("Erro ao buscar produtos")
+		}
+		for _, style := range styles {
+			styleSKUs[style.ID] = style.SKU
+		}
+	}
+
+	categories, err := loadCategories(h.DB)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Erro ao buscar categorias")
+	}
+
+	lines := make([]promotions.Line, len(sale.SaleItems))
+	for i := range sale.SaleItems {
+		item := &sale.SaleItems[i]
+		product, ok := byID[item.ProductID]
+		if !ok {
+			return http.StatusBadRequest, errors.New("Produto não encontrado: " + strconv.Itoa(int(item.ProductID)))
+		}
+		if product.HasVariants {
+			return http.StatusBadRequest, errors.New("O produto " + product.SKU + " é um estilo; venda uma de suas variações")
+		}
+		if product.IsBundle {
+			components := bundles[product.ID]
+			if len(components) == 0 {
+				return http.StatusBadRequest, errors.New("O kit " + product.SKU + " não tem componentes")
+			}
+			for _, component := range components {
+				if component.Component == nil {
+					return http.StatusBadRequest, errors.New("Um componente do kit " + product.SKU + " foi removido")
+				}
+			}
+		}
+		item.ListPrice = product.Price
+		item.UnitCost = unitCost(product, bundles[product.ID])
+		if item.UnitPrice <= 0 {
+			item.UnitPrice = product.Price
+		} else if item.UnitPrice != product.Price && !canOverride {
+			return http.StatusForbidden, errors.New("Alterar o preço do produto " + product.SKU + " na venda exige a capacidade " + models.CapProductsPrice)
+		}
+		lines[i] = promotions.Line{Product: product, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+		if product.IsVariant() {
+			lines[i].StyleSKU = styleSKUs[*product.ParentID]
+		}
+		if product.CategoryID != nil {
+			lines[i].Categories = categories.names(*product.CategoryID)
+		}
+	}
+
+	var active []models.Promotion
+	if err := h.DB.Where("active = ?", true).Find(&active).Error; err != nil {
+		return http.StatusInternalServerError, errors.New("Erro ao buscar promoções")
+	}
+	at := sale.SaleDate
+	if at.IsZero() {
+		at = time.Now()
+	}
+	results := promotions.Apply(active, lines, sale.StoreID, at)
+
+	sale.TotalAmount, sale.PromotionDiscount = 0, 0
+	for i := range sale.SaleItems {
+		item := &sale.SaleItems[i]
+		gross := item.UnitPrice.Mul(item.Quantity)
+		item.Discount = results[i].Discount
+		item.Promotions = results[i].Applied
+		item.TotalPrice = gross - item.Discount
+		item.Components = nil
+		if product := byID[item.ProductID]; product.IsBundle {
+			splitBundleItem(item, bundles[product.ID])
+		}
+		sale.TotalAmount += gross
+		sale.PromotionDiscount += item.Discount
+	}
+
+	if sale.Discount < 0 || sale.Discount > sale.TotalAmount-sale.PromotionDiscount {
+		return http.StatusBadRequest, errors.New("O desconto não pode ser negativo nem maior que o valor dos itens")
+	}
+	sale.FinalAmount = sale.TotalAmount - sale.PromotionDiscount - sale.Discount
+	return 0, nil
+}
+
+// unitCost retorna o custo do produto a registrar na venda. O kit sem custo
+// próprio custa a soma dos custos dos componentes.
+func unitCost(product models.Product, components []models.BundleComponent) money.Amount {
+	if !product.IsBundle || product.CostPrice > 0 {
+		return product.CostPrice
+	}
+	var cost money.Amount
+	for _, component := range components {
+		cost += component.Component.CostPrice.Mul(component.Quantity)
+	}
+	return cost
+}
+
+// QuoteSale calcula os preços, as promoções e os totais de uma venda sem
+// registrá-la, para o PDV exibir os descontos antes de fechar
+func (h *Handler) QuoteSale(c *gin.Context) {
+	var sale models.Sale
+	if err := c.ShouldBindJSON(&sale); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	storeID, ok := requireActiveStore(c)
+	if !ok {
+		return
+	}
+	sale.StoreID = storeID
+	sale.SaleDate = time.Now()
+
+	if status, err := h.priceSale(&sale, middleware.Can(c, models.CapProductsPrice)); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sale": sale})
+}
@@ -1,25 +1,90 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"loja-online/internal/middleware"
 	"loja-online/internal/models"
+	"loja-online/internal/money"
+	"loja-online/internal/pagination"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// GetSales retorna todas as vendas
+// saleSort são os campos pelos quais a listagem de vendas pode ser ordenada
+var saleSort = &pagination.Spec[models.Sale]{
+	DefaultSort: "-sale_date",
+	Fields: map[string]pagination.Field[models.Sale]{
+		"id":           {Column: "id", Value: func(s *models.Sale) any { return s.ID }},
+		"sale_date":    {Column: "sale_date", Value: func(s *models.Sale) any { return s.SaleDate }},
+		"total_amount": {Column: "total_amount", Value: func(s *models.Sale) any { return s.TotalAmount }},
+		"discount":     {Column: "COALESCE(discount, 0)", Value: func(s *models.Sale) any { return s.Discount }},
+		"final_amount": {Column: "final_amount", Value: func(s *models.Sale) any { return s.FinalAmount }},
+		"status":       {Column: "COALESCE(status, '')", Value: func(s *models.Sale) any { return s.Status }},
+		"created_at":   {Column: "created_at", Value: func(s *models.Sale) any { return s.CreatedAt }},
+	},
+}
+
+// GetSales retorna as vendas das lojas visíveis, filtradas, ordenadas e
+// paginadas. start_date e end_date (AAAA-MM-DD) são inclusivos.
 func (h *Handler) GetSales(c *gin.Context) {
 	var sales []models.Sale
+	var total int64
+
+	page, ok := listPage(c, saleSort)
+	if !ok {
+		return
+	}
+
+	query := scopeStore(c, h.DB.Model(&models.Sale{}), "store_id")
+	query = filterValues(c, query, "status", "status")
+	query = filterValues(c, query, "payment_method", "payment_method")
+	if customerID := c.Query("customer_id"); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.ParseInLocation(periodDateLayout, startDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date deve estar no formato AAAA-MM-DD"})
+			return
+		}
+		query = query.Where("sale_date >= ?", start)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.ParseInLocation(periodDateLayout, endDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end_date deve estar no formato AAAA-MM-DD"})
+			return
+		}
+		query = query.Where("sale_date < ?", end.AddDate(0, 0, 1))
+	}
+
+	query, err := filterRange(c, query, "min_amount", "max_amount", "final_amount")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.DB.Preload("Customer").Preload("User").Preload("SaleItems.Product").Find(&sales).Error; err != nil {
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar vendas"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sales": sales})
+	if err := page.Apply(query).Preload("Customer").Preload("User").Preload("SaleItems.Product").Find(&sales).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar vendas"})
+		return
+	}
+	sales = page.Trim(sales)
+
+	c.JSON(http.StatusOK, listResponse("sales", sales, page.Meta(sales, total)))
 }
 
 // GetSale retorna uma venda específica
@@ -31,7 +96,7 @@ func (h *Handler) GetSale(c *gin.Context) {
 	}
 
 	var sale models.Sale
-	if err := h.DB.Preload("Customer").Preload("User").Preload("SaleItems.Product").First(&sale, id).Error; err != nil {
+	if err := scopeStore(c, h.DB, "store_id").Preload("Customer").Preload("User").Preload("SaleItems.Product").Preload("SaleItems.Components").First(&sale, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Venda não encontrada"})
 		return
 	}
@@ -48,11 +113,22 @@ func (h *Handler) CreateSale(c *gin.Context) {
 		return
 	}
 
+	storeID, ok := requireActiveStore(c)
+	if !ok {
+		return
+	}
+
+	// A venda e a baixa de estoque pertencem à loja ativa
+	sale.StoreID = storeID
+
 	// Define a data da venda
 	sale.SaleDate = time.Now()
 
-	// Calcula o valor final
-	sale.FinalAmount = sale.TotalAmount - sale.Discount
+	// Calcula os itens com as promoções vigentes e o valor final
+	if status, err := h.priceSale(&sale, middleware.Can(c, models.CapProductsPrice)); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Inicia transação
 	tx := h.DB.Begin()
@@ -63,10 +139,29 @@ func (h *Handler) CreateSale(c *gin.Context) {
 		return
 	}
 
-	// Atualiza o estoque para cada item vendido
+	// Atualiza o estoque para cada item vendido; kits baixam os componentes
+	type exit struct {
+		ProductID uint
+		Quantity  int
+		Reason    string
+	}
+	var exits []exit
 	for _, item := range sale.SaleItems {
+		if len(item.Components) == 0 {
+			exits = append(exits, exit{ProductID: item.ProductID, Quantity: item.Quantity, Reason: "Venda #" + strconv.Itoa(int(sale.ID))})
+			continue
+		}
+		for _, component := range item.Components {
+			exits = append(exits, exit{
+				ProductID: component.ProductID,
+				Quantity:  component.Quantity,
+				Reason:    "Venda #" + strconv.Itoa(int(sale.ID)) + ", kit #" + strconv.Itoa(int(item.ProductID)),
+			})
+		}
+	}
+	for _, item := range exits {
 		var inventoryItem models.InventoryItem
-		if err := tx.Where("product_id = ?", item.ProductID).First(&inventoryItem).Error; err != nil {
+		if err := tx.Where("store_id = ? AND product_id = ?", storeID, item.ProductID).First(&inventoryItem).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Produto não encontrado no estoque"})
 			return
@@ -88,12 +183,13 @@ func (h *Handler) CreateSale(c *gin.Context) {
 
 		// Registra movimento de estoque
 		movement := models.InventoryMovement{
+			StoreID:       storeID,
 			ProductID:     item.ProductID,
 			MovementType:  "exit",
 			Quantity:      -int(item.Quantity),
 			PreviousStock: int(inventoryItem.Quantity) + int(item.Quantity),
 			NewStock:      int(inventoryItem.Quantity),
-			Reason:        "Venda #" + strconv.Itoa(int(sale.ID)),
+			Reason:        item.Reason,
 			UserID:        sale.UserID,
 		}
 		if err := tx.Create(&movement).Error; err != nil {
@@ -106,11 +202,41 @@ func (h *Handler) CreateSale(c *gin.Context) {
 	tx.Commit()
 
 	// Recarrega a venda com os relacionamentos
-	h.DB.Preload("Customer").Preload("User").Preload("SaleItems.Product").First(&sale, sale.ID)
+	h.DB.Preload("Customer").Preload("User").Preload("SaleItems.Product").Preload("SaleItems.Components").First(&sale, sale.ID)
+
+	h.recordAudit(c, auditEntitySale, sale.ID, models.AuditActionCreate, nil, saleAudit(sale))
 
 	c.JSON(http.StatusCreated, gin.H{"sale": sale})
 }
 
+// priceOverride é um preço de venda diferente do cadastro, registrado na
+// auditoria
+type priceOverride struct {
+	ListPrice money.Amount `json:"list_price"`
+	UnitPrice money.Amount `json:"unit_price"`
+}
+
+// saleAuditEntry acrescenta à auditoria da venda os preços alterados no PDV,
+// por SKU, já que os itens não entram na comparação
+type saleAuditEntry struct {
+	models.Sale
+	PriceOverrides map[string]priceOverride `json:"price_overrides,omitempty"`
+}
+
+func saleAudit(sale models.Sale) saleAuditEntry {
+	entry := saleAuditEntry{Sale: sale}
+	for _, item := range sale.SaleItems {
+		if item.UnitPrice == item.ListPrice {
+			continue
+		}
+		if entry.PriceOverrides == nil {
+			entry.PriceOverrides = make(map[string]priceOverride)
+		}
+		entry.PriceOverrides[item.Product.SKU] = priceOverride{ListPrice: item.ListPrice, UnitPrice: item.UnitPrice}
+	}
+	return entry
+}
+
 // UpdateSale atualiza uma venda existente
 func (h *Handler) UpdateSale(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -120,45 +246,65 @@ func (h *Handler) UpdateSale(c *gin.Context) {
 	}
 
 	var sale models.Sale
-	if err := h.DB.First(&sale, id).Error; err != nil {
+	if err := scopeStore(c, h.DB, "store_id").First(&sale, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Venda não encontrada"})
 		return
 	}
 
-	var updateData models.Sale
+	var updateData models.SaleUpdate
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Recalcula valor final se necessário
-	if updateData.TotalAmount > 0 || updateData.Discount >= 0 {
-		totalAmount := updateData.TotalAmount
-		if totalAmount == 0 {
-			totalAmount = sale.TotalAmount
+	// Valores, itens e vendedor vêm do registro da venda e não são editáveis
+	updates := map[string]interface{}{}
+	if updateData.PaymentMethod != nil {
+		updates["payment_method"] = *updateData.PaymentMethod
+	}
+	if updateData.Notes != nil {
+		updates["notes"] = *updateData.Notes
+	}
+	if updateData.Status != nil && *updateData.Status != sale.Status {
+		updates["status"] = *updateData.Status
+		voiding := models.IsVoidStatus(*updateData.Status) && !models.IsVoidStatus(sale.Status)
+
+		// Vendas de período de comissões fechado só podem ser canceladas ou
+		// devolvidas, e o estorno entra no período do cancelamento
+		if !voiding && h.inClosedCommissionPeriod(sale.SaleDate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Venda pertence a um período de comissões fechado"})
+			return
 		}
-		discount := updateData.Discount
-		if updateData.Discount == 0 && sale.Discount != 0 {
-			discount = sale.Discount
+
+		// Cancelar ou registrar devolução exige a capacidade específica
+		if voiding {
+			if !middleware.Can(c, models.CapSalesCancel) {
+				middleware.ForbiddenCapability(c, models.CapSalesCancel)
+				return
+			}
+			updates["cancelled_at"] = time.Now()
 		}
-		updateData.FinalAmount = totalAmount - discount
 	}
 
-	if err := h.DB.Model(&sale).Updates(updateData).Error; err != nil {
+	before := sale
+	if err := h.DB.Model(&sale).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar venda"})
 		return
 	}
 
+	h.DB.First(&sale, id)
+	h.recordAudit(c, auditEntitySale, sale.ID, models.AuditActionUpdate, before, sale)
+
 	c.JSON(http.StatusOK, gin.H{"sale": sale})
 }
 
 // GetSalesReport gera relatório de vendas
 func (h *Handler) GetSalesReport(c *gin.Context) {
 	var sales []models.Sale
-	var totalSales float64
+	var totalSales money.Amount
 	var totalCount int64
 
-	query := h.DB.Model(&models.Sale{})
+	query := scopeStore(c, h.DB.Model(&models.Sale{}), "store_id")
 
 	// Filtros opcionais
 	if startDate := c.Query("start_date"); startDate != "" {
@@ -171,6 +317,9 @@ func (h *Handler) GetSalesReport(c *gin.Context) {
 		query = query.Where("status = ?", status)
 	}
 
+	// Permite reutilizar os filtros nas consultas abaixo
+	query = query.Session(&gorm.Session{})
+
 	// Conta total e soma valores
 	query.Count(&totalCount)
 	query.Select("SUM(final_amount)").Row().Scan(&totalSales)
@@ -181,9 +330,127 @@ func (h *Handler) GetSalesReport(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"sales":       sales,
 		"total_sales": totalSales,
 		"total_count": totalCount,
-	})
+	}
+
+	// Totais por estilo ou por variação
+	if level := c.Query("level"); level != "" {
+		byProduct, err := h.salesByProduct(query, level)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		response["by_product"] = byProduct
+	}
+
+	// Totais por categoria em um nível da árvore ou nas filhas de uma categoria
+	if c.Query("category_level") != "" || c.Query("category_parent_id") != "" {
+		byCategory, err := h.salesByCategory(query, c.Query("category_level"), c.Query("category_parent_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		response["by_category"] = byCategory
+	}
+
+	// Na visão consolidada, detalha os totais por loja
+	if _, ok := middleware.StoreIDFromContext(c); !ok {
+		var byStore []struct {
+			StoreID     uint         `json:"store_id"`
+			StoreName   string       `json:"store_name"`
+			TotalCount  int64        `json:"total_count"`
+			TotalAmount money.Amount `json:"total_amount"`
+		}
+		if err := query.Select("sales.store_id, stores.name AS store_name, COUNT(*) AS total_count, COALESCE(SUM(sales.final_amount), 0) AS total_amount").
+			Joins("LEFT JOIN stores ON stores.id = sales.store_id").
+			Group("sales.store_id, stores.name").
+			Order("sales.store_id").
+			Scan(&byStore).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório"})
+			return
+		}
+		response["by_store"] = byStore
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// productSales são os totais vendidos de um produto no relatório
+type productSales struct {
+	ProductID uint         `json:"product_id"`
+	Name      string       `json:"name"`
+	SKU       string       `json:"sku"`
+	Size      string       `json:"size,omitempty"`
+	Color     string       `json:"color,omitempty"`
+	Quantity  int64        `json:"quantity"`
+	Revenue   money.Amount `json:"revenue"`
+}
+
+// salesByProduct soma os itens das vendas filtradas por variação (variant) ou
+// agrupando as variações no estilo (style). Kits entram pelos componentes.
+func (h *Handler) salesByProduct(sales *gorm.DB, level string) ([]productSales, error) {
+	query := h.DB.Table("(?) AS sale_items", h.soldLines(sales)).
+		Joins("JOIN products AS variants ON variants.id = sale_items.product_id")
+
+	switch level {
+	case "style":
+		query = query.Joins("JOIN products ON products.id = COALESCE(variants.parent_id, variants.id)").
+			Select("products.id AS product_id, products.name, products.sku, SUM(sale_items.quantity) AS quantity, SUM(sale_items.total_price) AS revenue").
+			Group("products.id, products.name, products.sku")
+	case "variant":
+		query = query.Select("variants.id AS product_id, variants.name, variants.sku, variants.size, variants.color, SUM(sale_items.quantity) AS quantity, SUM(sale_items.total_price) AS revenue").
+			Group("variants.id, variants.name, variants.sku, variants.size, variants.color")
+	default:
+		return nil, errors.New("level deve ser style ou variant")
+	}
+
+	var rows []productSales
+	err := query.Order("revenue DESC").Scan(&rows).Error
+	return rows, err
+}
+
+// categorySales são os totais vendidos de uma categoria e suas subcategorias
+type categorySales struct {
+	CategoryID uint         `json:"category_id"`
+	Name       string       `json:"name"`
+	Slug       string       `json:"slug"`
+	Depth      int          `json:"depth"`
+	Quantity   int64        `json:"quantity"`
+	Revenue    money.Amount `json:"revenue"`
+}
+
+// salesByCategory soma os itens das vendas filtradas nas categorias do nível
+// informado (1 para as raízes) ou nas filhas de parentID, incluindo em cada
+// uma as vendas das subcategorias. Itens de categorias acima do nível pedido
+// não entram. Kits entram pelos componentes.
+func (h *Handler) salesByCategory(sales *gorm.DB, level, parentID string) ([]categorySales, error) {
+	query := h.DB.Table("(?) AS sale_items", h.soldLines(sales)).
+		Joins("JOIN products ON products.id = sale_items.product_id").
+		Joins("JOIN categories AS c ON c.id = products.category_id").
+		Joins("JOIN categories AS a ON c.path LIKE a.path || '%'").
+		Where("a.deleted_at IS NULL")
+
+	if parentID != "" {
+		id, err := strconv.Atoi(parentID)
+		if err != nil {
+			return nil, errors.New("category_parent_id inválido")
+		}
+		query = query.Where("a.parent_id = ?", id)
+	} else {
+		depth, err := strconv.Atoi(level)
+		if err != nil || depth < 1 {
+			return nil, errors.New("category_level deve ser um número a partir de 1")
+		}
+		query = query.Where("a.depth = ?", depth)
+	}
+
+	var rows []categorySales
+	err := query.Select("a.id AS category_id, a.name, a.slug, a.depth, SUM(sale_items.quantity) AS quantity, SUM(sale_items.total_price) AS revenue").
+		Group("a.id, a.name, a.slug, a.depth").
+		Order("revenue DESC").
+		Scan(&rows).Error
+	return rows, err
 }
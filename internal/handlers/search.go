@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSearchLimit       = 20
+	maxSearchLimit           = 100
+	defaultAutocompleteLimit = 8
+	maxAutocompleteLimit     = 20
+)
+
+// productSuggestion é o resumo do produto retornado pelo autocompletar do PDV
+type productSuggestion struct {
+	ID       uint         `json:"id"`
+	Name     string       `json:"name"`
+	SKU      string       `json:"sku"`
+	Barcode  string       `json:"barcode"`
+	Color    string       `json:"color"`
+	Size     string       `json:"size"`
+	Price    money.Amount `json:"price"`
+	ImageURL string       `json:"image_url"`
+	Stock    *int         `json:"stock,omitempty"`
+}
+
+// searchTerms separa o texto buscado em palavras, descartando pontuação e os
+// operadores de to_tsquery
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchQuery monta a consulta ranqueada: produtos cujo vetor contém todas
+// as palavras (com radicais em português e sem acentos), cujo nome é
+// parecido com o texto buscado (trigramas) ou cujo SKU começa com ele.
+// Com prefix, cada palavra também casa com as que começam por ela.
+func (h *Handler) searchQuery(text string, prefix bool) *gorm.DB {
+	terms := searchTerms(text)
+	phrase := strings.Join(terms, " ")
+	if prefix {
+		for i := range terms {
+			terms[i] += ":*"
+		}
+	}
+	tsquery := strings.Join(terms, " & ")
+
+	return h.DB.Model(&models.Product{}).
+		Select(`products.id, ts_rank(search_vector, to_tsquery('portuguese', immutable_unaccent(?)))
+			+ word_similarity(immutable_unaccent(?), immutable_unaccent(lower(name))) AS rank`, tsquery, phrase).
+		Where(`search_vector @@ to_tsquery('portuguese', immutable_unaccent(?))
+			OR immutable_unaccent(?) <% immutable_unaccent(lower(name))
+			OR sku ILIKE ? OR barcode = ?`, tsquery, phrase, strings.TrimSpace(text)+"%", strings.TrimSpace(text)).
+		Order("rank DESC, products.id")
+}
+
+// searchLimit lê o limite de resultados da busca
+func searchLimit(c *gin.Context, fallback, maximum int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return fallback
+	}
+	return min(limit, maximum)
+}
+
+// rankedProducts executa a busca e carrega os produtos na ordem do ranking
+func (h *Handler) rankedProducts(query *gorm.DB, limit int) ([]models.Product, error) {
+	var ranked []struct {
+		ID   uint
+		Rank float64
+	}
+	if err := query.Limit(limit).Scan(&ranked).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(ranked))
+	for i, row := range ranked {
+		ids[i] = row.ID
+	}
+
+	var found []models.Product
+	if len(ids) > 0 {
+		if err := h.DB.Where("id IN ?", ids).Find(&found).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[uint]models.Product, len(found))
+	for _, product := range found {
+		byID[product.ID] = product
+	}
+
+	products := make([]models.Product, 0, len(found))
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			products = append(products, product)
+		}
+	}
+	return products, nil
+}
+
+// SearchProducts faz a busca textual de produtos em nome, descrição, marca,
+// categoria, material e SKU, sem diferenciar acentos e tolerando erros de
+// digitação, com os resultados mais relevantes primeiro. Aceita level e active
+// como a listagem.
+func (h *Handler) SearchProducts(c *gin.Context) {
+	text := c.Query("q")
+	if len(searchTerms(text)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o texto da busca em q"})
+		return
+	}
+
+	query := h.searchQuery(text, false)
+	switch c.Query("level") {
+	case "":
+	case "style":
+		query = query.Where("parent_id IS NULL")
+	case "variant":
+		query = query.Where("has_variants = ?", false)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "level deve ser style ou variant"})
+		return
+	}
+	query, err := filterBool(c, query, "active", "active")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	products, err := h.rankedProducts(query, searchLimit(c, defaultSearchLimit, maxSearchLimit))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
+		return
+	}
+	h.attachStock(c, products)
+
+	c.JSON(http.StatusOK, gin.H{"products": products})
+}
+
+// AutocompleteProducts sugere, enquanto o operador digita no PDV, os itens
+// ativos e vendáveis cujas palavras começam com o texto digitado
+func (h *Handler) AutocompleteProducts(c *gin.Context) {
+	text := c.Query("q")
+	if len(searchTerms(text)) == 0 {
+		c.JSON(http.StatusOK, gin.H{"suggestions": []productSuggestion{}})
+		return
+	}
+
+	query := h.searchQuery(text, true).Where("active = ? AND has_variants = ?", true, false)
+	products, err := h.rankedProducts(query, searchLimit(c, defaultAutocompleteLimit, maxAutocompleteLimit))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
+		return
+	}
+	h.attachStock(c, products)
+
+	suggestions := make([]productSuggestion, len(products))
+	for i, product := range products {
+		suggestions[i] = productSuggestion{
+			ID:       product.ID,
+			Name:     product.Name,
+			SKU:      product.SKU,
+			Barcode:  product.Barcode,
+			Color:    product.Color,
+			Size:     product.Size,
+			Price:    product.Price,
+			ImageURL: product.ImageURL,
+			Stock:    product.Stock,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"loja-online/internal/dbtest"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Camiseta Azul", []string{"camiseta", "azul"}},
+		{"calça & (jeans) | !skinny:*", []string{"calça", "jeans", "skinny"}},
+		{"CAM-BAS-BCO-P", []string{"cam", "bas", "bco", "p"}},
+		{"  <-> ", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := searchTerms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("palavras de %q = %q, esperado %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// rankedResults responde à consulta ranqueada com os IDs na ordem do ranking
+// e à carga dos produtos na ordem do banco
+func rankedResults(fake *dbtest.DB) {
+	fake.On("ts_rank(").Rows([]string{"id", "rank"},
+		[]interface{}{3, 0.9},
+		[]interface{}{1, 0.5},
+		[]interface{}{2, 0.1})
+	fake.On("WHERE id IN").Rows([]string{"id", "name"},
+		[]interface{}{1, "Camiseta Básica"},
+		[]interface{}{3, "Camiseta Azul"})
+}
+
+func TestSearchProductsKeepsRanking(t *testing.T) {
+	h, fake := newTestHandler(t)
+	rankedResults(fake)
+
+	w := serve(h.SearchProducts, http.MethodGet, "/products/search?q=camiseta", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body.String())
+	}
+
+	products, _ := decode(t, w)["products"].([]interface{})
+	var ids []float64
+	for _, product := range products {
+		ids = append(ids, product.(map[string]interface{})["id"].(float64))
+	}
+	// O produto 2 foi removido entre a busca e a carga
+	if !reflect.DeepEqual(ids, []float64{3, 1}) {
+		t.Errorf("ordem = %v, esperado [3 1]", ids)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		autocomplete bool
+		tsquery      string
+		conditions   []string
+		limit        int64
+	}{
+		{"busca", "/products/search?q=Camiseta+azul", false, "camiseta & azul", nil, defaultSearchLimit},
+		{"estilos", "/products/search?q=camiseta&level=style", false, "camiseta", []string{"parent_id IS NULL"}, defaultSearchLimit},
+		{"variações", "/products/search?q=camiseta&level=variant", false, "camiseta", []string{"has_variants = $"}, defaultSearchLimit},
+		{"ativos", "/products/search?q=camiseta&active=true&limit=500", false, "camiseta", []string{"active = $"}, maxSearchLimit},
+		{"autocompletar por prefixo", "/products/autocomplete?q=cam+az", true, "cam:* & az:*", []string{"active = $", "has_variants = $"}, defaultAutocompleteLimit},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		handler := h.SearchProducts
+		if tt.autocomplete {
+			handler = h.AutocompleteProducts
+		}
+
+		if w := serve(handler, http.MethodGet, tt.target, nil, nil); w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, esperado 200: %s", tt.name, w.Code, w.Body.String())
+		}
+
+		queries := fake.Queries("ts_rank(")
+		if len(queries) != 1 {
+			t.Fatalf("%s: %d consultas ranqueadas, esperada 1", tt.name, len(queries))
+		}
+		query := queries[0]
+		if query.Args[0] != tt.tsquery || query.Args[2] != tt.tsquery {
+			t.Errorf("%s: tsquery = %v, esperado %q", tt.name, query.Args, tt.tsquery)
+		}
+		if !strings.Contains(query.SQL, "ORDER BY rank DESC, products.id") {
+			t.Errorf("%s: os resultados devem vir pelo ranking: %s", tt.name, query.SQL)
+		}
+		for _, condition := range tt.conditions {
+			if !strings.Contains(query.SQL, condition) {
+				t.Errorf("%s: falta a condição %q em %s", tt.name, condition, query.SQL)
+			}
+		}
+		if limit := query.Args[len(query.Args)-1]; limit != tt.limit {
+			t.Errorf("%s: limite = %v, esperado %d", tt.name, limit, tt.limit)
+		}
+	}
+}
+
+func TestSearchProductsRejected(t *testing.T) {
+	for _, target := range []string{"/products/search?q=+&+", "/products/search?q=camiseta&level=kit", "/products/search?q=camiseta&active=talvez"} {
+		h, fake := newTestHandler(t)
+		if w := serve(h.SearchProducts, http.MethodGet, target, nil, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, esperado 400", target, w.Code)
+		}
+		if fake.Executed("SELECT") {
+			t.Errorf("%s: nenhuma consulta deve ser feita", target)
+		}
+	}
+}
+
+func TestAutocompleteWithoutText(t *testing.T) {
+	h, fake := newTestHandler(t)
+
+	w := serve(h.AutocompleteProducts, http.MethodGet, "/products/autocomplete?q=-", nil, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"suggestions":[]`) {
+		t.Errorf("resposta = %d %s, esperada lista vazia", w.Code, w.Body.String())
+	}
+	if fake.Executed("SELECT") {
+		t.Error("nenhuma consulta deve ser feita")
+	}
+}
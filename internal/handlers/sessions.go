@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// newAccessToken gera o JWT de curta duração vinculado a uma sessão e à sua
+// loja ativa
+func (h *Handler) newAccessToken(user *models.User, session *models.Session) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"sid":     session.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(h.Config.AccessTokenTTL).Unix(),
+	}
+	if session.StoreID != nil {
+		claims["store_id"] = *session.StoreID
+	}
+	return h.Keys.Sign(claims)
+}
+
+// startSession cria uma sessão para o usuário e responde com o par de tokens
+func (h *Handler) startSession(c *gin.Context, user *models.User) {
+	session, refreshToken, err := h.createSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar sessão"})
+		return
+	}
+
+	h.respondWithTokens(c, user, session, refreshToken)
+}
+
+// createSession grava uma nova sessão e retorna o refresh token em texto puro
+func (h *Handler) createSession(c *gin.Context, user *models.User) (*models.Session, string, error) {
+	refreshToken, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:           user.ID,
+		StoreID:          h.defaultStoreID(user),
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
+		ExpiresAt:        now.Add(h.Config.RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if err := h.DB.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &session, refreshToken, nil
+}
+
+// respondWithTokens gera o token de acesso e devolve os dois tokens ao cliente
+func (h *Handler) respondWithTokens(c *gin.Context, user *models.User, session *models.Session, refreshToken string) {
+	tokenString, err := h.newAccessToken(user, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":                     tokenString,
+		"expires_in":                int(h.Config.AccessTokenTTL.Seconds()),
+		"refresh_token":             refreshToken,
+		"refresh_expires_at":        session.ExpiresAt,
+		"user":                      user,
+		"two_factor_setup_required": h.twoFactorSetupRequired(user),
+	})
+}
+
+// revokeUserSessions encerra todas as sessões ativas de um usuário
+func revokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Refresh troca um refresh token válido por um novo par de tokens. O refresh
+// token é rotacionado a cada uso; a reapresentação de um token já rotacionado
+// indica vazamento e encerra a sessão.
+func (h *Handler) Refresh(c *gin.Context) {
+	var input models.RefreshRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenHash := hashToken(input.RefreshToken)
+	now := time.Now()
+
+	var session models.Session
+	if err := h.DB.Preload("User").Where("refresh_token_hash = ?", tokenHash).First(&session).Error; err != nil {
+		// Token antigo reutilizado: revoga a sessão inteira
+		var reused models.Session
+		if h.DB.Where("previous_token_hash = ? AND revoked_at IS NULL", tokenHash).First(&reused).Error == nil {
+			h.revokeSession(c, reused)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token inválido"})
+		return
+	}
+
+	if !session.IsActive(now) || session.User.ID == 0 || !session.User.Active {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sessão inválida ou encerrada"})
+		return
+	}
+
+	newRefreshToken, err := generateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		return
+	}
+
+	// Atualização condicional para que duas renovações simultâneas não gerem dois tokens válidos
+	result := h.DB.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  hashToken(newRefreshToken),
+			"previous_token_hash": tokenHash,
+			"last_used_at":        now,
+			"ip_address":          c.ClientIP(),
+			"user_agent":          c.Request.UserAgent(),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token inválido"})
+		return
+	}
+
+	h.respondWithTokens(c, &session.User, &session, newRefreshToken)
+}
+
+// revokeSession encerra a sessão, se ainda ativa, e registra na auditoria.
+// Retorna false se ela já estava encerrada.
+func (h *Handler) revokeSession(c *gin.Context, session models.Session) (bool, error) {
+	before := session
+	now := time.Now()
+
+	result := h.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", session.ID).
+		Update("revoked_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	session.RevokedAt = &now
+	h.recordAudit(c, auditEntitySession, session.ID, models.AuditActionUpdate, before, session)
+	return true, nil
+}
+
+// Logout encerra a sessão do token atual
+func (h *Handler) Logout(c *gin.Context) {
+	sessionID, ok := middleware.SessionIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+		return
+	}
+
+	var session models.Session
+	if err := h.DB.First(&session, sessionID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+		return
+	}
+	if _, err := h.revokeSession(c, session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessão"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sessão encerrada com sucesso"})
+}
+
+// GetSessions retorna as sessões ativas do usuário logado
+func (h *Handler) GetSessions(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+	currentID, _ := middleware.SessionIDFromContext(c)
+
+	var sessions []models.Session
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar sessões"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":           sessions,
+		"current_session_id": currentID,
+	})
+}
+
+// RevokeSession encerra uma sessão do usuário logado
+func (h *Handler) RevokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+
+	var session models.Session
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL", userID).First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+
+	revoked, err := h.revokeSession(c, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao encerrar sessão"})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sessão encerrada com sucesso"})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/dbtest"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var sessionColumns = []string{"id", "user_id", "refresh_token_hash", "previous_token_hash", "expires_at", "revoked_at"}
+
+// activeSession responde à busca da sessão pelo hash do refresh token
+func activeSession(fake *dbtest.DB, fragment, tokenHash string) {
+	fake.On(fragment).Once().Rows(sessionColumns,
+		[]interface{}{7, 3, tokenHash, "", time.Now().Add(time.Hour), nil})
+	fake.On(`FROM "users"`).Once().Rows([]string{"id", "email", "role", "active"},
+		[]interface{}{3, "ana@loja.test", "admin", true})
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	h, fake := newTestHandler(t)
+	activeSession(fake, "refresh_token_hash = $1", hashToken("antigo"))
+
+	w := serve(h.Refresh, http.MethodPost, "/auth/refresh", models.RefreshRequest{RefreshToken: "antigo"}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body.String())
+	}
+
+	newToken, _ := decode(t, w)["refresh_token"].(string)
+	if newToken == "" || newToken == "antigo" {
+		t.Fatalf("refresh token não foi rotacionado: %q", newToken)
+	}
+
+	updates := fake.Queries(`UPDATE "sessions" SET`)
+	if len(updates) != 1 {
+		t.Fatalf("%d atualizações da sessão, esperada 1", len(updates))
+	}
+	update := updates[0]
+	if !contains(update.Args, hashToken(newToken)) || !contains(update.Args, hashToken("antigo")) {
+		t.Errorf("a atualização deve gravar o hash do novo token e guardar o anterior: %v", update.Args)
+	}
+	if !contains(update.Args, int64(7)) || !strings.Contains(update.SQL, "AND refresh_token_hash = $") {
+		t.Errorf("a atualização deve ser condicionada à sessão 7 e ao token apresentado: %s %v", update.SQL, update.Args)
+	}
+}
+
+func TestRefreshLosesConcurrentRotation(t *testing.T) {
+	h, fake := newTestHandler(t)
+	activeSession(fake, "refresh_token_hash = $1", hashToken("antigo"))
+	// Outra renovação trocou o token entre a leitura e a atualização
+	fake.On(`UPDATE "sessions" SET`).Affect(0)
+
+	w := serve(h.Refresh, http.MethodPost, "/auth/refresh", models.RefreshRequest{RefreshToken: "antigo"}, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, esperado 401", w.Code)
+	}
+}
+
+func TestRefreshRejectsRevokedSession(t *testing.T) {
+	h, fake := newTestHandler(t)
+	fake.On("refresh_token_hash = $1").Rows(sessionColumns,
+		[]interface{}{7, 3, hashToken("antigo"), "", time.Now().Add(time.Hour), time.Now()})
+	fake.On(`FROM "users"`).Rows([]string{"id", "active"}, []interface{}{3, true})
+
+	w := serve(h.Refresh, http.MethodPost, "/auth/refresh", models.RefreshRequest{RefreshToken: "antigo"}, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, esperado 401", w.Code)
+	}
+	if fake.Executed(`UPDATE "sessions"`) {
+		t.Error("sessão encerrada não deve ser renovada")
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	h, fake := newTestHandler(t)
+	// O token já foi rotacionado: não é o atual de nenhuma sessão, mas é o
+	// anterior da sessão 7
+	fake.On("previous_token_hash = $1").Rows(sessionColumns,
+		[]interface{}{7, 3, hashToken("novo"), hashToken("antigo"), time.Now().Add(time.Hour), nil})
+
+	w := serve(h.Refresh, http.MethodPost, "/auth/refresh", models.RefreshRequest{RefreshToken: "antigo"}, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, esperado 401", w.Code)
+	}
+
+	revokes := fake.Queries(`UPDATE "sessions" SET "revoked_at"`)
+	if len(revokes) != 1 || !contains(revokes[0].Args, int64(7)) || !strings.Contains(revokes[0].SQL, "revoked_at IS NULL") {
+		t.Fatalf("a sessão 7 deve ser encerrada, se ainda ativa: %v", revokes)
+	}
+	if !fake.Executed(`INSERT INTO "audit_logs"`) {
+		t.Error("o encerramento deve ser registrado na auditoria")
+	}
+}
+
+func TestRefreshUnknownToken(t *testing.T) {
+	h, fake := newTestHandler(t)
+
+	w := serve(h.Refresh, http.MethodPost, "/auth/refresh", models.RefreshRequest{RefreshToken: "desconhecido"}, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, esperado 401", w.Code)
+	}
+	if fake.Executed("UPDATE") {
+		t.Error("token desconhecido não deve alterar sessões")
+	}
+}
+
+func TestLogoutRevokesCurrentSession(t *testing.T) {
+	h, fake := newTestHandler(t)
+	fake.On(`FROM "sessions"`).Rows(sessionColumns,
+		[]interface{}{7, 3, hashToken("atual"), "", time.Now().Add(time.Hour), nil})
+
+	w := serve(h.Logout, http.MethodPost, "/auth/logout", nil, asUser(3, 7, "user"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200: %s", w.Code, w.Body.String())
+	}
+
+	lookup := fake.Queries(`FROM "sessions"`)
+	if len(lookup) != 1 || !contains(lookup[0].Args, int64(7)) {
+		t.Errorf("deve buscar a sessão do token: %v", lookup)
+	}
+	revokes := fake.Queries(`UPDATE "sessions" SET "revoked_at"`)
+	if len(revokes) != 1 || !contains(revokes[0].Args, int64(7)) {
+		t.Fatalf("a sessão 7 deve ser encerrada: %v", revokes)
+	}
+	if !fake.Executed(`INSERT INTO "audit_logs"`) {
+		t.Error("o encerramento deve ser registrado na auditoria")
+	}
+}
+
+func TestLogoutWithoutSession(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	w := serve(h.Logout, http.MethodPost, "/auth/logout", nil, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, esperado 401", w.Code)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		found    bool
+		affected int64
+		status   int
+		audited  bool
+	}{
+		{"sessão do usuário", true, 1, http.StatusOK, true},
+		{"sessão de outro usuário ou encerrada", false, 0, http.StatusNotFound, false},
+		{"encerrada por outra requisição", true, 0, http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		if tt.found {
+			fake.On(`FROM "sessions"`).Rows(sessionColumns,
+				[]interface{}{9, 3, hashToken("outro"), "", time.Now().Add(time.Hour), nil})
+		}
+		fake.On(`UPDATE "sessions" SET "revoked_at"`).Affect(tt.affected)
+
+		w := serve(func(c *gin.Context) {
+			c.Params = gin.Params{{Key: "id", Value: "9"}}
+			h.RevokeSession(c)
+		}, http.MethodDelete, "/auth/sessions/9", nil, asUser(3, 7, "user"))
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, esperado %d", tt.name, w.Code, tt.status)
+		}
+
+		lookup := fake.Queries(`FROM "sessions"`)
+		if len(lookup) != 1 || !contains(lookup[0].Args, int64(3)) || !contains(lookup[0].Args, int64(9)) {
+			t.Errorf("%s: a busca deve se restringir às sessões do usuário: %v", tt.name, lookup)
+		}
+		if audited := fake.Executed(`INSERT INTO "audit_logs"`); audited != tt.audited {
+			t.Errorf("%s: auditado = %v, esperado %v", tt.name, audited, tt.audited)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopeStore restringe a consulta à loja ativa ou, sem loja ativa, às lojas
+// visíveis ao usuário. Administradores sem loja ativa veem o consolidado.
+func scopeStore(c *gin.Context, query *gorm.DB, column string) *gorm.DB {
+	if storeID, ok := middleware.StoreIDFromContext(c); ok {
+		return query.Where(column+" = ?", storeID)
+	}
+	if storeIDs := middleware.StoreIDsFromContext(c); storeIDs != nil {
+		return query.Where(column+" IN ?", storeIDs)
+	}
+	return query
+}
+
+// requireActiveStore retorna a loja ativa para operações que gravam dados de
+// uma loja específica, respondendo 400 quando nenhuma foi selecionada
+func requireActiveStore(c *gin.Context) (uint, bool) {
+	storeID, ok := middleware.StoreIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Selecione a loja ativa pelo cabeçalho " + middleware.StoreHeader})
+		return 0, false
+	}
+	return storeID, true
+}
+
+// GetStores retorna as lojas visíveis ao usuário
+func (h *Handler) GetStores(c *gin.Context) {
+	var stores []models.Store
+
+	query := h.DB.Order("name")
+	if storeIDs := middleware.StoreIDsFromContext(c); storeIDs != nil {
+		query = query.Where("id IN ?", storeIDs)
+	}
+
+	if err := query.Find(&stores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar lojas"})
+		return
+	}
+
+	activeStoreID, _ := middleware.StoreIDFromContext(c)
+	c.JSON(http.StatusOK, gin.H{
+		"stores":          stores,
+		"active_store_id": activeStoreID,
+	})
+}
+
+// CreateStore cria uma nova loja
+func (h *Handler) CreateStore(c *gin.Context) {
+	var input models.StoreCreate
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store := models.Store{
+		Name:    input.Name,
+		Code:    input.Code,
+		Address: input.Address,
+		Phone:   input.Phone,
+		Active:  true,
+	}
+
+	if err := h.DB.Create(&store).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Já existe uma loja com este código"})
+		return
+	}
+
+	h.recordAudit(c, auditEntityStore, store.ID, models.AuditActionCreate, nil, store)
+
+	c.JSON(http.StatusCreated, gin.H{"store": store})
+}
+
+// UpdateStore atualiza os dados de uma loja
+func (h *Handler) UpdateStore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var store models.Store
+	if err := h.DB.First(&store, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loja não encontrada"})
+		return
+	}
+
+	var input models.StoreUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.Address != nil {
+		updates["address"] = *input.Address
+	}
+	if input.Phone != nil {
+		updates["phone"] = *input.Phone
+	}
+	if input.Active != nil {
+		updates["active"] = *input.Active
+	}
+
+	before := store
+	if err := h.DB.Model(&store).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar loja"})
+		return
+	}
+
+	h.DB.First(&store, id)
+	h.recordAudit(c, auditEntityStore, store.ID, models.AuditActionUpdate, before, store)
+
+	c.JSON(http.StatusOK, gin.H{"store": store})
+}
+
+// SetUserStores define as lojas às quais um usuário tem acesso
+func (h *Handler) SetUserStores(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Preload("Stores").First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	var input models.UserStoresUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stores := []models.Store{}
+	if len(input.StoreIDs) > 0 {
+		if err := h.DB.Where("id IN ?", input.StoreIDs).Find(&stores).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar lojas"})
+			return
+		}
+	}
+	if len(stores) != len(uniqueIDs(input.StoreIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loja não encontrada"})
+		return
+	}
+
+	// Quem não é administrador só concede ou retira acesso às próprias lojas
+	if visible := middleware.StoreIDsFromContext(c); visible != nil {
+		for _, store := range append(stores, user.Stores...) {
+			if !containsStoreID(visible, store.ID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Acesso à loja negado", "store_id": store.ID})
+				return
+			}
+		}
+	}
+
+	before := storeIDList(user.Stores)
+	if err := h.DB.Model(&user).Association("Stores").Replace(stores); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar lojas do usuário"})
+		return
+	}
+	user.Stores = stores
+
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate,
+		gin.H{"store_ids": before}, gin.H{"store_ids": storeIDList(stores)})
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// SelectStore troca a loja ativa da sessão e emite um novo token de acesso.
+// store_id 0 remove a seleção e volta à visão consolidada.
+func (h *Handler) SelectStore(c *gin.Context) {
+	var input models.StoreSelect
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessionID, okSession := middleware.SessionIDFromContext(c)
+	userID, _ := middleware.UserIDFromContext(c)
+
+	var session models.Session
+	if !okSession || h.DB.Preload("User").Where("user_id = ?", userID).First(&session, sessionID).Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A loja ativa só pode ser trocada em sessões de login"})
+		return
+	}
+
+	var storeID *uint
+	if input.StoreID != 0 {
+		storeIDs, err := middleware.StoresFromDB(h.DB)(&session.User)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar lojas"})
+			return
+		}
+		if !containsStoreID(storeIDs, input.StoreID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Acesso à loja negado", "store_id": input.StoreID})
+			return
+		}
+		storeID = &input.StoreID
+	}
+
+	if err := h.DB.Model(&session).Update("store_id", storeID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao trocar loja"})
+		return
+	}
+	session.StoreID = storeID
+
+	token, err := h.newAccessToken(&session.User, &session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_in": int(h.Config.AccessTokenTTL.Seconds()),
+		"store_id":   storeID,
+	})
+}
+
+// defaultStoreID retorna a loja ativa inicial de uma nova sessão: a única
+// loja do usuário, quando ele tem exatamente uma
+func (h *Handler) defaultStoreID(user *models.User) *uint {
+	if user.IsAdmin() {
+		return nil
+	}
+	storeIDs, err := middleware.StoresFromDB(h.DB)(user)
+	if err != nil || len(storeIDs) != 1 {
+		return nil
+	}
+	return &storeIDs[0]
+}
+
+// storeIDList representa as lojas como texto ("1,3") para a auditoria, que
+// não compara listas
+func storeIDList(stores []models.Store) string {
+	ids := make([]string, 0, len(stores))
+	for _, store := range stores {
+		ids = append(ids, strconv.FormatUint(uint64(store.ID), 10))
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func containsStoreID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/models"
+	"loja-online/internal/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// groupedStyle é um estilo criado, ou que seria criado, pelo agrupamento
+type groupedStyle struct {
+	ID       uint             `json:"id,omitempty"`
+	SKU      string           `json:"sku"`
+	Name     string           `json:"name"`
+	Category string           `json:"category"`
+	Brand    string           `json:"brand"`
+	Variants []groupedVariant `json:"variants"`
+}
+
+// groupedVariant é um produto que passa a ser variação do estilo
+type groupedVariant struct {
+	ID            uint   `json:"id"`
+	SKU           string `json:"sku"`
+	Size          string `json:"size"`
+	Color         string `json:"color"`
+	PriceOverride bool   `json:"price_override"`
+}
+
+// simpleProducts restringe aos produtos simples: nem estilos, nem variações,
+// nem kits
+func simpleProducts(db *gorm.DB) *gorm.DB {
+	return db.Where("parent_id IS NULL AND has_variants = ? AND is_bundle = ?", false, false)
+}
+
+// GroupProductStyles agrupa em estilos os produtos cadastrados um por tamanho
+// ou cor antes da grade de variações: produtos simples com mesmo nome,
+// categoria e marca que diferem por tamanho ou cor passam a ser variações de
+// um novo estilo. Com dry_run=true, apenas lista os estilos que seriam
+// criados; sem ele, todos os grupos são gravados na mesma transação.
+func (h *Handler) GroupProductStyles(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run deve ser true ou false"})
+			return
+		}
+	}
+
+	var groups []struct {
+		Name     string
+		Category string
+		Brand    string
+	}
+	if err := h.DB.Model(&models.Product{}).Scopes(simpleProducts).
+		Select("name, category, brand").
+		Group("name, category, brand").
+		Having("COUNT(*) > 1").
+		Order("name, category, brand").
+		Scan(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar produtos"})
+		return
+	}
+
+	styles := []groupedStyle{}
+	skipped := []gin.H{}
+	type variantChange struct{ before, after models.Product }
+	var createdStyles []models.Product
+	var changes []variantChange
+	taken := make(map[string]bool)
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for _, group := range groups {
+			var products []models.Product
+			if err := tx.Scopes(simpleProducts).
+				Where("name = ? AND category = ? AND brand = ?", group.Name, group.Category, group.Brand).
+				Order("id").Find(&products).Error; err != nil {
+				return err
+			}
+			if !distinctVariants(products) {
+				skipped = append(skipped, gin.H{
+					"name":     group.Name,
+					"category": group.Category,
+					"brand":    group.Brand,
+					"reason":   "Tamanho e cor não distinguem os produtos",
+				})
+				continue
+			}
+
+			first := products[0]
+			style := models.Product{
+				Name:        first.Name,
+				Description: first.Description,
+				Category:    first.Category,
+				CategoryID:  first.CategoryID,
+				Brand:       first.Brand,
+				Price:       first.Price,
+				CostPrice:   first.CostPrice,
+				SKU:         styleSKU(tx, products, taken),
+				Material:    first.Material,
+				Gender:      first.Gender,
+				Season:      first.Season,
+				Active:      true,
+				ImageURL:    first.ImageURL,
+				HasVariants: true,
+			}
+			taken[style.SKU] = true
+
+			if !dryRun {
+				if err := tx.Create(&style).Error; err != nil {
+					return err
+				}
+				if err := pricing.RecordChange(tx, nil, &style, priceAuthor(c, models.PriceSourceInitial)); err != nil {
+					return err
+				}
+				createdStyles = append(createdStyles, style)
+			}
+
+			report := groupedStyle{ID: style.ID, SKU: style.SKU, Name: style.Name, Category: style.Category, Brand: style.Brand}
+			for _, product := range products {
+				after := product
+				after.ParentID = &style.ID
+				after.PriceOverride = product.Price != style.Price
+				if !dryRun {
+					if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(map[string]interface{}{
+						"parent_id":      style.ID,
+						"price_override": after.PriceOverride,
+					}).Error; err != nil {
+						return err
+					}
+					changes = append(changes, variantChange{product, after})
+				}
+				report.Variants = append(report.Variants, groupedVariant{
+					ID:            product.ID,
+					SKU:           product.SKU,
+					Size:          product.Size,
+					Color:         product.Color,
+					PriceOverride: after.PriceOverride,
+				})
+			}
+			styles = append(styles, report)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao agrupar estilos"})
+		return
+	}
+
+	for _, style := range createdStyles {
+		h.recordAudit(c, auditEntityProduct, style.ID, models.AuditActionCreate, nil, style)
+	}
+	for _, change := range changes {
+		h.recordAudit(c, auditEntityProduct, change.after.ID, models.AuditActionUpdate, change.before, change.after)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"styles":  styles,
+		"skipped": skipped,
+	})
+}
+
+// distinctVariants indica se os produtos diferem entre si por tamanho ou cor
+func distinctVariants(products []models.Product) bool {
+	seen := make(map[string]bool, len(products))
+	for _, product := range products {
+		key := strings.ToLower(product.Size) + "|" + strings.ToLower(product.Color)
+		if key == "|" || seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+	return true
+}
+
+// styleSKU usa o prefixo comum dos SKUs das variações, se ainda não estiver
+// cadastrado nem reservado em taken, ou o primeiro SKU com o sufixo -GRADE
+func styleSKU(tx *gorm.DB, products []models.Product, taken map[string]bool) string {
+	skus := make([]string, len(products))
+	for i, product := range products {
+		skus[i] = product.SKU
+	}
+
+	if prefix := commonSKUPrefix(skus); prefix != "" && !taken[prefix] {
+		var count int64
+		tx.Unscoped().Model(&models.Product{}).Where("sku = ?", prefix).Count(&count)
+		if count == 0 {
+			return prefix
+		}
+	}
+	return skus[0] + "-GRADE"
+}
+
+// commonSKUPrefix retorna o prefixo comum dos SKUs até o último hífen
+// (CAM-BAS-BCO-P e CAM-BAS-BCO-M → CAM-BAS-BCO), ou vazio se não houver
+func commonSKUPrefix(skus []string) string {
+	prefix := skus[0]
+	for _, sku := range skus[1:] {
+		for !strings.HasPrefix(sku, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if i := strings.LastIndex(prefix, "-"); i > 0 {
+		return prefix[:i]
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"loja-online/internal/models"
+)
+
+func TestDistinctVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		products []models.Product
+		want     bool
+	}{
+		{"tamanhos diferentes", []models.Product{{Size: "P"}, {Size: "M"}}, true},
+		{"cores diferentes", []models.Product{{Color: "Azul"}, {Color: "Preto"}}, true},
+		{"mesma grade sem distinguir maiúsculas", []models.Product{{Size: "P", Color: "azul"}, {Size: "p", Color: "Azul"}}, false},
+		{"sem tamanho nem cor", []models.Product{{Size: "P"}, {}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := distinctVariants(tt.products); got != tt.want {
+			t.Errorf("%s: distintos = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCommonSKUPrefix(t *testing.T) {
+	tests := []struct {
+		skus []string
+		want string
+	}{
+		{[]string{"CAM-BAS-BCO-P", "CAM-BAS-BCO-M", "CAM-BAS-BCO-G"}, "CAM-BAS-BCO"},
+		{[]string{"CAL-10", "CAL-12"}, "CAL"},
+		{[]string{"CAMP", "CAMM"}, ""},
+		{[]string{"A1", "B1"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := commonSKUPrefix(tt.skus); got != tt.want {
+			t.Errorf("prefixo de %v = %q, esperado %q", tt.skus, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+const (
+	// Validade do token intermediário entre a senha e o código 2FA
+	twoFactorChallengeTTL = 5 * time.Minute
+	// Quantidade de códigos de recuperação gerados por vez
+	recoveryCodeCount = 10
+)
+
+// twoFactorSetupRequired indica se o usuário precisa ativar o 2FA antes de
+// acessar os módulos do sistema
+func (h *Handler) twoFactorSetupRequired(user *models.User) bool {
+	return h.Config.TwoFactorMandatory && user.IsPrivileged() && !user.TwoFactorEnabled
+}
+
+// newTwoFactorChallenge gera o token que identifica um login aguardando o
+// segundo fator. Ele não dá acesso à API.
+func (h *Handler) newTwoFactorChallenge(user *models.User) (string, error) {
+	return h.Keys.Sign(jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": "2fa",
+		"exp":     time.Now().Add(twoFactorChallengeTTL).Unix(),
+	})
+}
+
+// parseTwoFactorChallenge valida o token intermediário e retorna o ID do usuário
+func (h *Handler) parseTwoFactorChallenge(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, h.Keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return 0, errors.New("token de desafio inválido")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "2fa" {
+		return 0, errors.New("token de desafio inválido")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return 0, errors.New("token de desafio inválido")
+	}
+	return uint(userID), nil
+}
+
+// TwoFactorChallengeKey limita as tentativas do segundo fator pelo usuário do
+// token de desafio, que um novo login com a senha renova sem afetar o limite
+func (h *Handler) TwoFactorChallengeKey(c *gin.Context) string {
+	userID, err := h.parseTwoFactorChallenge(middleware.JSONField(c, "challenge_token"))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// verifyTOTP valida o código do aplicativo autenticador e registra o contador
+// usado, de forma que o mesmo código não seja aceito duas vezes
+func (h *Handler) verifyTOTP(user *models.User, code string) bool {
+	step, ok := totp.Validate(user.TwoFactorSecret, code, time.Now(), user.TwoFactorLastStep)
+	if !ok {
+		return false
+	}
+
+	result := h.DB.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+		Update("two_factor_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	user.TwoFactorLastStep = step
+	return true
+}
+
+// useRecoveryCode consome um código de recuperação do usuário. A gravação é
+// condicional para que o mesmo código não seja aceito em dois logins simultâneos.
+func (h *Handler) useRecoveryCode(userID uint, code string) bool {
+	var codes []models.RecoveryCode
+	if err := h.DB.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error; err != nil {
+		return false
+	}
+
+	used, ok := matchRecoveryCode(codes, code)
+	if !ok {
+		return false
+	}
+	result := h.DB.Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", used.ID).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// matchRecoveryCode procura entre os códigos ainda não usados o informado
+func matchRecoveryCode(codes []models.RecoveryCode, code string) (*models.RecoveryCode, bool) {
+	for i := range codes {
+		if codes[i].UsedAt == nil && totp.VerifyRecoveryCode(codes[i].CodeHash, code) {
+			return &codes[i], true
+		}
+	}
+	return nil, false
+}
+
+// replaceRecoveryCodes gera novos códigos de recuperação, invalidando os anteriores
+func replaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	if err := db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: totp.HashRecoveryCode(code)}
+	}
+
+	if err := db.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// EnrollTwoFactor gera um novo segredo TOTP para o usuário logado. O 2FA só
+// é ativado após a confirmação de um código em VerifyTwoFactor.
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	userID, _ := middleware.UserIDFromContext(c)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Autenticação em dois fatores já está ativa"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar segredo"})
+		return
+	}
+
+	if err := h.DB.Model(&user).Updates(map[string]interface{}{
+		"two_factor_secret":    secret,
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar segredo"})
+		return
+	}
+
+	uri := totp.URI(h.Config.TwoFactorIssuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar QR code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// VerifyTwoFactor confirma o cadastro do aplicativo autenticador, ativa o 2FA
+// e retorna os códigos de recuperação (exibidos apenas uma vez)
+func (h *Handler) VerifyTwoFactor(c *gin.Context) {
+	var input models.TwoFactorCode
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Autenticação em dois fatores já está ativa"})
+		return
+	}
+	if user.TwoFactorSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Inicie o cadastro em /auth/2fa/enroll"})
+		return
+	}
+
+	if !h.verifyTOTP(&user, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código inválido"})
+		return
+	}
+
+	before := user
+	var codes []string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ativar autenticação em dois fatores"})
+		return
+	}
+
+	user.TwoFactorEnabled = true
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate, before, user)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Autenticação em dois fatores ativada com sucesso",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor desativa o 2FA do usuário logado mediante senha e código
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var input models.TwoFactorDisable
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Autenticação em dois fatores não está ativa"})
+		return
+	}
+	if h.Config.TwoFactorMandatory && user.IsPrivileged() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Autenticação em dois fatores é obrigatória para o seu perfil"})
+		return
+	}
+	if !user.CheckPassword(input.Password) || !h.verifyTOTP(&user, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Senha ou código inválido"})
+		return
+	}
+
+	before := user
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"two_factor_enabled":   false,
+			"two_factor_secret":    "",
+			"two_factor_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao desativar autenticação em dois fatores"})
+		return
+	}
+
+	user.TwoFactorEnabled = false
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate, before, user)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Autenticação em dois fatores desativada com sucesso"})
+}
+
+// RegenerateRecoveryCodes substitui os códigos de recuperação do usuário logado
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var input models.TwoFactorCode
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Autenticação em dois fatores não está ativa"})
+		return
+	}
+	if !h.verifyTOTP(&user, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código inválido"})
+		return
+	}
+
+	var previous []string
+	h.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Pluck("code_hash", &previous)
+
+	codes, err := replaceRecoveryCodes(h.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar códigos de recuperação"})
+		return
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+	h.recordAudit(c, auditEntityUser, user.ID, models.AuditActionUpdate,
+		gin.H{"recovery_codes": previous}, gin.H{"recovery_codes": hashes})
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// LoginTwoFactor conclui o login de usuários com 2FA ativo
+func (h *Handler) LoginTwoFactor(c *gin.Context) {
+	var input models.TwoFactorLogin
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Code == "" && input.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o código ou um código de recuperação"})
+		return
+	}
+
+	userID, err := h.parseTwoFactorChallenge(input.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expirado, entre novamente"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ? AND active = ?", userID, true).First(&user).Error; err != nil || !user.TwoFactorEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciais inválidas"})
+		return
+	}
+
+	if user.IsLocked(time.Now()) {
+		respondLocked(c, *user.LockedUntil)
+		return
+	}
+
+	verified := false
+	if input.Code != "" {
+		verified = h.verifyTOTP(&user, input.Code)
+	} else {
+		verified = h.useRecoveryCode(user.ID, input.RecoveryCode)
+	}
+	if !verified {
+		if lockedUntil := h.registerLoginFailure(&user); lockedUntil != nil {
+			respondLocked(c, *lockedUntil)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Código inválido"})
+		return
+	}
+
+	h.resetLoginFailures(&user)
+	h.startSession(c, &user)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"loja-online/internal/models"
+	"loja-online/internal/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTwoFactorChallengeKey(t *testing.T) {
+	h, _ := newTestHandler(t)
+	challenge, err := h.newTwoFactorChallenge(&models.User{ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Token de acesso válido, mas sem o propósito de desafio
+	access, err := h.Keys.Sign(jwt.MapClaims{"user_id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"desafio válido", challenge, "user:7"},
+		{"token de acesso", access, ""},
+		{"token inválido", "abc", ""},
+	}
+
+	for _, tt := range tests {
+		var key, body string
+		serve(func(c *gin.Context) {
+			key = h.TwoFactorChallengeKey(c)
+			data, _ := io.ReadAll(c.Request.Body)
+			body = string(data)
+		}, http.MethodPost, "/login/2fa", gin.H{"challenge_token": tt.token, "code": "123456"}, nil)
+
+		if key != tt.want {
+			t.Errorf("%s: chave = %q, esperado %q", tt.name, key, tt.want)
+		}
+		if !strings.Contains(body, `"code":"123456"`) {
+			t.Errorf("%s: o corpo deve continuar disponível ao handler: %s", tt.name, body)
+		}
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		affected int64
+		want     bool
+		consumed int64
+	}{
+		{"código válido", codes[1], 1, true, 12},
+		{"código em maiúsculas", strings.ToUpper(codes[0]), 1, true, 11},
+		{"código inexistente", "aaaaa-aaaaa", 1, false, 0},
+		{"usado em login simultâneo", codes[1], 0, false, 12},
+	}
+
+	for _, tt := range tests {
+		h, fake := newTestHandler(t)
+		fake.On(`FROM "recovery_codes"`).Rows([]string{"id", "user_id", "code_hash"},
+			[]interface{}{11, 3, totp.HashRecoveryCode(codes[0])},
+			[]interface{}{12, 3, totp.HashRecoveryCode(codes[1])})
+		fake.On(`UPDATE "recovery_codes"`).Affect(tt.affected)
+
+		if got := h.useRecoveryCode(3, tt.code); got != tt.want {
+			t.Errorf("%s: aceito = %v, esperado %v", tt.name, got, tt.want)
+		}
+
+		lookup := fake.Queries(`FROM "recovery_codes"`)
+		if len(lookup) != 1 || !strings.Contains(lookup[0].SQL, "used_at IS NULL") || !contains(lookup[0].Args, int64(3)) {
+			t.Errorf("%s: só os códigos não usados do usuário devem ser consultados: %v", tt.name, lookup)
+		}
+		updates := fake.Queries(`UPDATE "recovery_codes"`)
+		if tt.consumed == 0 {
+			if len(updates) != 0 {
+				t.Errorf("%s: nenhum código deve ser consumido: %v", tt.name, updates)
+			}
+			continue
+		}
+		if len(updates) != 1 || !strings.Contains(updates[0].SQL, "used_at IS NULL") || !contains(updates[0].Args, tt.consumed) {
+			t.Errorf("%s: o código %d deve ser consumido condicionalmente: %v", tt.name, tt.consumed, updates)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"loja-online/internal/barcode"
+	"loja-online/internal/middleware"
+	"loja-online/internal/models"
+	"loja-online/internal/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateVariants cria a grade de variações do estilo: uma variação para cada
+// combinação de tamanho e cor que ainda não exista. Um produto simples sem
+// estoque passa a ser o estilo da grade.
+func (h *Handler) CreateVariants(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var style models.Product
+	if err := h.DB.Preload("Variants").First(&style, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Produto não encontrado"})
+		return
+	}
+	if style.IsVariant() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variações não podem ter grade própria; use o estilo"})
+		return
+	}
+	if style.IsBundle {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kits não podem ter grade; cadastre um kit para cada combinação"})
+		return
+	}
+
+	var input models.VariantGridCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(input.Sizes) == 0 && len(input.Colors) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe sizes e/ou colors"})
+		return
+	}
+
+	// O estoque de um produto simples ficaria sem variação correspondente
+	if !style.HasVariants {
+		var stocked int64
+		h.DB.Model(&models.InventoryItem{}).Where("product_id = ? AND quantity <> 0", style.ID).Count(&stocked)
+		if stocked > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Produto com estoque não pode receber grade; zere o estoque ou cadastre um novo estilo"})
+			return
+		}
+		// Um estilo não é vendável e deixaria os kits sem o componente
+		if skus := h.bundlesUsing([]uint{style.ID}); len(skus) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "O produto é componente dos kits " + strings.Join(skus, ", ")})
+			return
+		}
+	}
+
+	overrides := make(map[string]models.VariantInput, len(input.Variants))
+	for _, cell := range input.Variants {
+		if cell.Barcode != "" {
+			code, err := barcode.Normalize(cell.Barcode)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "barcode": cell.Barcode})
+				return
+			}
+			cell.Barcode = code
+		}
+		overrides[variantKey(cell.Size, cell.Color)] = cell
+		if cell.Price != nil && *cell.Price != style.Price && !middleware.Can(c, models.CapProductsPrice) {
+			middleware.ForbiddenCapability(c, models.CapProductsPrice)
+			return
+		}
+	}
+
+	existing := make(map[string]bool, len(style.Variants))
+	for _, variant := range style.Variants {
+		existing[variantKey(variant.Size, variant.Color)] = true
+	}
+
+	var variants []models.Product
+	var skipped []gin.H
+	for _, size := range gridValues(input.Sizes) {
+		for _, color := range gridValues(input.Colors) {
+			key := variantKey(size, color)
+			if existing[key] {
+				skipped = append(skipped, gin.H{"size": size, "color": color})
+				continue
+			}
+			existing[key] = true
+			variants = append(variants, newVariant(&style, size, color, overrides[key]))
+		}
+	}
+
+	if conflicts := h.variantConflicts(variants); len(conflicts) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "SKU ou código de barras já cadastrado", "conflicts": conflicts})
+		return
+	}
+
+	before := style
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range variants {
+			if variants[i].Barcode == "" && input.GenerateBarcodes {
+				code, err := nextBarcode(tx)
+				if err != nil {
+					return err
+				}
+				variants[i].Barcode = code
+			}
+			if err := tx.Create(&variants[i]).Error; err != nil {
+				return err
+			}
+			if err := pricing.RecordChange(tx, nil, &variants[i], priceAuthor(c, models.PriceSourceInitial)); err != nil {
+				return err
+			}
+		}
+		if !style.HasVariants {
+			return tx.Model(&style).Update("has_variants", true).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar variações"})
+		return
+	}
+
+	for _, variant := range variants {
+		h.recordAudit(c, auditEntityProduct, variant.ID, models.AuditActionCreate, nil, variant)
+	}
+	if !before.HasVariants {
+		before.Variants = nil
+		after := before
+		after.HasVariants = true
+		h.recordAudit(c, auditEntityProduct, style.ID, models.AuditActionUpdate, before, after)
+	}
+
+	h.DB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&style, style.ID)
+	products := []models.Product{style}
+	h.attachStock(c, products)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"product": products[0],
+		"created": len(variants),
+		"skipped": skipped,
+	})
+}
+
+// variantConflicts retorna os SKUs e códigos de barras das novas variações
+// que já existem, inclusive em produtos removidos
+func (h *Handler) variantConflicts(variants []models.Product) []string {
+	var skus, barcodes []string
+	seen := make(map[string]bool)
+	var conflicts []string
+	for _, variant := range variants {
+		skus = append(skus, variant.SKU)
+		if seen["sku:"+variant.SKU] {
+			conflicts = append(conflicts, variant.SKU)
+		}
+		seen["sku:"+variant.SKU] = true
+		if variant.Barcode != "" {
+			barcodes = append(barcodes, variant.Barcode)
+			if seen["barcode:"+variant.Barcode] {
+				conflicts = append(conflicts, variant.Barcode)
+			}
+			seen["barcode:"+variant.Barcode] = true
+		}
+	}
+	if len(skus) == 0 {
+		return conflicts
+	}
+
+	var existing []models.Product
+	query := h.DB.Unscoped().Select("sku, barcode").Where("sku IN ?", skus)
+	if len(barcodes) > 0 {
+		query = query.Or("barcode IN ?", barcodes)
+	}
+	query.Find(&existing)
+	for _, product := range existing {
+		if seen["sku:"+product.SKU] {
+			conflicts = append(conflicts, product.SKU)
+		}
+		if product.Barcode != "" && seen["barcode:"+product.Barcode] {
+			conflicts = append(conflicts, product.Barcode)
+		}
+	}
+	return conflicts
+}
+
+// newVariant monta a variação com os dados comuns do estilo e os ajustes da célula
+func newVariant(style *models.Product, size, color string, cell models.VariantInput) models.Product {
+	variant := models.Product{
+		Name:        style.Name,
+		Description: style.Description,
+		Category:    style.Category,
+		CategoryID:  style.CategoryID,
+		Brand:       style.Brand,
+		Price:       style.Price,
+		CostPrice:   style.CostPrice,
+		SKU:         models.VariantSKU(style.SKU, color, size),
+		Barcode:     cell.Barcode,
+		Color:       color,
+		Size:        size,
+		Material:    style.Material,
+		Gender:      style.Gender,
+		Season:      style.Season,
+		Active:      style.Active,
+		ImageURL:    style.ImageURL,
+		ParentID:    &style.ID,
+	}
+	if cell.SKU != "" {
+		variant.SKU = cell.SKU
+	}
+	if cell.Price != nil && *cell.Price != style.Price {
+		variant.Price = *cell.Price
+		variant.PriceOverride = true
+	}
+	return variant
+}
+
+// syncVariants replica nas variações os dados comuns alterados no estilo. O
+// custo e o preço são replicados por pricing.SyncVariants, que registra o
+// histórico; o preço só nas variações sem preço próprio.
+func syncVariants(tx *gorm.DB, before, style *models.Product, author pricing.Author) error {
+	shared := map[string]interface{}{
+		"name":        style.Name,
+		"description": style.Description,
+		"category":    style.Category,
+		"category_id": style.CategoryID,
+		"brand":       style.Brand,
+		"material":    style.Material,
+		"gender":      style.Gender,
+		"season":      style.Season,
+	}
+	if before.Active != style.Active {
+		shared["active"] = style.Active
+	}
+	if err := tx.Model(&models.Product{}).Where("parent_id = ?", style.ID).Updates(shared).Error; err != nil {
+		return err
+	}
+	return pricing.SyncVariants(tx, before, style, author)
+}
+
+// priceAuthor identifica o usuário da requisição como autor de uma alteração de preço
+func priceAuthor(c *gin.Context, source string) pricing.Author {
+	author := pricing.Author{Source: source}
+	if userID, ok := middleware.UserIDFromContext(c); ok {
+		author.UserID = &userID
+	}
+	return author
+}
+
+// gridValues remove espaços e repetições; uma dimensão vazia vira um único valor vazio
+func gridValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		result = append(result, value)
+	}
+	if len(result) == 0 {
+		return []string{""}
+	}
+	return result
+}
+
+func variantKey(size, color string) string {
+	return strings.ToLower(strings.TrimSpace(size)) + "|" + strings.ToLower(strings.TrimSpace(color))
+}
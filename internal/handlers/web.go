@@ -6,6 +6,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ResetPasswordPath é a página aberta pelo link do email de redefinição de
+// senha
+const ResetPasswordPath = "/reset-password"
+
 // Dashboard renderiza a página principal do dashboard
 func (h *Handler) Dashboard(c *gin.Context) {
 	c.HTML(http.StatusOK, "dashboard.html", gin.H{
@@ -16,6 +20,15 @@ func (h *Handler) Dashboard(c *gin.Context) {
 // LoginPage renderiza a página de login
 func (h *Handler) LoginPage(c *gin.Context) {
 	c.HTML(http.StatusOK, "login.html", gin.H{
-		"title": "Login - Loja Online",
+		"title":       "Login - Loja Online",
+		"sso_enabled": h.OIDC != nil,
+	})
+}
+
+// ResetPasswordPage renderiza a página de redefinição de senha, aberta pelo
+// link enviado por email. O token fica na URL e é lido pela própria página.
+func (h *Handler) ResetPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "reset_password.html", gin.H{
+		"title": "Redefinir senha - Loja Online",
 	})
 }
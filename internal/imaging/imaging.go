@@ -0,0 +1,145 @@
+// Package imaging valida as imagens enviadas e gera as miniaturas usando
+// apenas a biblioteca padrão (JPEG, PNG e GIF).
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	// Registra o decodificador de GIF em image.Decode
+	_ "image/gif"
+)
+
+// Qualidade das imagens JPEG geradas
+const jpegQuality = 85
+
+// Maior largura ou altura aceita, para limitar a memória usada na decodificação
+const maxDimension = 8000
+
+// ErrUnsupported indica um arquivo que não é JPEG, PNG ou GIF
+var ErrUnsupported = errors.New("formato de imagem não suportado; envie JPEG, PNG ou GIF")
+
+// ErrTooLarge indica uma imagem com dimensões acima do limite
+var ErrTooLarge = errors.New("imagem com dimensões acima do limite")
+
+// ContentTypes são os tipos aceitos no envio
+var ContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// Decode identifica o tipo pelo conteúdo (e não pelo nome ou cabeçalho
+// informados pelo cliente) e decodifica a imagem
+func Decode(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	supported := false
+	for _, candidate := range ContentTypes {
+		if contentType == candidate {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, "", ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, "", ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	return img, contentType, nil
+}
+
+// Fit reduz a imagem para caber em um quadrado de size pixels, mantendo a
+// proporção. Imagens menores são retornadas sem ampliação.
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if size <= 0 || (width <= size && height <= size) {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	return resize(img, width, height)
+}
+
+// resize reduz a imagem pela média das áreas de origem de cada pixel
+func resize(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Encode grava a miniatura em PNG, quando a original é PNG ou GIF (para
+// manter a transparência), ou em JPEG. Retorna o tipo de conteúdo e a extensão.
+func Encode(img image.Image, sourceType string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	if sourceType == "image/png" || sourceType == "image/gif" {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	}
+
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/jpeg", ".jpg", nil
+}
+
+// Extension retorna a extensão do arquivo original pelo tipo de conteúdo
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func solid(width, height int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestFitKeepsAspectRatio(t *testing.T) {
+	tests := []struct {
+		width, height, size int
+		wantW, wantH        int
+	}{
+		{1200, 800, 400, 400, 266},
+		{800, 1200, 400, 266, 400},
+		{300, 200, 400, 300, 200}, // Não amplia
+		{2000, 1, 150, 150, 1},
+	}
+
+	for _, tt := range tests {
+		got := Fit(solid(tt.width, tt.height, color.White), tt.size).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("Fit(%dx%d, %d) = %dx%d, esperado %dx%d",
+				tt.width, tt.height, tt.size, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestFitAveragesPixels(t *testing.T) {
+	// Metade esquerda preta e metade direita branca viram dois pixels
+	img := solid(4, 2, color.Black)
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	thumb := Fit(img, 2).(*image.RGBA)
+	if r, _, _, _ := thumb.At(0, 0).RGBA(); r != 0 {
+		t.Errorf("pixel esquerdo = %d, esperado preto", r)
+	}
+	if r, _, _, _ := thumb.At(1, 0).RGBA(); r != 0xffff {
+		t.Errorf("pixel direito = %d, esperado branco", r)
+	}
+}
+
+func TestDecodeValidatesContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solid(10, 10, color.White)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, contentType, err := Decode(buf.Bytes()); err != nil || contentType != "image/png" {
+		t.Fatalf("Decode(png) = %q, %v", contentType, err)
+	}
+
+	for _, data := range [][]byte{[]byte("<html></html>"), []byte("\x89PNG\r\n\x1a\ncorrompido"), {}} {
+		if _, _, err := Decode(data); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Decode(%q) = %v, esperado ErrUnsupported", data, err)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Package jwtkeys gerencia as chaves assimétricas (RS256 ou EdDSA) que
+// assinam os tokens de acesso: identificação por kid, rotação periódica,
+// publicação no JWKS e aceite de tokens HS256 antigos durante a migração.
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"loja-online/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// Intervalo mínimo entre recargas causadas por kid desconhecido
+const minReloadInterval = 10 * time.Second
+
+// Options configura o Manager
+type Options struct {
+	Algorithm string
+	// Idade da chave ativa a partir da qual uma nova é gerada
+	RotationInterval time.Duration
+	// Tempo que uma chave aposentada continua publicada e aceita. Deve cobrir
+	// a validade do token de acesso.
+	RetiredKeyTTL time.Duration
+	// Segredo HS256 aceito apenas para verificação, quando AcceptHMAC é true
+	HMACSecret string
+	AcceptHMAC bool
+}
+
+// Manager assina e valida tokens com as chaves do Store
+type Manager struct {
+	store   Store
+	options Options
+	now     func() time.Time
+
+	mu       sync.RWMutex
+	keys     map[string]*key
+	active   *key
+	loadedAt time.Time
+}
+
+// New cria o Manager a partir da configuração da aplicação, com as chaves no banco
+func New(db *gorm.DB, cfg *config.Config) (*Manager, error) {
+	retiredKeyTTL := cfg.JWTRetiredKeyTTL
+	if retiredKeyTTL < cfg.AccessTokenTTL {
+		retiredKeyTTL = cfg.AccessTokenTTL
+	}
+
+	return NewManager(NewDBStore(db), Options{
+		Algorithm:        cfg.JWTAlgorithm,
+		RotationInterval: cfg.JWTKeyRotation,
+		RetiredKeyTTL:    retiredKeyTTL,
+		HMACSecret:       cfg.JWTSecret,
+		AcceptHMAC:       cfg.JWTAcceptHS256,
+	})
+}
+
+// NewManager carrega as chaves existentes e gera a primeira quando necessário
+func NewManager(store Store, options Options) (*Manager, error) {
+	if options.Algorithm != AlgorithmRS256 && options.Algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("algoritmo de assinatura não suportado: %s", options.Algorithm)
+	}
+
+	m := &Manager{store: store, options: options, now: time.Now}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	if err := m.RotateIfDue(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reload lê as chaves do Store; a mais recente não aposentada passa a assinar
+func (m *Manager) reload() error {
+	records, err := m.store.List()
+	if err != nil {
+		return fmt.Errorf("erro ao carregar chaves de assinatura: %w", err)
+	}
+
+	keys := make(map[string]*key, len(records))
+	var active *key
+	for _, record := range records {
+		k, err := decodeKey(record)
+		if err != nil {
+			log.Printf("Aviso: Chave de assinatura %s ignorada: %v", record.KID, err)
+			continue
+		}
+		keys[k.kid] = k
+		if k.retiredAt == nil && (active == nil || k.createdAt.After(active.createdAt)) {
+			active = k
+		}
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.active = active
+	m.loadedAt = m.now()
+	m.mu.Unlock()
+	return nil
+}
+
+// RotateIfDue gera uma nova chave quando não há chave ativa, quando ela passou
+// do intervalo de rotação ou quando o algoritmo configurado mudou
+func (m *Manager) RotateIfDue() error {
+	m.mu.RLock()
+	active := m.active
+	m.mu.RUnlock()
+
+	if active != nil && active.algorithm == m.options.Algorithm &&
+		m.now().Sub(active.createdAt) < m.options.RotationInterval {
+		return nil
+	}
+	return m.Rotate()
+}
+
+// Rotate gera uma nova chave ativa, aposenta as anteriores e remove as que já
+// não podem ter tokens válidos. Se outra instância rotacionou desde a última
+// carga, a chave dela é mantida e apenas recarregada.
+func (m *Manager) Rotate() error {
+	now := m.now()
+
+	m.mu.RLock()
+	previous := ""
+	if m.active != nil {
+		previous = m.active.kid
+	}
+	m.mu.RUnlock()
+
+	record, err := generateKey(m.options.Algorithm, now)
+	if err != nil {
+		return err
+	}
+	replaced, err := m.store.Replace(previous, record, now)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar chave de assinatura: %w", err)
+	}
+	if !replaced {
+		return m.reload()
+	}
+	if err := m.store.DeleteRetiredBefore(now.Add(-m.options.RetiredKeyTTL)); err != nil {
+		return fmt.Errorf("erro ao remover chaves de assinatura: %w", err)
+	}
+
+	log.Printf("Nova chave de assinatura %s (%s)", record.KID, record.Algorithm)
+	return m.reload()
+}
+
+// Run verifica periodicamente se a chave deve ser rotacionada e recarrega as
+// chaves geradas por outras instâncias. Bloqueia até stop ser fechado.
+func (m *Manager) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := m.reload(); err != nil {
+				log.Printf("Erro ao recarregar chaves de assinatura: %v", err)
+				continue
+			}
+			if err := m.RotateIfDue(); err != nil {
+				log.Printf("Erro ao rotacionar chave de assinatura: %v", err)
+			}
+		}
+	}
+}
+
+// Sign assina as claims com a chave ativa, identificada no cabeçalho kid
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	active := m.active
+	m.mu.RUnlock()
+
+	if active == nil {
+		return "", errors.New("nenhuma chave de assinatura ativa")
+	}
+
+	token := jwt.NewWithClaims(active.method(), claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// Keyfunc retorna a chave pública do kid do token. Tokens HS256 sem kid só
+// são aceitos enquanto AcceptHMAC estiver habilitado.
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && m.options.AcceptHMAC {
+			return []byte(m.options.HMACSecret), nil
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	}
+
+	k := m.lookup(kid)
+	if k == nil {
+		return nil, fmt.Errorf("chave %q desconhecida", kid)
+	}
+	if token.Method.Alg() != k.algorithm {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	if k.retiredAt != nil && m.now().Sub(*k.retiredAt) > m.options.RetiredKeyTTL {
+		return nil, fmt.Errorf("chave %q expirada", kid)
+	}
+	return k.private.Public(), nil
+}
+
+// lookup procura a chave e, se não encontrar, recarrega o Store para
+// reconhecer chaves geradas por outra instância
+func (m *Manager) lookup(kid string) *key {
+	m.mu.RLock()
+	k := m.keys[kid]
+	stale := m.now().Sub(m.loadedAt) >= minReloadInterval
+	m.mu.RUnlock()
+
+	if k != nil || !stale {
+		return k
+	}
+	if err := m.reload(); err != nil {
+		log.Printf("Erro ao recarregar chaves de assinatura: %v", err)
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[kid]
+}
+
+// JWKS retorna as chaves públicas em uso, no formato de /.well-known/jwks.json
+func (m *Manager) JWKS() map[string][]JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	published := make([]*key, 0, len(m.keys))
+	for _, k := range m.keys {
+		if k.retiredAt != nil && now.Sub(*k.retiredAt) > m.options.RetiredKeyTTL {
+			continue
+		}
+		published = append(published, k)
+	}
+	sort.Slice(published, func(i, j int) bool {
+		return published[i].createdAt.After(published[j].createdAt)
+	})
+
+	keys := make([]JWK, 0, len(published))
+	for _, k := range published {
+		keys = append(keys, k.jwk())
+	}
+	return map[string][]JWK{"keys": keys}
+}
@@ -0,0 +1,247 @@
+package jwtkeys
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"loja-online/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memoryStore simula a tabela signing_keys; o mutex faz o papel do advisory lock
+type memoryStore struct {
+	mu   sync.Mutex
+	keys []models.SigningKey
+}
+
+func (s *memoryStore) List() ([]models.SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.SigningKey(nil), s.keys...), nil
+}
+
+func (s *memoryStore) Replace(previous string, key *models.SigningKey, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.activeKID() != previous {
+		return false, nil
+	}
+	for i := range s.keys {
+		if s.keys[i].RetiredAt == nil {
+			retiredAt := at
+			s.keys[i].RetiredAt = &retiredAt
+		}
+	}
+	s.keys = append(s.keys, *key)
+	return true, nil
+}
+
+// activeKID retorna a chave não aposentada mais recente
+func (s *memoryStore) activeKID() string {
+	kid := ""
+	var createdAt time.Time
+	for _, key := range s.keys {
+		if key.RetiredAt == nil && (kid == "" || key.CreatedAt.After(createdAt)) {
+			kid, createdAt = key.KID, key.CreatedAt
+		}
+	}
+	return kid
+}
+
+func (s *memoryStore) DeleteRetiredBefore(at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.keys[:0]
+	for _, key := range s.keys {
+		if key.RetiredAt == nil || !key.RetiredAt.Before(at) {
+			kept = append(kept, key)
+		}
+	}
+	s.keys = kept
+	return nil
+}
+
+func newTestManager(t *testing.T, algorithm string) (*Manager, *memoryStore) {
+	t.Helper()
+
+	store := &memoryStore{}
+	m, err := NewManager(store, Options{
+		Algorithm:        algorithm,
+		RotationInterval: 24 * time.Hour,
+		RetiredKeyTTL:    time.Hour,
+		HMACSecret:       "segredo",
+		AcceptHMAC:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, store
+}
+
+func parse(m *Manager, token string) error {
+	_, err := jwt.Parse(token, m.Keyfunc)
+	return err
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		m, _ := newTestManager(t, algorithm)
+
+		token, err := m.Sign(claims())
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if err := parse(m, token); err != nil {
+			t.Errorf("%s: token assinado recusado: %v", algorithm, err)
+		}
+
+		jwks := m.JWKS()["keys"]
+		if len(jwks) != 1 || jwks[0].Alg != algorithm || jwks[0].Kid == "" {
+			t.Errorf("%s: JWKS inesperado: %+v", algorithm, jwks)
+		}
+	}
+}
+
+func TestRotationKeepsPreviousKeyDuringGracePeriod(t *testing.T) {
+	m, _ := newTestManager(t, AlgorithmRS256)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	oldToken, _ := m.Sign(claims())
+
+	// Passado o intervalo de rotação, uma nova chave passa a assinar
+	now = now.Add(25 * time.Hour)
+	if err := m.RotateIfDue(); err != nil {
+		t.Fatal(err)
+	}
+	newToken, _ := m.Sign(claims())
+
+	oldKid := mustKid(t, oldToken)
+	if mustKid(t, newToken) == oldKid {
+		t.Fatal("a rotação deveria gerar um novo kid")
+	}
+	if len(m.JWKS()["keys"]) != 2 {
+		t.Errorf("JWKS deveria publicar a chave nova e a aposentada")
+	}
+	if _, err := m.Keyfunc(&jwt.Token{Method: jwt.SigningMethodRS256, Header: map[string]interface{}{"kid": oldKid}}); err != nil {
+		t.Errorf("chave aposentada deveria ser aceita no período de carência: %v", err)
+	}
+
+	// Após a carência, a chave antiga deixa de ser aceita e publicada
+	now = now.Add(2 * time.Hour)
+	if _, err := m.Keyfunc(&jwt.Token{Method: jwt.SigningMethodRS256, Header: map[string]interface{}{"kid": oldKid}}); err == nil {
+		t.Error("chave aposentada não deveria ser aceita após a carência")
+	}
+	if len(m.JWKS()["keys"]) != 1 {
+		t.Errorf("JWKS não deveria publicar a chave expirada")
+	}
+}
+
+func TestHMACFallback(t *testing.T) {
+	m, _ := newTestManager(t, AlgorithmRS256)
+
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("segredo"))
+	if err := parse(m, legacy); err != nil {
+		t.Errorf("token HS256 deveria ser aceito durante a migração: %v", err)
+	}
+
+	m.options.AcceptHMAC = false
+	if err := parse(m, legacy); err == nil {
+		t.Error("token HS256 não deveria ser aceito com o fallback desabilitado")
+	}
+}
+
+func TestRejectsAlgorithmMismatch(t *testing.T) {
+	m, _ := newTestManager(t, AlgorithmRS256)
+	token, _ := m.Sign(claims())
+
+	// Um token HS256 com o kid de uma chave RSA não pode usar a chave pública como segredo
+	forged := &jwt.Token{Method: jwt.SigningMethodHS256, Header: map[string]interface{}{"kid": mustKid(t, token)}}
+	if _, err := m.Keyfunc(forged); err == nil {
+		t.Error("algoritmo diferente do da chave deveria ser recusado")
+	}
+}
+
+func TestSharedStoreAcrossInstances(t *testing.T) {
+	first, store := newTestManager(t, AlgorithmEdDSA)
+	second, err := NewManager(store, first.options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Uma instância rotaciona; a outra reconhece o novo kid ao recarregar
+	if err := first.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := first.Sign(claims())
+	second.loadedAt = time.Time{}
+	if err := parse(second, token); err != nil {
+		t.Errorf("token da outra instância recusado: %v", err)
+	}
+}
+
+func TestConcurrentRotationKeepsOneActiveKey(t *testing.T) {
+	first, store := newTestManager(t, AlgorithmEdDSA)
+	second, err := NewManager(store, first.options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// As duas instâncias veem a mesma chave vencida e rotacionam ao mesmo tempo
+	later := time.Now().Add(25 * time.Hour)
+	first.now = func() time.Time { return later }
+	second.now = func() time.Time { return later }
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, m := range []*Manager{first, second} {
+		wg.Add(1)
+		go func(i int, m *Manager) {
+			defer wg.Done()
+			errs[i] = m.RotateIfDue()
+		}(i, m)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, _ := store.List()
+	active := 0
+	for _, key := range keys {
+		if key.RetiredAt == nil {
+			active++
+		}
+	}
+	if len(keys) != 2 || active != 1 {
+		t.Fatalf("esperado 2 chaves com 1 ativa, obtido %d com %d ativas", len(keys), active)
+	}
+
+	// Ambas assinam com a chave vencedora
+	a, _ := first.Sign(claims())
+	b, _ := second.Sign(claims())
+	if mustKid(t, a) != store.activeKID() || mustKid(t, b) != store.activeKID() {
+		t.Error("as instâncias deveriam assinar com a mesma chave ativa")
+	}
+}
+
+func mustKid(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
@@ -0,0 +1,143 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"loja-online/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algoritmos assimétricos suportados
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Tamanho das chaves RSA geradas
+const rsaKeyBits = 2048
+
+// key é uma chave de assinatura já decodificada
+type key struct {
+	kid       string
+	algorithm string
+	private   crypto.Signer
+	createdAt time.Time
+	retiredAt *time.Time
+}
+
+func (k *key) method() jwt.SigningMethod {
+	if k.algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// generateKey cria um novo par de chaves com kid aleatório
+func generateKey(algorithm string, now time.Time) (*models.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("algoritmo de assinatura não suportado: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		KID:        now.UTC().Format("20060102") + "-" + hex.EncodeToString(id),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		CreatedAt:  now,
+	}, nil
+}
+
+// decodeKey converte o registro do banco na chave utilizável
+func decodeKey(record models.SigningKey) (*key, error) {
+	block, _ := pem.Decode([]byte(record.PrivateKey))
+	if block == nil {
+		return nil, errors.New("PEM inválido")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tipo de chave não suportado")
+	}
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		if record.Algorithm != AlgorithmRS256 {
+			return nil, errors.New("algoritmo incompatível com a chave")
+		}
+	case ed25519.PrivateKey:
+		if record.Algorithm != AlgorithmEdDSA {
+			return nil, errors.New("algoritmo incompatível com a chave")
+		}
+	default:
+		return nil, errors.New("tipo de chave não suportado")
+	}
+
+	return &key{
+		kid:       record.KID,
+		algorithm: record.Algorithm,
+		private:   private,
+		createdAt: record.CreatedAt,
+		retiredAt: record.RetiredAt,
+	}, nil
+}
+
+// JWK é a representação pública de uma chave no JWKS
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func (k *key) jwk() JWK {
+	result := JWK{Kid: k.kid, Use: "sig", Alg: k.algorithm}
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		result.Kty = "RSA"
+		result.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		result.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		result.Kty = "OKP"
+		result.Crv = "Ed25519"
+		result.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return result
+}
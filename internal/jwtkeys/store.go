@@ -0,0 +1,82 @@
+package jwtkeys
+
+import (
+	"errors"
+	"time"
+
+	"loja-online/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Store persiste as chaves de assinatura, compartilhadas entre instâncias
+type Store interface {
+	// List retorna as chaves ainda publicadas, da mais recente para a mais antiga
+	List() ([]models.SigningKey, error)
+	// Replace grava a nova chave e aposenta as demais somente se a chave ativa
+	// ainda for previous (vazio quando não há chave ativa). Retorna false, sem
+	// alterar nada, quando outra instância já rotacionou.
+	Replace(previous string, key *models.SigningKey, at time.Time) (bool, error)
+	// DeleteRetiredBefore remove as chaves aposentadas antes do instante informado
+	DeleteRetiredBefore(at time.Time) error
+}
+
+// Chave do advisory lock que serializa a rotação entre instâncias
+const rotationLockKey = 7_461_201
+
+// errKeyChanged interrompe a transação quando a chave ativa mudou
+var errKeyChanged = errors.New("chave ativa alterada por outra instância")
+
+// DBStore guarda as chaves na tabela signing_keys
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore cria o Store baseado no banco de dados
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) List() ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := s.db.Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Replace roda sob pg_advisory_xact_lock: a verificação da chave ativa, a
+// gravação da nova e a aposentadoria das demais são atômicas entre instâncias
+func (s *DBStore) Replace(previous string, key *models.SigningKey, at time.Time) (bool, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rotationLockKey).Error; err != nil {
+			return err
+		}
+
+		var active []string
+		if err := tx.Model(&models.SigningKey{}).Where("retired_at IS NULL").
+			Order("created_at DESC, id DESC").Limit(1).Pluck("kid", &active).Error; err != nil {
+			return err
+		}
+		current := ""
+		if len(active) > 0 {
+			current = active[0]
+		}
+		if current != previous {
+			return errKeyChanged
+		}
+
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SigningKey{}).
+			Where("kid <> ? AND retired_at IS NULL", key.KID).
+			Update("retired_at", at).Error
+	})
+	if errors.Is(err, errKeyChanged) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *DBStore) DeleteRetiredBefore(at time.Time) error {
+	return s.db.Where("retired_at IS NOT NULL AND retired_at < ?", at).Delete(&models.SigningKey{}).Error
+}
@@ -0,0 +1,116 @@
+// Package labels gera etiquetas de produto com nome, tamanho, cor, preço e
+// código de barras EAN-13, em folhas PDF para impressoras comuns ou em ZPL
+// para impressoras térmicas.
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"loja-online/internal/barcode"
+	"loja-online/internal/money"
+)
+
+// Formatos suportados
+const (
+	FormatPDF = "pdf"
+	FormatZPL = "zpl"
+)
+
+// ErrUnsupported indica um formato de etiqueta desconhecido
+var ErrUnsupported = errors.New("formato de etiqueta não suportado; use pdf ou zpl")
+
+// Label é uma etiqueta, impressa Quantity vezes
+type Label struct {
+	Name     string
+	Size     string
+	Color    string
+	Price    money.Amount
+	Barcode  string // EAN-13 válido
+	Quantity int
+}
+
+// ContentType retorna o tipo de conteúdo do formato
+func ContentType(format string) string {
+	if format == FormatZPL {
+		return "application/zpl; charset=utf-8"
+	}
+	return "application/pdf"
+}
+
+// Write grava as etiquetas no formato pedido; o PDF usa a folha informada
+func Write(w io.Writer, format string, labels []Label, sheet Sheet) error {
+	for _, label := range labels {
+		if !barcode.Valid(label.Barcode) {
+			return fmt.Errorf("código de barras inválido na etiqueta %q", label.Name)
+		}
+	}
+
+	switch format {
+	case FormatPDF:
+		return writePDF(w, labels, sheet)
+	case FormatZPL:
+		return writeZPL(w, labels)
+	}
+	return ErrUnsupported
+}
+
+// Dimensões da etiqueta ZPL: 50 x 30 mm a 203 dpi (8 pontos por mm)
+const (
+	zplWidth  = 400
+	zplHeight = 240
+)
+
+// writeZPL gera um formato por etiqueta, com a quantidade em ^PQ
+func writeZPL(w io.Writer, labels []Label) error {
+	var b strings.Builder
+	for _, label := range labels {
+		if label.Quantity <= 0 {
+			continue
+		}
+		b.WriteString("^XA\n^CI28\n")
+		fmt.Fprintf(&b, "^PW%d\n^LL%d\n", zplWidth, zplHeight)
+		fmt.Fprintf(&b, "^FO16,14^A0N,26,26^FB368,1,0,L^FH^FD%s^FS\n", zplField(label.Name))
+		if details := labelDetails(label); details != "" {
+			fmt.Fprintf(&b, "^FO16,46^A0N,22,22^FB368,1,0,L^FH^FD%s^FS\n", zplField(details))
+		}
+		fmt.Fprintf(&b, "^FO16,74^A0N,34,34^FH^FD%s^FS\n", zplField(label.Price.Format()))
+		// A impressora calcula o dígito verificador a partir dos 12 primeiros
+		fmt.Fprintf(&b, "^FO100,120^BY2^BEN,80,Y,N^FD%s^FS\n", label.Barcode[:12])
+		fmt.Fprintf(&b, "^PQ%d\n^XZ\n", label.Quantity)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zplField escapa os caracteres de controle do ZPL com ^FH
+func zplField(value string) string {
+	return strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E", "\n", " ").Replace(value)
+}
+
+// labelDetails monta a linha de tamanho e cor
+func labelDetails(label Label) string {
+	var parts []string
+	if label.Size != "" {
+		parts = append(parts, "Tam. "+label.Size)
+	}
+	if label.Color != "" {
+		parts = append(parts, label.Color)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// truncate limita o texto a max caracteres, terminando com reticências
+func truncate(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	runes := []rune(value)
+	if max <= 3 {
+		return string(runes[:max])
+	}
+	return string(runes[:max-3]) + "..."
+}
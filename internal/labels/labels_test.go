@@ -0,0 +1,89 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"loja-online/internal/money"
+)
+
+func TestWriteZPL(t *testing.T) {
+	labels := []Label{
+		{Name: "Camiseta Básica ^Promo_", Size: "M", Color: "Azul", Price: money.FromCents(5990), Barcode: "2000000000428", Quantity: 3},
+		{Name: "Sem impressão", Barcode: "2000000000428", Quantity: 0},
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, FormatZPL, labels, SheetA4); err != nil {
+		t.Fatal(err)
+	}
+	zpl := out.String()
+
+	if strings.Count(zpl, "^XA") != 1 {
+		t.Fatalf("esperado um formato de etiqueta:\n%s", zpl)
+	}
+	for _, want := range []string{
+		"^FDCamiseta Básica _5EPromo_5F^FS",
+		"^FDTam. M - Azul^FS",
+		"^FDR$ 59,90^FS",
+		"^BEN,80,Y,N^FD200000000042^FS",
+		"^PQ3",
+	} {
+		if !strings.Contains(zpl, want) {
+			t.Errorf("ZPL sem %q:\n%s", want, zpl)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	labels := []Label{
+		{Name: "Calça Jeans (Slim)", Size: "42", Color: "Índigo", Price: money.FromCents(19900), Barcode: "7891000315507", Quantity: 20},
+		{Name: "Meia", Price: money.FromCents(1500), Barcode: "2000000000428", Quantity: 5},
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, FormatPDF, labels, SheetA4); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.String()
+
+	if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatal("cabeçalho ou final do PDF inválido")
+	}
+	// 25 etiquetas em folhas de 24
+	if !strings.Contains(pdf, "/Count 2") {
+		t.Fatal("esperadas 2 páginas")
+	}
+	if !strings.Contains(pdf, `(Cal\347a Jeans \(Slim\))`) {
+		t.Fatal("texto da etiqueta não codificado em WinAnsi")
+	}
+
+	// Cada entrada da tabela xref aponta para o início do objeto
+	match := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(pdf)
+	if match == nil {
+		t.Fatal("startxref ausente")
+	}
+	xref, _ := strconv.Atoi(match[1])
+	lines := strings.Split(pdf[xref:], "\n")
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for i := 1; i < count; i++ {
+		offset, _ := strconv.Atoi(strings.Fields(lines[2+i])[0])
+		if !strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj", i)) {
+			t.Fatalf("xref do objeto %d aponta para %q", i, pdf[offset:offset+10])
+		}
+	}
+}
+
+func TestWriteRejectsInvalidBarcode(t *testing.T) {
+	labels := []Label{{Name: "Boné", Barcode: "123", Quantity: 1}}
+	if err := Write(&bytes.Buffer{}, FormatPDF, labels, SheetA4); err == nil {
+		t.Fatal("código inválido deveria falhar")
+	}
+	if err := Write(&bytes.Buffer{}, "png", nil, SheetA4); err != ErrUnsupported {
+		t.Fatalf("erro = %v, esperado ErrUnsupported", err)
+	}
+}
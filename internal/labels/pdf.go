@@ -0,0 +1,179 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"loja-online/internal/barcode"
+)
+
+// Sheet descreve a folha de etiquetas do PDF, com medidas em milímetros
+type Sheet struct {
+	PageWidth   float64
+	PageHeight  float64
+	Columns     int
+	Rows        int
+	LabelWidth  float64
+	LabelHeight float64
+	MarginLeft  float64
+	MarginTop   float64
+	GapX        float64
+	GapY        float64
+}
+
+// SheetA4 é a folha A4 com 3 colunas e 8 linhas de etiquetas de 70 x 37 mm
+var SheetA4 = Sheet{
+	PageWidth: 210, PageHeight: 297,
+	Columns: 3, Rows: 8,
+	LabelWidth: 70, LabelHeight: 37,
+	MarginTop: 0.5,
+}
+
+// Medidas do conteúdo da etiqueta, em milímetros
+const (
+	labelPadding  = 3
+	moduleWidth   = 0.26 // Largura de uma barra estreita
+	barcodeHeight = 11
+	quietModules  = 11 // Margem de silêncio à esquerda do código
+)
+
+const ptPerMM = 72 / 25.4
+
+// writePDF distribui as etiquetas pela folha, preenchendo as linhas da
+// esquerda para a direita e abrindo novas páginas quando necessário
+func writePDF(w io.Writer, labels []Label, sheet Sheet) error {
+	perPage := sheet.Columns * sheet.Rows
+	if perPage <= 0 {
+		return fmt.Errorf("folha de etiquetas sem colunas ou linhas")
+	}
+
+	var pages []*bytes.Buffer
+	position := 0
+	for _, label := range labels {
+		modules, err := barcode.Modules(label.Barcode)
+		if err != nil {
+			return err
+		}
+		for n := 0; n < label.Quantity; n++ {
+			if position%perPage == 0 {
+				pages = append(pages, &bytes.Buffer{})
+			}
+			slot := position % perPage
+			x := sheet.MarginLeft + float64(slot%sheet.Columns)*(sheet.LabelWidth+sheet.GapX)
+			y := sheet.MarginTop + float64(slot/sheet.Columns)*(sheet.LabelHeight+sheet.GapY)
+			drawLabel(pages[len(pages)-1], sheet, x, y, label, modules)
+			position++
+		}
+	}
+	if len(pages) == 0 {
+		pages = append(pages, &bytes.Buffer{})
+	}
+
+	return writeDocument(w, sheet, pages)
+}
+
+// drawLabel desenha a etiqueta com o canto superior esquerdo em (x, y)
+func drawLabel(page *bytes.Buffer, sheet Sheet, x, y float64, label Label, modules []bool) {
+	left := x + labelPadding
+	width := sheet.LabelWidth - 2*labelPadding
+	// Largura média de um caractere da Helvetica: metade do corpo
+	chars := func(size float64) int { return int(width * ptPerMM / (size * 0.5)) }
+
+	top := y + labelPadding
+	text(page, sheet, "F2", 9, left, top+3.2, truncate(label.Name, chars(9)))
+	if details := labelDetails(label); details != "" {
+		text(page, sheet, "F1", 8, left, top+7, truncate(details, chars(8)))
+	}
+	text(page, sheet, "F2", 13, left, top+12.5, label.Price.Format())
+
+	// Código de barras centralizado, com os dígitos embaixo
+	codeWidth := float64(len(modules)+quietModules) * moduleWidth
+	barsLeft := x + (sheet.LabelWidth-codeWidth)/2 + quietModules*moduleWidth
+	barsTop := top + 15
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(modules) && modules[i] {
+			i++
+		}
+		rect(page, sheet, barsLeft+float64(start)*moduleWidth, barsTop, float64(i-start)*moduleWidth, barcodeHeight)
+	}
+	text(page, sheet, "F1", 8, barsLeft-quietModules*moduleWidth*0.8, barsTop+barcodeHeight+3,
+		label.Barcode[:1]+"  "+label.Barcode[1:7]+"  "+label.Barcode[7:])
+}
+
+// text escreve o texto com a linha de base em (x, y), medidos do topo da página
+func text(page *bytes.Buffer, sheet Sheet, font string, size, x, y float64, value string) {
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font, size, x*ptPerMM, (sheet.PageHeight-y)*ptPerMM, pdfString(value))
+}
+
+// rect preenche o retângulo com o canto superior esquerdo em (x, y)
+func rect(page *bytes.Buffer, sheet Sheet, x, y, width, height float64) {
+	fmt.Fprintf(page, "%.3f %.3f %.3f %.3f re f\n",
+		x*ptPerMM, (sheet.PageHeight-y-height)*ptPerMM, width*ptPerMM, height*ptPerMM)
+}
+
+// pdfString converte o texto para WinAnsiEncoding, que coincide com o
+// Latin-1 nos caracteres acentuados, e escapa os delimitadores
+func pdfString(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writeDocument monta o PDF: catálogo, árvore de páginas, fontes e, para cada
+// página, o objeto da página e o seu conteúdo
+func writeDocument(w io.Writer, sheet Sheet, pages []*bytes.Buffer) error {
+	var doc bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, doc.Len())
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	doc.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			sheet.PageWidth*ptPerMM, sheet.PageHeight*ptPerMM, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(doc.Bytes())
+	return err
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// LogMailer não envia emails: grava a mensagem completa em um arquivo ou, se
+// nenhum caminho for configurado, no log da aplicação. Indicado para
+// desenvolvimento e testes.
+type LogMailer struct {
+	Path string
+	From string
+
+	mu sync.Mutex
+}
+
+// Send registra a mensagem no destino configurado
+func (m *LogMailer) Send(msg Message) error {
+	raw := buildMessage(m.From, msg)
+
+	if m.Path == "" {
+		log.Printf("📧 Email para %s:\n%s", msg.To, raw)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo de emails: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s\r\n.\r\n", raw); err != nil {
+		return fmt.Errorf("erro ao gravar email: %w", err)
+	}
+	return nil
+}
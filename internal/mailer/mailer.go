@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"loja-online/internal/config"
+)
+
+// Message representa um email em texto simples
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envia emails da aplicação
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New cria o Mailer configurado em MAIL_DRIVER ("smtp" ou "log")
+func New(cfg *config.Config) Mailer {
+	switch cfg.MailDriver {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	default:
+		return &LogMailer{
+			Path: cfg.MailLogFile,
+			From: cfg.MailFrom,
+		}
+	}
+}
@@ -1,16 +1,109 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
+
+	"loja-online/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-// AuthRequired verifica se o usuário está autenticado
-func AuthRequired(jwtSecret string) gin.HandlerFunc {
+// SessionChecker valida se a sessão do token continua ativa para o usuário
+type SessionChecker func(sessionID, userID uint) error
+
+// CheckSessionInDB retorna um SessionChecker que consulta o banco de dados.
+// A sessão não pode estar revogada ou expirada e o usuário precisa estar ativo.
+func CheckSessionInDB(db *gorm.DB) SessionChecker {
+	return func(sessionID, userID uint) error {
+		var session models.Session
+		if err := db.Preload("User").Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+			return err
+		}
+		if !session.IsActive(time.Now()) {
+			return errors.New("sessão revogada ou expirada")
+		}
+		if session.User.ID == 0 || !session.User.Active {
+			return errors.New("usuário inativo")
+		}
+		return nil
+	}
+}
+
+// APIKeyAuthenticator valida uma chave de API e retorna o seu registro
+type APIKeyAuthenticator func(key, clientIP string) (*models.APIKey, error)
+
+// AuthenticateAPIKeyInDB retorna um APIKeyAuthenticator que consulta o banco
+// de dados e registra o último uso da chave
+func AuthenticateAPIKeyInDB(db *gorm.DB) APIKeyAuthenticator {
+	return func(key, clientIP string) (*models.APIKey, error) {
+		// Mesmo hash SHA-256 usado na criação da chave
+		sum := sha256.Sum256([]byte(key))
+
+		var apiKey models.APIKey
+		if err := db.Preload("ServiceAccount").Where("key_hash = ?", hex.EncodeToString(sum[:])).First(&apiKey).Error; err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if !apiKey.IsActive(now) {
+			return nil, errors.New("chave revogada ou expirada")
+		}
+		if apiKey.ServiceAccount.ID == 0 || !apiKey.ServiceAccount.Active {
+			return nil, errors.New("conta de serviço inativa")
+		}
+
+		// Evita uma escrita a cada requisição. A atualização não parte de
+		// apiKey para não regravar a conta de serviço carregada com ela.
+		if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute {
+			db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).UpdateColumns(map[string]interface{}{
+				"last_used_at": now,
+				"last_used_ip": clientIP,
+			})
+		}
+		return &apiKey, nil
+	}
+}
+
+// apiKeyFromRequest extrai a chave de API do cabeçalho X-API-Key ou de
+// "Authorization: ApiKey <chave>"
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "ApiKey ") {
+		return strings.TrimSpace(authHeader[len("ApiKey "):])
+	}
+	return ""
+}
+
+// AuthRequired verifica se o usuário está autenticado e se a sessão do token
+// não foi revogada. Integrações podem se autenticar com uma chave de API.
+func AuthRequired(keyFunc jwt.Keyfunc, checkSession SessionChecker, authenticateKey APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			apiKey, err := authenticateKey(key, c.ClientIP())
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Chave de API inválida"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", apiKey.ServiceAccountID)
+			c.Set("email", apiKey.ServiceAccount.Email)
+			c.Set("role", apiKey.ServiceAccount.Role)
+			c.Set("api_key_id", apiKey.ID)
+			c.Set("api_key_permissions", apiKey.Permissions)
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de autorização requerido"})
@@ -28,14 +121,8 @@ func AuthRequired(jwtSecret string) gin.HandlerFunc {
 		// Extrai o token
 		tokenString := authHeader[7:]
 
-		// Valida o token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Verifica se o método de assinatura é HMAC
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(jwtSecret), nil
-		})
+		// Valida o token; keyFunc escolhe a chave pelo kid e confere o algoritmo
+		token, err := jwt.Parse(tokenString, keyFunc)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
@@ -44,13 +131,58 @@ func AuthRequired(jwtSecret string) gin.HandlerFunc {
 		}
 
 		// Extrai as claims do token
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Adiciona as informações do usuário ao contexto
-			c.Set("user_id", claims["user_id"])
-			c.Set("email", claims["email"])
-			c.Set("role", claims["role"])
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		// Adiciona as informações do usuário ao contexto
+		c.Set("user_id", claims["user_id"])
+		c.Set("email", claims["email"])
+		c.Set("role", claims["role"])
+		c.Set("session_id", claims["sid"])
+		c.Set("token_store_id", claims["store_id"])
+
+		// Tokens sem sessão (emitidos antes da revogação existir) não são aceitos
+		userID, okUser := UserIDFromContext(c)
+		sessionID, okSession := SessionIDFromContext(c)
+		if !okUser || !okSession || checkSession(sessionID, userID) != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sessão inválida ou encerrada"})
+			c.Abort()
+			return
 		}
 
 		c.Next()
 	}
 }
+
+// UserIDFromContext extrai o ID do usuário autenticado do contexto
+func UserIDFromContext(c *gin.Context) (uint, bool) {
+	return uintFromContext(c, "user_id")
+}
+
+// SessionIDFromContext extrai o ID da sessão do token do contexto
+func SessionIDFromContext(c *gin.Context) (uint, bool) {
+	return uintFromContext(c, "session_id")
+}
+
+// uintFromContext converte um ID numérico do contexto, vindo das claims do JWT
+// como float64 ou definido diretamente pela aplicação
+func uintFromContext(c *gin.Context, key string) (uint, bool) {
+	value, exists := c.Get(key)
+	if !exists {
+		return 0, false
+	}
+
+	switch id := value.(type) {
+	case float64:
+		return uint(id), id > 0
+	case uint:
+		return id, id > 0
+	case int:
+		return uint(id), id > 0
+	}
+	return 0, false
+}
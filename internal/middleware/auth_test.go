@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"loja-online/internal/dbtest"
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAPIKey = "lok_0123456789abcdef"
+
+func staticAuthenticator(apiKey *models.APIKey) APIKeyAuthenticator {
+	return func(key, clientIP string) (*models.APIKey, error) {
+		if key != testAPIKey {
+			return nil, errors.New("chave não encontrada")
+		}
+		return apiKey, nil
+	}
+}
+
+func performAPIKeyRequest(apiKey *models.APIKey, header, value, capability string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/test",
+		AuthRequired(nil, nil, staticAuthenticator(apiKey)),
+		RequireCapability(staticLoader(&apiKey.ServiceAccount), capability),
+		func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(header, value)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	account := models.User{ID: 9, Role: models.RoleService, Active: true, Permissions: models.UserPermissions{
+		Products:     true,
+		Sales:        true,
+		Capabilities: models.Capabilities{models.CapProductsRead, models.CapProductsWrite, models.CapSalesRead},
+	}}
+	apiKey := &models.APIKey{ID: 4, ServiceAccountID: 9, ServiceAccount: account, Permissions: models.UserPermissions{
+		Products:     true,
+		Capabilities: models.Capabilities{models.CapProductsRead},
+	}}
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		capability string
+		want       int
+	}{
+		{"cabeçalho X-API-Key", "X-API-Key", testAPIKey, models.CapProductsRead, http.StatusOK},
+		{"Authorization ApiKey", "Authorization", "ApiKey " + testAPIKey, models.CapProductsRead, http.StatusOK},
+		{"chave inválida", "X-API-Key", "lok_outra", models.CapProductsRead, http.StatusUnauthorized},
+		{"capacidade da conta fora da chave", "X-API-Key", testAPIKey, models.CapProductsWrite, http.StatusForbidden},
+		{"módulo da conta fora da chave", "X-API-Key", testAPIKey, models.CapSalesRead, http.StatusForbidden},
+		{"capacidade que a conta não tem", "X-API-Key", testAPIKey, models.CapProductsDelete, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		if w := performAPIKeyRequest(apiKey, tt.header, tt.value, tt.capability); w.Code != tt.want {
+			t.Errorf("%s: status %d, esperado %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthenticateAPIKeyInDB(t *testing.T) {
+	sum := sha256.Sum256([]byte(testAPIKey))
+	keyHash := hex.EncodeToString(sum[:])
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	recent := time.Now().Add(-10 * time.Second)
+
+	tests := []struct {
+		name          string
+		revokedAt     *time.Time
+		expiresAt     *time.Time
+		lastUsedAt    *time.Time
+		accountActive bool
+		valid         bool
+		touched       bool
+	}{
+		{"chave ativa", nil, &future, nil, true, true, true},
+		{"sem expiração", nil, nil, &past, true, true, true},
+		{"uso recente não grava de novo", nil, nil, &recent, true, true, false},
+		{"chave revogada", &past, nil, nil, true, false, false},
+		{"chave expirada", nil, &past, nil, true, false, false},
+		{"conta de serviço inativa", nil, nil, nil, false, false, false},
+	}
+
+	for _, tt := range tests {
+		db, fake := dbtest.Open(t)
+		fake.On(`FROM "api_keys"`).Rows(
+			[]string{"id", "key_hash", "service_account_id", "revoked_at", "expires_at", "last_used_at"},
+			[]interface{}{4, keyHash, 9, tt.revokedAt, tt.expiresAt, tt.lastUsedAt})
+		fake.On(`FROM "users"`).Rows([]string{"id", "role", "active"},
+			[]interface{}{9, models.RoleService, tt.accountActive})
+
+		apiKey, err := AuthenticateAPIKeyInDB(db)(testAPIKey, "10.0.0.1")
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: erro = %v, válida esperado %v", tt.name, err, tt.valid)
+		}
+		if tt.valid && (apiKey == nil || apiKey.ServiceAccount.ID != 9) {
+			t.Errorf("%s: chave sem a conta de serviço: %+v", tt.name, apiKey)
+		}
+
+		lookup := fake.Queries(`FROM "api_keys"`)
+		if len(lookup) != 1 || len(lookup[0].Args) == 0 || lookup[0].Args[0] != keyHash {
+			t.Errorf("%s: a chave deve ser buscada pelo hash SHA-256: %v", tt.name, lookup)
+		}
+		if touched := fake.Executed(`UPDATE "api_keys"`); touched != tt.touched {
+			t.Errorf("%s: último uso gravado = %v, esperado %v", tt.name, touched, tt.touched)
+		}
+		if fake.Executed(`INSERT INTO "users"`) {
+			t.Errorf("%s: a conta de serviço não deve ser regravada", tt.name)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserLoader carrega o estado atual de um usuário a partir do seu ID
+type UserLoader func(userID uint) (*models.User, error)
+
+// LoadUserFromDB retorna um UserLoader que consulta o banco de dados
+func LoadUserFromDB(db *gorm.DB) UserLoader {
+	return func(userID uint) (*models.User, error) {
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+}
+
+// currentUser retorna o usuário autenticado, carregando-o uma única vez por requisição
+func currentUser(c *gin.Context, loadUser UserLoader) (*models.User, error) {
+	if value, exists := c.Get("current_user"); exists {
+		if user, ok := value.(*models.User); ok {
+			return user, nil
+		}
+	}
+
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		return nil, errors.New("usuário não autenticado")
+	}
+
+	user, err := loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	c.Set("current_user", user)
+	return user, nil
+}
+
+// RequirePermission garante que o usuário autenticado tenha acesso ao módulo.
+// As permissões são lidas a cada requisição para que alterações tenham efeito
+// imediato, sem depender do conteúdo do token. Administradores sempre passam,
+// exceto quando autenticados por chave de API, que também precisa do módulo.
+func RequirePermission(loadUser UserLoader, module string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := UserIDFromContext(c); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		user, err := currentUser(c, loadUser)
+		if err != nil || !user.Active {
+			forbidden(c, module)
+			return
+		}
+
+		if !user.IsAdmin() && !user.Permissions.Allows(module) {
+			forbidden(c, module)
+			return
+		}
+
+		// Chaves de API ficam restritas aos módulos liberados na própria chave
+		if value, exists := c.Get("api_key_permissions"); exists {
+			if keyPermissions, ok := value.(models.UserPermissions); !ok || !keyPermissions.Allows(module) {
+				forbidden(c, module)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireCapability garante que o usuário autenticado tenha a capacidade
+// informada (por exemplo "products:write"), com as mesmas regras de
+// RequirePermission para administradores e chaves de API
+func RequireCapability(loadUser UserLoader, capability string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := UserIDFromContext(c); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		user, err := currentUser(c, loadUser)
+		if err != nil || !user.Active || !can(c, user, capability) {
+			ForbiddenCapability(c, capability)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Can verifica, dentro de um handler, se o usuário autenticado possui a
+// capacidade. Deve ser usado em rotas protegidas por RequireCapability ou
+// RequirePermission, que carregam o usuário da requisição.
+func Can(c *gin.Context, capability string) bool {
+	value, exists := c.Get("current_user")
+	if !exists {
+		return false
+	}
+	user, ok := value.(*models.User)
+	return ok && user.Active && can(c, user, capability)
+}
+
+// IsAdmin verifica, dentro de um handler, se o usuário autenticado é
+// administrador. Como Can, depende do usuário carregado pelo middleware.
+func IsAdmin(c *gin.Context) bool {
+	value, exists := c.Get("current_user")
+	if !exists {
+		return false
+	}
+	user, ok := value.(*models.User)
+	return ok && user.Active && user.IsAdmin()
+}
+
+// can aplica as regras de capacidade ao usuário e à chave de API, se houver
+func can(c *gin.Context, user *models.User, capability string) bool {
+	if !user.IsAdmin() && !user.Permissions.Can(capability) {
+		return false
+	}
+
+	if value, exists := c.Get("api_key_permissions"); exists {
+		keyPermissions, ok := value.(models.UserPermissions)
+		return ok && keyPermissions.Can(capability)
+	}
+	return true
+}
+
+// forbidden responde com 403 no formato padrão da API
+func forbidden(c *gin.Context, module string) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  "Permissão negada",
+		"module": module,
+	})
+	c.Abort()
+}
+
+// ForbiddenCapability responde com 403 indicando a capacidade ausente
+func ForbiddenCapability(c *gin.Context, capability string) {
+	module, _, _ := strings.Cut(capability, ":")
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":      "Permissão negada",
+		"module":     module,
+		"capability": capability,
+	})
+	c.Abort()
+}
+
+// RequireAdmin restringe a rota a usuários com papel admin. Chaves de API
+// nunca passam, pois pertencem a contas de serviço.
+func RequireAdmin(loadUser UserLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := currentUser(c, loadUser)
+		if err != nil || !user.Active || !user.IsAdmin() {
+			forbidden(c, "admin")
+			return
+		}
+		c.Next()
+	}
+}
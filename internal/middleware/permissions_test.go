@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var allModules = []string{
+	models.ModuleProducts,
+	models.ModuleCustomers,
+	models.ModuleInventory,
+	models.ModuleSales,
+	models.ModuleReports,
+	models.ModuleUsers,
+}
+
+func permissionsFor(module string) models.UserPermissions {
+	var p models.UserPermissions
+	switch module {
+	case models.ModuleProducts:
+		p.Products = true
+	case models.ModuleCustomers:
+		p.Customers = true
+	case models.ModuleInventory:
+		p.Inventory = true
+	case models.ModuleSales:
+		p.Sales = true
+	case models.ModuleReports:
+		p.Reports = true
+	case models.ModuleUsers:
+		p.Users = true
+	}
+	return p
+}
+
+func staticLoader(user *models.User) UserLoader {
+	return func(userID uint) (*models.User, error) {
+		if user == nil || user.ID != userID {
+			return nil, errors.New("usuário não encontrado")
+		}
+		return user, nil
+	}
+}
+
+func performRequest(loader UserLoader, module string, userID interface{}) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		if userID != nil {
+			c.Set("user_id", userID)
+		}
+		c.Next()
+	}, RequirePermission(loader, module), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequirePermissionPerModule(t *testing.T) {
+	for _, module := range allModules {
+		for _, other := range allModules {
+			user := &models.User{ID: 7, Role: "user", Active: true, Permissions: permissionsFor(other)}
+			w := performRequest(staticLoader(user), module, float64(7))
+
+			want := http.StatusForbidden
+			if module == other {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Errorf("módulo %s com permissão %s: status %d, esperado %d", module, other, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestRequirePermissionAdminAlwaysAllowed(t *testing.T) {
+	admin := &models.User{ID: 1, Role: "admin", Active: true}
+	for _, module := range allModules {
+		if w := performRequest(staticLoader(admin), module, float64(1)); w.Code != http.StatusOK {
+			t.Errorf("admin no módulo %s: status %d, esperado 200", module, w.Code)
+		}
+	}
+}
+
+func TestRequirePermissionInactiveUser(t *testing.T) {
+	user := &models.User{ID: 3, Role: "user", Active: false, Permissions: permissionsFor(models.ModuleSales)}
+	if w := performRequest(staticLoader(user), models.ModuleSales, float64(3)); w.Code != http.StatusForbidden {
+		t.Errorf("usuário inativo: status %d, esperado 403", w.Code)
+	}
+}
+
+func TestRequirePermissionUnknownUser(t *testing.T) {
+	if w := performRequest(staticLoader(nil), models.ModuleProducts, float64(99)); w.Code != http.StatusForbidden {
+		t.Errorf("usuário inexistente: status %d, esperado 403", w.Code)
+	}
+}
+
+func TestRequirePermissionMissingUserID(t *testing.T) {
+	if w := performRequest(staticLoader(nil), models.ModuleProducts, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("sem user_id: status %d, esperado 401", w.Code)
+	}
+}
+
+func performCapabilityRequest(user *models.User, capability string, keyPermissions *models.UserPermissions) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		c.Set("user_id", float64(user.ID))
+		if keyPermissions != nil {
+			c.Set("api_key_permissions", *keyPermissions)
+		}
+		c.Next()
+	}, RequireCapability(staticLoader(user), capability), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireCapability(t *testing.T) {
+	cashier := &models.User{ID: 5, Role: "user", Active: true, Permissions: models.UserPermissions{
+		Products:     true,
+		Sales:        true,
+		Capabilities: models.Capabilities{models.CapProductsRead, models.CapSalesRead, models.CapSalesWrite},
+	}}
+	legacy := &models.User{ID: 6, Role: "user", Active: true, Permissions: models.UserPermissions{Products: true}}
+	admin := &models.User{ID: 1, Role: "admin", Active: true}
+	keyPermissions := &models.UserPermissions{Capabilities: models.Capabilities{models.CapProductsRead}}
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		capability string
+		key        *models.UserPermissions
+		want       int
+	}{
+		{"leitura liberada", cashier, models.CapProductsRead, nil, http.StatusOK},
+		{"alteração de preço negada", cashier, models.CapProductsPrice, nil, http.StatusForbidden},
+		{"exclusão negada", cashier, models.CapProductsDelete, nil, http.StatusForbidden},
+		{"cancelamento negado", cashier, models.CapSalesCancel, nil, http.StatusForbidden},
+		{"flag legado libera o módulo", legacy, models.CapProductsDelete, nil, http.StatusOK},
+		{"flag legado não libera outro módulo", legacy, models.CapSalesRead, nil, http.StatusForbidden},
+		{"admin sempre passa", admin, models.CapUsersDelete, nil, http.StatusOK},
+		{"chave limita o admin", admin, models.CapProductsWrite, keyPermissions, http.StatusForbidden},
+		{"chave com a capacidade", admin, models.CapProductsRead, keyPermissions, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		if w := performCapabilityRequest(tt.user, tt.capability, tt.key); w.Code != tt.want {
+			t.Errorf("%s: status %d, esperado %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
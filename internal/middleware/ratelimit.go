@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"loja-online/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey extrai a chave de limitação da requisição. Uma chave vazia
+// dispensa a verificação.
+type RateLimitKey func(c *gin.Context) string
+
+// ByIP limita por endereço IP do cliente
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByJSONField limita pelo valor de um campo do corpo JSON, como o email do
+// login
+func ByJSONField(field string) RateLimitKey {
+	return func(c *gin.Context) string {
+		value := strings.ToLower(JSONField(c, field))
+		if value == "" {
+			return ""
+		}
+		return field + ":" + value
+	}
+}
+
+// JSONField lê um campo texto do corpo JSON sem consumi-lo: o corpo é
+// restaurado para que o handler possa lê-lo normalmente
+func JSONField(c *gin.Context, field string) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var payload map[string]interface{}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	value, _ := payload[field].(string)
+	return strings.TrimSpace(value)
+}
+
+// RateLimit recusa com 429 as requisições que excederem o limite na janela.
+// O prefixo separa os contadores de regras diferentes que usam a mesma chave.
+func RateLimit(store ratelimit.Store, prefix string, limit int, window time.Duration, keyFunc RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := store.Hit(prefix+":"+key, limit, window)
+		if err != nil {
+			// Falha no armazenamento não deve derrubar a autenticação
+			log.Printf("Erro no rate limit: %v", err)
+			c.Next()
+			return
+		}
+
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Muitas tentativas. Tente novamente mais tarde",
+				"retry_after": seconds,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Regras com prefixos diferentes não compartilham o contador do mesmo email
+func TestRateLimitPrefixes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := ratelimit.NewMemoryStore()
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/login", RateLimit(store, "login", 2, time.Minute, ByJSONField("email")), ok)
+	router.POST("/password/forgot", RateLimit(store, "password_forgot", 2, time.Minute, ByJSONField("email")), ok)
+
+	post := func(path, email string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email":"`+email+`"}`)))
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if status := post("/password/forgot", "Ana@Loja.com"); status != http.StatusOK {
+			t.Fatalf("recuperação %d: status = %d", i+1, status)
+		}
+	}
+	if status := post("/password/forgot", "ana@loja.com"); status != http.StatusTooManyRequests {
+		t.Errorf("recuperação acima do limite: status = %d, esperado 429", status)
+	}
+	if status := post("/login", "ana@loja.com"); status != http.StatusOK {
+		t.Errorf("login após recuperações: status = %d, esperado 200", status)
+	}
+}
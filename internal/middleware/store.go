@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Cabeçalho que seleciona a loja ativa, com prioridade sobre o token
+const StoreHeader = "X-Store-ID"
+
+// StoreResolver retorna as lojas ativas que o usuário pode acessar
+type StoreResolver func(user *models.User) ([]uint, error)
+
+// StoresFromDB retorna um StoreResolver que consulta o banco de dados.
+// Administradores acessam todas as lojas ativas.
+func StoresFromDB(db *gorm.DB) StoreResolver {
+	return func(user *models.User) ([]uint, error) {
+		ids := []uint{}
+		query := db.Model(&models.Store{}).Where("active = ?", true)
+		if !user.IsAdmin() {
+			query = query.Where("id IN (?)", db.Table("user_stores").Select("store_id").Where("user_id = ?", user.ID))
+		}
+		err := query.Order("id").Pluck("id", &ids).Error
+		return ids, err
+	}
+}
+
+// StoreScope define a loja ativa da requisição, vinda do cabeçalho X-Store-ID
+// ou da claim store_id do token. Sem seleção, usuários com uma única loja a
+// usam como ativa; os demais veem os dados consolidados das suas lojas.
+func StoreScope(loadUser UserLoader, resolveStores StoreResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := currentUser(c, loadUser)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		storeIDs, err := resolveStores(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar lojas"})
+			c.Abort()
+			return
+		}
+
+		requested, _ := uintFromContext(c, "token_store_id")
+		if header := c.GetHeader(StoreHeader); header != "" {
+			id, err := strconv.ParseUint(header, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cabeçalho " + StoreHeader + " inválido"})
+				c.Abort()
+				return
+			}
+			requested = uint(id)
+		}
+
+		if requested == 0 && len(storeIDs) == 1 && !user.IsAdmin() {
+			requested = storeIDs[0]
+		}
+
+		if requested != 0 {
+			if !containsID(storeIDs, requested) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Acesso à loja negado", "store_id": requested})
+				c.Abort()
+				return
+			}
+			c.Set("store_id", requested)
+		}
+
+		// Sem loja ativa, administradores veem todas as lojas, inclusive inativas
+		if !user.IsAdmin() {
+			c.Set("store_ids", storeIDs)
+		}
+
+		c.Next()
+	}
+}
+
+// StoreIDFromContext retorna a loja ativa da requisição, se houver
+func StoreIDFromContext(c *gin.Context) (uint, bool) {
+	return uintFromContext(c, "store_id")
+}
+
+// StoreIDsFromContext retorna as lojas visíveis quando não há loja ativa.
+// O retorno nil indica acesso a todas as lojas.
+func StoreIDsFromContext(c *gin.Context) []uint {
+	if value, exists := c.Get("store_ids"); exists {
+		if ids, ok := value.([]uint); ok {
+			return ids
+		}
+	}
+	return nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"loja-online/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func staticStores(admin, assigned []uint) StoreResolver {
+	return func(user *models.User) ([]uint, error) {
+		if user.IsAdmin() {
+			return admin, nil
+		}
+		return assigned, nil
+	}
+}
+
+func performStoreRequest(user *models.User, resolver StoreResolver, tokenStore interface{}, header string) (*httptest.ResponseRecorder, *gin.Context) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	var captured *gin.Context
+	router.GET("/test", func(c *gin.Context) {
+		c.Set("user_id", float64(user.ID))
+		c.Set("token_store_id", tokenStore)
+		c.Next()
+	}, StoreScope(staticLoader(user), resolver), func(c *gin.Context) {
+		captured = c.Copy()
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if header != "" {
+		req.Header.Set(StoreHeader, header)
+	}
+	router.ServeHTTP(w, req)
+	return w, captured
+}
+
+func TestStoreScope(t *testing.T) {
+	seller := &models.User{ID: 2, Role: "user", Active: true}
+	admin := &models.User{ID: 1, Role: "admin", Active: true}
+	resolver := staticStores([]uint{1, 2, 3}, []uint{1, 2})
+	single := staticStores([]uint{1, 2, 3}, []uint{2})
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		resolver   StoreResolver
+		tokenStore interface{}
+		header     string
+		wantStatus int
+		wantStore  uint
+		wantIDs    []uint
+	}{
+		{"loja do token", seller, resolver, float64(2), "", http.StatusOK, 2, []uint{1, 2}},
+		{"cabeçalho tem prioridade", seller, resolver, float64(2), "1", http.StatusOK, 1, []uint{1, 2}},
+		{"loja sem acesso", seller, resolver, nil, "3", http.StatusForbidden, 0, nil},
+		{"cabeçalho inválido", seller, resolver, nil, "abc", http.StatusBadRequest, 0, nil},
+		{"várias lojas sem seleção", seller, resolver, nil, "", http.StatusOK, 0, []uint{1, 2}},
+		{"loja única é a ativa", seller, single, nil, "", http.StatusOK, 2, []uint{2}},
+		{"admin consolidado", admin, resolver, nil, "", http.StatusOK, 0, nil},
+		{"admin escolhe qualquer loja", admin, resolver, nil, "3", http.StatusOK, 3, nil},
+	}
+
+	for _, tt := range tests {
+		w, c := performStoreRequest(tt.user, tt.resolver, tt.tokenStore, tt.header)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status %d, esperado %d", tt.name, w.Code, tt.wantStatus)
+			continue
+		}
+		if c == nil {
+			continue
+		}
+
+		storeID, _ := StoreIDFromContext(c)
+		if storeID != tt.wantStore {
+			t.Errorf("%s: loja ativa %d, esperada %d", tt.name, storeID, tt.wantStore)
+		}
+		if ids := StoreIDsFromContext(c); len(ids) != len(tt.wantIDs) || (ids == nil) != (tt.wantIDs == nil) {
+			t.Errorf("%s: lojas visíveis %v, esperadas %v", tt.name, ids, tt.wantIDs)
+		}
+	}
+}
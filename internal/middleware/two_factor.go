@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireTwoFactorSetup bloqueia administradores e gerentes que ainda não
+// ativaram a autenticação em dois fatores quando ela é obrigatória. As rotas
+// de /auth continuam acessíveis para que o cadastro possa ser feito.
+func RequireTwoFactorSetup(loadUser UserLoader, mandatory bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !mandatory {
+			c.Next()
+			return
+		}
+
+		user, err := currentUser(c, loadUser)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		if user.IsPrivileged() && !user.TwoFactorEnabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                     "Ative a autenticação em dois fatores para continuar",
+				"two_factor_setup_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
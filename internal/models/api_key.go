@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Papel dos usuários que representam integrações (sem login por senha)
+const RoleService = "service"
+
+// APIKey é uma chave de acesso para integrações, vinculada a uma conta de
+// serviço. A chave em si nunca é armazenada, apenas o seu hash.
+type APIKey struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	Name             string     `json:"name" gorm:"not null"`
+	Prefix           string     `json:"prefix" gorm:"not null"` // Início da chave, para identificação
+	KeyHash          string     `json:"-" gorm:"uniqueIndex;not null"`
+	ServiceAccountID uint       `json:"service_account_id" gorm:"not null;index"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	LastUsedIP       string     `json:"last_used_ip"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedByID      uint       `json:"created_by_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Módulos liberados para a chave, limitados às permissões da conta de serviço
+	Permissions UserPermissions `json:"permissions" gorm:"embedded"`
+
+	// Relacionamentos
+	ServiceAccount User `json:"service_account,omitempty" gorm:"foreignKey:ServiceAccountID"`
+}
+
+type APIKeyCreate struct {
+	Name             string          `json:"name" binding:"required"`
+	ServiceAccountID uint            `json:"service_account_id" binding:"required"`
+	Permissions      UserPermissions `json:"permissions"`
+	ExpiresAt        *time.Time      `json:"expires_at"`
+}
+
+// IsActive indica se a chave pode ser utilizada
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// JSON armazena um documento JSON em uma coluna jsonb
+type JSON []byte
+
+// Value implementa driver.Valuer
+func (j JSON) Value() (driver.Value, error) {
+	return JSONB[JSON]{&j}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (j *JSON) Scan(value interface{}) error {
+	return JSONB[JSON]{j}.Scan(value)
+}
+
+// MarshalJSON devolve o documento sem escapá-lo como string
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON guarda o documento recebido
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
+// Ações registradas na auditoria
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog registra uma alteração feita por um usuário ou chave de API
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    *uint     `json:"user_id" gorm:"index"`
+	APIKeyID  *uint     `json:"api_key_id"`
+	IPAddress string    `json:"ip_address"`
+	Entity    string    `json:"entity" gorm:"not null;index:idx_audit_entity"`
+	EntityID  uint      `json:"entity_id" gorm:"index:idx_audit_entity"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	Changes   JSON      `json:"changes" gorm:"type:jsonb"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	// Relacionamentos
+	User *User `json:"user,omitempty"`
+}
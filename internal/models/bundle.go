@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"loja-online/internal/money"
+)
+
+// BundleComponent é um item de um kit, como a bermuda do "kit praia": cada
+// kit vendido consome Quantity unidades de ComponentID. O kit não tem
+// estoque próprio; o disponível vem dos componentes.
+type BundleComponent struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	BundleID    uint      `json:"bundle_id" gorm:"not null;uniqueIndex:idx_bundle_components_bundle_component"`
+	ComponentID uint      `json:"component_id" gorm:"not null;uniqueIndex:idx_bundle_components_bundle_component;index"`
+	Quantity    int       `json:"quantity" gorm:"not null;default:1"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Relacionamentos
+	Component *Product `json:"component,omitempty" gorm:"foreignKey:ComponentID"`
+}
+
+type BundleComponentInput struct {
+	ComponentID uint `json:"component_id" binding:"required"`
+	Quantity    int  `json:"quantity" binding:"required,gt=0"`
+}
+
+// BundleComponentsUpdate substitui a composição de um kit
+type BundleComponentsUpdate struct {
+	Components []BundleComponentInput `json:"components" binding:"required,min=1,dive"`
+}
+
+// SaleItemComponent é a parte de um item de kit vendido que cabe a cada
+// componente: a quantidade que saiu do estoque e a receita do item rateada
+// pelo preço de tabela dos componentes no momento da venda
+type SaleItemComponent struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	SaleItemID uint         `json:"sale_item_id" gorm:"not null;index"`
+	ProductID  uint         `json:"product_id" gorm:"not null;index"`
+	Quantity   int          `json:"quantity" gorm:"not null"`
+	Revenue    money.Amount `json:"revenue" gorm:"type:numeric(12,2);not null"`
+}
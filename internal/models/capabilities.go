@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Capacidades de acesso por módulo, no formato "módulo:ação"
+const (
+	CapProductsRead   = "products:read"
+	CapProductsWrite  = "products:write"
+	CapProductsDelete = "products:delete"
+	CapProductsPrice  = "products:price" // Alterar preço e custo
+
+	CapCustomersRead   = "customers:read"
+	CapCustomersWrite  = "customers:write"
+	CapCustomersDelete = "customers:delete"
+
+	CapInventoryRead   = "inventory:read"
+	CapInventoryAdjust = "inventory:adjust"
+
+	CapSalesRead       = "sales:read"
+	CapSalesWrite      = "sales:write"
+	CapSalesCancel     = "sales:cancel"
+	CapSalesPromotions = "sales:promotions" // Cadastrar promoções
+
+	CapReportsRead              = "reports:read"
+	CapReportsCommissions       = "reports:commissions"        // Extrato de comissões de todos os vendedores
+	CapReportsCommissionsManage = "reports:commissions_manage" // Regras de comissão e fechamento de período
+	CapReportsGoals             = "reports:goals"              // Definir metas e acompanhar as de todos os vendedores
+
+	CapUsersRead   = "users:read"
+	CapUsersWrite  = "users:write"
+	CapUsersDelete = "users:delete"
+)
+
+// ModuleCapabilities lista as capacidades de cada módulo. Um flag de módulo
+// legado equivale a todas as capacidades do módulo.
+var ModuleCapabilities = map[string][]string{
+	ModuleProducts:  {CapProductsRead, CapProductsWrite, CapProductsDelete, CapProductsPrice},
+	ModuleCustomers: {CapCustomersRead, CapCustomersWrite, CapCustomersDelete},
+	ModuleInventory: {CapInventoryRead, CapInventoryAdjust},
+	ModuleSales:     {CapSalesRead, CapSalesWrite, CapSalesCancel, CapSalesPromotions},
+	ModuleReports:   {CapReportsRead, CapReportsCommissions, CapReportsCommissionsManage, CapReportsGoals},
+	ModuleUsers:     {CapUsersRead, CapUsersWrite, CapUsersDelete},
+}
+
+// Capabilities é a lista de capacidades, armazenada como jsonb
+type Capabilities []string
+
+// Value implementa driver.Valuer
+func (c Capabilities) Value() (driver.Value, error) {
+	return JSONB[Capabilities]{&c}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (c *Capabilities) Scan(value interface{}) error {
+	return JSONB[Capabilities]{c}.Scan(value)
+}
+
+// Has indica se a capacidade está na lista
+func (c Capabilities) Has(capability string) bool {
+	for _, item := range c {
+		if item == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidCapability indica se a capacidade existe
+func IsValidCapability(capability string) bool {
+	module, _, _ := strings.Cut(capability, ":")
+	for _, item := range ModuleCapabilities[module] {
+		if item == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Can verifica se a capacidade está liberada. Registros sem lista de
+// capacidades (anteriores à migração) usam o flag do módulo.
+func (p UserPermissions) Can(capability string) bool {
+	if p.Capabilities == nil {
+		module, _, _ := strings.Cut(capability, ":")
+		return p.moduleFlag(module)
+	}
+	return p.Capabilities.Has(capability)
+}
+
+// Normalize torna as capacidades a fonte da verdade. Sem lista explícita, as
+// capacidades são derivadas dos flags de módulo; em seguida os flags são
+// recalculados para indicar se há alguma capacidade no módulo.
+func (p *UserPermissions) Normalize() error {
+	if p.Capabilities == nil {
+		p.Capabilities = Capabilities{}
+		for module, capabilities := range ModuleCapabilities {
+			if p.moduleFlag(module) {
+				p.Capabilities = append(p.Capabilities, capabilities...)
+			}
+		}
+	}
+
+	unique := Capabilities{}
+	for _, capability := range p.Capabilities {
+		if !IsValidCapability(capability) {
+			return errors.New("capacidade inválida: " + capability)
+		}
+		if !unique.Has(capability) {
+			unique = append(unique, capability)
+		}
+	}
+	sort.Strings(unique)
+	p.Capabilities = unique
+
+	p.Products = p.hasModule(ModuleProducts)
+	p.Customers = p.hasModule(ModuleCustomers)
+	p.Inventory = p.hasModule(ModuleInventory)
+	p.Sales = p.hasModule(ModuleSales)
+	p.Reports = p.hasModule(ModuleReports)
+	p.Users = p.hasModule(ModuleUsers)
+	return nil
+}
+
+// IsSubsetOf verifica se todas as capacidades liberadas também estão em other
+func (p UserPermissions) IsSubsetOf(other UserPermissions) bool {
+	for _, capabilities := range ModuleCapabilities {
+		for _, capability := range capabilities {
+			if p.Can(capability) && !other.Can(capability) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hasModule indica se alguma capacidade do módulo está liberada
+func (p UserPermissions) hasModule(module string) bool {
+	for _, capability := range ModuleCapabilities[module] {
+		if p.Capabilities.Has(capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleFlag retorna o flag legado do módulo
+func (p UserPermissions) moduleFlag(module string) bool {
+	switch module {
+	case ModuleProducts:
+		return p.Products
+	case ModuleCustomers:
+		return p.Customers
+	case ModuleInventory:
+		return p.Inventory
+	case ModuleSales:
+		return p.Sales
+	case ModuleReports:
+		return p.Reports
+	case ModuleUsers:
+		return p.Users
+	}
+	return false
+}
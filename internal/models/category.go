@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CategoryPathSeparator separa os níveis no caminho legível de uma categoria
+const CategoryPathSeparator = " > "
+
+// Category é um nó da árvore de categorias de produto, como Masculino >
+// Bermudas > Surf. Path guarda os IDs dos ancestrais e do próprio nó
+// ("/1/4/9/"), para consultar uma subárvore com LIKE, e Depth o nível, 1 nas
+// raízes. O produto guarda a categoria em CategoryID e repete o nome em
+// Category, mantido em sincronia.
+type Category struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"not null"`
+	Slug      string         `json:"slug" gorm:"not null;uniqueIndex:idx_categories_slug,where:deleted_at IS NULL"`
+	ParentID  *uint          `json:"parent_id" gorm:"index"`
+	Path      string         `json:"path" gorm:"not null;index"`
+	Depth     int            `json:"depth" gorm:"not null;default:1"`
+	Position  int            `json:"position" gorm:"not null;default:0"` // Ordem entre as irmãs
+	Active    bool           `json:"active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relacionamentos
+	Children []Category `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+type CategoryInput struct {
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+	Position int    `json:"position"`
+	Active   *bool  `json:"active"`
+}
+
+// SetParent posiciona a categoria, que já precisa ter ID, sob parent (nil
+// para raiz), atualizando ParentID, Path e Depth
+func (c *Category) SetParent(parent *Category) {
+	if parent == nil {
+		c.ParentID = nil
+		c.Path = fmt.Sprintf("/%d/", c.ID)
+		c.Depth = 1
+		return
+	}
+	c.ParentID = &parent.ID
+	c.Path = fmt.Sprintf("%s%d/", parent.Path, c.ID)
+	c.Depth = parent.Depth + 1
+}
+
+// IsDescendantOf indica se a categoria é other ou está abaixo dela na árvore
+func (c *Category) IsDescendantOf(other *Category) bool {
+	return strings.HasPrefix(c.Path, other.Path)
+}
+
+// Slugify gera o identificador de URL: minúsculas, sem acentos e com hífens
+// no lugar de espaços e pontuação. "Calças & Shorts" → "calcas-shorts"
+func Slugify(value string) string {
+	value = skuReplacer.Replace(strings.ToUpper(strings.TrimSpace(value)))
+	var slug strings.Builder
+	separator := false
+	for _, r := range value {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			if separator && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			separator = false
+			slug.WriteString(strings.ToLower(string(r)))
+			continue
+		}
+		separator = true
+	}
+	return slug.String()
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"loja-online/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Bases de cálculo da comissão
+const (
+	CommissionBaseRevenue = "revenue" // Valor vendido (parte do FinalAmount)
+	CommissionBaseMargin  = "margin"  // Valor vendido menos o custo
+)
+
+// Tipos de lançamento do extrato de comissões
+const (
+	CommissionEntrySale      = "sale"
+	CommissionEntryDeduction = "deduction" // Estorno de venda cancelada ou devolvida após o fechamento
+)
+
+// CommissionRule define o percentual de comissão. Os campos de escopo
+// preenchidos precisam coincidir com a venda; entre as regras aplicáveis,
+// vence a mais específica (produto, marca, categoria, vendedor, papel). A
+// regra de categoria vale também para as subcategorias.
+type CommissionRule struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"not null"`
+	UserID     *uint      `json:"user_id" gorm:"index"` // Vendedor
+	Role       string     `json:"role"`
+	CategoryID *uint      `json:"category_id" gorm:"index"`
+	Category   string     `json:"category"` // Caminho da categoria, para exibição
+	Brand      string     `json:"brand"`
+	ProductID  *uint      `json:"product_id"`
+	StoreID    *uint      `json:"store_id"`
+	Base       string     `json:"base" gorm:"not null;default:'revenue'"` // revenue, margin
+	Rate       money.Rate `json:"rate" gorm:"type:numeric(5,2);not null;default:0"`
+	// Faixas por meta: o percentual passa a ser o da maior faixa atingida
+	// pelo total vendido pelo vendedor no período
+	Tiers     CommissionTiers `json:"tiers" gorm:"type:jsonb"`
+	ValidFrom *time.Time      `json:"valid_from"`
+	ValidTo   *time.Time      `json:"valid_to"`
+	Active    bool            `json:"active" gorm:"default:true"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt gorm.DeletedAt  `json:"-" gorm:"index"`
+}
+
+// CommissionTier é uma faixa de meta com o percentual aplicado a partir dela
+type CommissionTier struct {
+	MinAmount money.Amount `json:"min_amount" binding:"gte=0"`
+	Rate      money.Rate   `json:"rate" binding:"gte=0,lte=10000"` // Até 100%, em pontos-base
+}
+
+// CommissionTiers é a lista de faixas, armazenada como jsonb
+type CommissionTiers []CommissionTier
+
+// Value implementa driver.Valuer
+func (t CommissionTiers) Value() (driver.Value, error) {
+	return JSONB[CommissionTiers]{&t}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (t *CommissionTiers) Scan(value interface{}) error {
+	return JSONB[CommissionTiers]{t}.Scan(value)
+}
+
+// RateFor retorna o percentual para o total vendido no período
+func (r *CommissionRule) RateFor(periodRevenue money.Amount) money.Rate {
+	if len(r.Tiers) == 0 {
+		return r.Rate
+	}
+
+	rate, reached := r.Rate, money.Amount(-1)
+	for _, tier := range r.Tiers {
+		if periodRevenue >= tier.MinAmount && tier.MinAmount > reached {
+			rate, reached = tier.Rate, tier.MinAmount
+		}
+	}
+	return rate
+}
+
+type CommissionRuleInput struct {
+	Name   string `json:"name" binding:"required"`
+	UserID *uint  `json:"user_id"`
+	Role   string `json:"role"`
+	// Categoria por category_id ou, sem ele, pelo nome ou caminho em category
+	CategoryID *uint            `json:"category_id"`
+	Category   string           `json:"category"`
+	Brand      string           `json:"brand"`
+	ProductID  *uint            `json:"product_id"`
+	StoreID    *uint            `json:"store_id"`
+	Base       string           `json:"base" binding:"required,oneof=revenue margin"`
+	Rate       money.Rate       `json:"rate" binding:"gte=0,lte=10000"` // Até 100%, em pontos-base
+	Tiers      []CommissionTier `json:"tiers" binding:"dive"`
+	ValidFrom  *time.Time       `json:"valid_from"`
+	ValidTo    *time.Time       `json:"valid_to"`
+	Active     *bool            `json:"active"`
+}
+
+// CommissionPeriod é um período de comissões fechado; seus lançamentos não
+// mudam mais, e cancelamentos posteriores viram estornos no período seguinte
+type CommissionPeriod struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	StartDate   time.Time    `json:"start_date" gorm:"not null;index"`
+	EndDate     time.Time    `json:"end_date" gorm:"not null;index"` // Exclusivo
+	TotalAmount money.Amount `json:"total_amount" gorm:"type:numeric(12,2)"`
+	ClosedByID  uint         `json:"closed_by_id"`
+	ClosedAt    time.Time    `json:"closed_at"`
+
+	// Relacionamentos
+	ClosedBy User `json:"-"`
+}
+
+// CommissionEntry é um lançamento do extrato de comissões. Lançamentos com
+// PeriodID pertencem a um período fechado.
+type CommissionEntry struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	PeriodID   *uint        `json:"period_id" gorm:"index"`
+	UserID     uint         `json:"user_id" gorm:"not null;index"`
+	StoreID    uint         `json:"store_id" gorm:"index"`
+	SaleID     uint         `json:"sale_id" gorm:"not null;index"`
+	SaleItemID uint         `json:"sale_item_id"`
+	ProductID  uint         `json:"product_id"`
+	RuleID     uint         `json:"rule_id"`
+	Type       string       `json:"type" gorm:"not null"` // sale, deduction
+	Base       string       `json:"base"`
+	BaseAmount money.Amount `json:"base_amount" gorm:"type:numeric(12,2)"`
+	Rate       money.Rate   `json:"rate" gorm:"type:numeric(5,2)"`
+	Amount     money.Amount `json:"amount" gorm:"type:numeric(12,2)"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type CommissionPeriodClose struct {
+	StartDate string `json:"start_date" binding:"required"` // AAAA-MM-DD
+	EndDate   string `json:"end_date" binding:"required"`   // AAAA-MM-DD, inclusivo
+}
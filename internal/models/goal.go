@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"loja-online/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Indicadores acompanhados pelas metas
+const (
+	GoalMetricRevenue       = "revenue"        // Valor vendido (FinalAmount)
+	GoalMetricUnits         = "units"          // Peças vendidas
+	GoalMetricAverageTicket = "average_ticket" // Valor médio por venda
+)
+
+// Goal é a meta de um indicador no período: em reais em Target ou, na meta
+// de unidades, em peças em TargetUnits. Com UserID, vale para as vendas do
+// vendedor; com StoreID, para as vendas da loja; com ambos, para as vendas do
+// vendedor naquela loja.
+type Goal struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name"`
+	UserID      *uint          `json:"user_id" gorm:"index"`
+	StoreID     *uint          `json:"store_id" gorm:"index"`
+	Metric      string         `json:"metric" gorm:"not null"`                    // revenue, units, average_ticket
+	Target      money.Amount   `json:"target" gorm:"type:numeric(12,2);not null"` // Reais, nas metas revenue e average_ticket
+	TargetUnits int64          `json:"target_units" gorm:"not null;default:0"`    // Peças, na meta units
+	StartDate   time.Time      `json:"start_date" gorm:"not null;index"`
+	EndDate     time.Time      `json:"end_date" gorm:"not null;index"` // Exclusivo
+	CreatedByID uint           `json:"created_by_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relacionamentos
+	User  *User  `json:"user,omitempty"`
+	Store *Store `json:"store,omitempty"`
+}
+
+// ElapsedFraction retorna a fração do período já decorrida em at, entre 0 e 1
+func (g *Goal) ElapsedFraction(at time.Time) float64 {
+	elapsed, total := g.Elapsed(at)
+	if total <= 0 {
+		return 0
+	}
+	return float64(elapsed) / float64(total)
+}
+
+// Elapsed retorna quanto do período já decorreu em at, limitado ao período, e
+// a duração total
+func (g *Goal) Elapsed(at time.Time) (elapsed, total time.Duration) {
+	total = g.EndDate.Sub(g.StartDate)
+	switch {
+	case total <= 0 || !at.After(g.StartDate):
+		return 0, total
+	case !at.Before(g.EndDate):
+		return total, total
+	}
+	return at.Sub(g.StartDate), total
+}
+
+// GoalInput define uma meta. O período vem de month (AAAA-MM) ou de
+// start_date e end_date (AAAA-MM-DD, inclusivos).
+type GoalInput struct {
+	Name        string       `json:"name"`
+	UserID      *uint        `json:"user_id"`
+	StoreID     *uint        `json:"store_id"`
+	Metric      string       `json:"metric" binding:"required,oneof=revenue units average_ticket"`
+	Target      money.Amount `json:"target" binding:"gte=0"`       // Metas revenue e average_ticket
+	TargetUnits int64        `json:"target_units" binding:"gte=0"` // Meta units
+	Month       string       `json:"month"`
+	StartDate   string       `json:"start_date"`
+	EndDate     string       `json:"end_date"`
+}
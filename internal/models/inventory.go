@@ -8,7 +8,8 @@ import (
 
 type InventoryItem struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	ProductID uint           `json:"product_id" gorm:"not null"`
+	StoreID   uint           `json:"store_id" gorm:"index:idx_inventory_store_product"`
+	ProductID uint           `json:"product_id" gorm:"not null;index:idx_inventory_store_product"`
 	Quantity  int            `json:"quantity" gorm:"not null;default:0"`
 	MinStock  int            `json:"min_stock" gorm:"default:0"`
 	MaxStock  int            `json:"max_stock" gorm:"default:1000"`
@@ -24,6 +25,7 @@ type InventoryItem struct {
 
 type InventoryMovement struct {
 	ID            uint      `json:"id" gorm:"primaryKey"`
+	StoreID       uint      `json:"store_id" gorm:"index"`
 	ProductID     uint      `json:"product_id" gorm:"not null"`
 	MovementType  string    `json:"movement_type" gorm:"not null"` // entry, exit, adjustment
 	Quantity      int       `json:"quantity" gorm:"not null"`
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+type Invitation struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Email       string     `json:"email" gorm:"not null;index"`
+	Role        string     `json:"role" gorm:"not null;default:'user'"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+	UserID      *uint      `json:"user_id"` // Usuário criado ao aceitar o convite
+	CreatedByID uint       `json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Permissões concedidas ao aceitar o convite
+	Permissions UserPermissions `json:"permissions" gorm:"embedded"`
+
+	// Relacionamentos
+	CreatedBy User `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+}
+
+type InvitationCreate struct {
+	Email       string          `json:"email" binding:"required,email"`
+	Role        string          `json:"role" binding:"required,oneof=admin manager user"`
+	Permissions UserPermissions `json:"permissions"`
+}
+
+type InvitationAccept struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// IsUsable indica se o convite ainda pode ser aceito
+func (i *Invitation) IsUsable(now time.Time) bool {
+	return i.UsedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONB grava em uma coluna jsonb o valor apontado por Data. Os tipos das
+// colunas jsonb delegam a ele o Value e o Scan; valores nulos, como listas
+// nil, viram NULL.
+type JSONB[T any] struct {
+	Data *T
+}
+
+// Value implementa driver.Valuer
+func (j JSONB[T]) Value() (driver.Value, error) {
+	if j.Data == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(*j.Data)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+// Scan implementa sql.Scanner
+func (j JSONB[T]) Scan(value interface{}) error {
+	var zero T
+	switch v := value.(type) {
+	case nil:
+		*j.Data = zero
+		return nil
+	case []byte:
+		*j.Data = zero
+		return json.Unmarshal(v, j.Data)
+	case string:
+		*j.Data = zero
+		return json.Unmarshal([]byte(v), j.Data)
+	}
+	return fmt.Errorf("tipo incompatível com %T", zero)
+}
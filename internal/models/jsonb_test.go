@@ -0,0 +1,51 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"loja-online/internal/money"
+)
+
+func TestJSONBRoundTrip(t *testing.T) {
+	tiers := PromotionTiers{{MinQuantity: 3, Percent: money.BasisPoints(10_00)}}
+	value, err := tiers.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// O driver entrega jsonb como []byte ou string
+	for _, raw := range []interface{}{[]byte(value.(string)), value} {
+		var scanned PromotionTiers
+		if err := scanned.Scan(raw); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(scanned, tiers) {
+			t.Errorf("lido %+v, esperado %+v", scanned, tiers)
+		}
+	}
+
+	audit := JSON(`{"name":{"before":"a","after":"b"}}`)
+	value, _ = audit.Value()
+	var scanned JSON
+	if err := scanned.Scan([]byte(value.(string))); err != nil || string(scanned) != string(audit) {
+		t.Errorf("documento lido %s, esperado %s (%v)", scanned, audit, err)
+	}
+}
+
+func TestJSONBNull(t *testing.T) {
+	var list StringList
+	if value, err := list.Value(); value != nil || err != nil {
+		t.Errorf("lista nil gravada como %v (%v), esperado NULL", value, err)
+	}
+
+	files := ImageFiles{"original": "a.jpg"}
+	if err := files.Scan(nil); err != nil || files != nil {
+		t.Errorf("NULL lido como %v (%v), esperado nil", files, err)
+	}
+
+	var capabilities Capabilities
+	if err := capabilities.Scan(42); err == nil {
+		t.Error("tipo incompatível deveria gerar erro")
+	}
+}
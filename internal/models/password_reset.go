@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordReset guarda o hash de um token de redefinição de senha
+type PasswordReset struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	RequestIP string     `json:"request_ip"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type PasswordForgot struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordResetConfirm struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// IsUsable indica se o token ainda pode ser utilizado
+func (p *PasswordReset) IsUsable(now time.Time) bool {
+	return p.UsedAt == nil && now.Before(p.ExpiresAt)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"loja-online/internal/money"
+)
+
+// Origens de uma alteração de preço ou custo
+const (
+	PriceSourceInitial  = "initial"  // Cadastro do produto
+	PriceSourceManual   = "manual"   // Edição do produto
+	PriceSourceImport   = "import"   // Importação de planilha
+	PriceSourceStyle    = "style"    // Replicada do estilo para a variação
+	PriceSourceSchedule = "schedule" // Alteração agendada
+)
+
+// Situações de uma alteração de preço agendada
+const (
+	ScheduledPricePending   = "pending"
+	ScheduledPriceApplied   = "applied"
+	ScheduledPriceCancelled = "cancelled"
+)
+
+// PriceHistory registra o preço e o custo do produto a partir de ChangedAt.
+// Os valores anteriores ficam vazios no registro do cadastro.
+type PriceHistory struct {
+	ID                uint          `json:"id" gorm:"primaryKey"`
+	ProductID         uint          `json:"product_id" gorm:"not null;index:idx_price_histories_product_changed"`
+	Price             money.Amount  `json:"price" gorm:"type:numeric(12,2);not null"`
+	CostPrice         money.Amount  `json:"cost_price" gorm:"type:numeric(12,2);not null;default:0"`
+	PreviousPrice     *money.Amount `json:"previous_price" gorm:"type:numeric(12,2)"`
+	PreviousCostPrice *money.Amount `json:"previous_cost_price" gorm:"type:numeric(12,2)"`
+	Source            string        `json:"source" gorm:"not null"`
+	UserID            *uint         `json:"user_id"`
+	ScheduledPriceID  *uint         `json:"scheduled_price_id"`
+	ChangedAt         time.Time     `json:"changed_at" gorm:"not null;index:idx_price_histories_product_changed"`
+
+	// Relacionamentos
+	User *User `json:"user,omitempty"`
+}
+
+// ScheduledPrice é uma alteração de preço e/ou custo com data futura, como a
+// remarcação de fim de estação, aplicada automaticamente em EffectiveAt
+type ScheduledPrice struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	ProductID   uint          `json:"product_id" gorm:"not null;index"`
+	Price       *money.Amount `json:"price" gorm:"type:numeric(12,2)"`
+	CostPrice   *money.Amount `json:"cost_price" gorm:"type:numeric(12,2)"`
+	EffectiveAt time.Time     `json:"effective_at" gorm:"not null;index"`
+	Status      string        `json:"status" gorm:"not null;default:'pending';index"`
+	Reason      string        `json:"reason"`
+	Error       string        `json:"error,omitempty"` // Motivo do cancelamento automático
+	CreatedByID uint          `json:"created_by_id"`
+	AppliedAt   *time.Time    `json:"applied_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+type ScheduledPriceInput struct {
+	Price       *money.Amount `json:"price" binding:"omitempty,gt=0"`
+	CostPrice   *money.Amount `json:"cost_price" binding:"omitempty,gte=0"`
+	EffectiveAt time.Time     `json:"effective_at" binding:"required"`
+	Reason      string        `json:"reason"`
+}
@@ -1,64 +1,158 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"loja-online/internal/money"
+
 	"gorm.io/gorm"
 )
 
+// Product é um produto vendável ou, com HasVariants, o estilo que agrupa a
+// grade de tamanhos e cores. As variações repetem os dados comuns do estilo,
+// mantidos em sincronia, e têm SKU, código de barras e estoque próprios. Com
+// IsBundle, é um kit vendido por um preço próprio e montado com Components.
 type Product struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description"`
-	Category    string         `json:"category" gorm:"not null"` // Camiseta, Calça, Vestido, etc.
-	Brand       string         `json:"brand"`
-	Price       float64        `json:"price" gorm:"not null"`
-	CostPrice   float64        `json:"cost_price"`
-	SKU         string         `json:"sku" gorm:"unique;not null"`
-	Color       string         `json:"color"`
-	Size        string         `json:"size"`
-	Material    string         `json:"material"`
-	Gender      string         `json:"gender"` // Masculino, Feminino, Unissex
-	Season      string         `json:"season"` // Verão, Inverno, etc.
-	Active      bool           `json:"active" gorm:"default:true"`
-	ImageURL    string         `json:"image_url"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Name          string         `json:"name" gorm:"not null"`
+	Description   string         `json:"description"`
+	Category      string         `json:"category" gorm:"not null"` // Nome da categoria: Camiseta, Calça, Vestido, etc.
+	CategoryID    *uint          `json:"category_id" gorm:"index"`
+	Brand         string         `json:"brand"`
+	Price         money.Amount   `json:"price" gorm:"type:numeric(12,2);not null"`
+	CostPrice     money.Amount   `json:"cost_price" gorm:"type:numeric(12,2)"`
+	SKU           string         `json:"sku" gorm:"unique;not null"`
+	Barcode       string         `json:"barcode" gorm:"uniqueIndex:idx_products_barcode,where:barcode <> ''"`
+	Color         string         `json:"color"`
+	Size          string         `json:"size"`
+	Material      string         `json:"material"`
+	Gender        string         `json:"gender"` // Masculino, Feminino, Unissex
+	Season        string         `json:"season"` // Verão, Inverno, etc.
+	Active        bool           `json:"active" gorm:"default:true"`
+	ImageURL      string         `json:"image_url"`
+	ParentID      *uint          `json:"parent_id" gorm:"index"`              // Estilo ao qual a variação pertence
+	HasVariants   bool           `json:"has_variants" gorm:"default:false"`   // Estilo com grade; não é vendido diretamente
+	PriceOverride bool           `json:"price_override" gorm:"default:false"` // Variação com preço próprio, diferente do estilo
+	IsBundle      bool           `json:"is_bundle" gorm:"default:false"`      // Kit formado por outros produtos; o estoque vem dos componentes
+	Stock         *int           `json:"stock,omitempty" gorm:"-"`            // Estoque na loja ativa ou nas lojas visíveis; nos estilos, soma das variações; nos kits, quantos os componentes permitem montar
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relacionamentos
-	InventoryItems []InventoryItem `json:"inventory_items,omitempty"`
-	SaleItems      []SaleItem      `json:"sale_items,omitempty"`
+	Variants       []Product         `json:"variants,omitempty" gorm:"foreignKey:ParentID"`
+	Components     []BundleComponent `json:"components,omitempty" gorm:"foreignKey:BundleID"`
+	Images         []ProductImage    `json:"images,omitempty"`
+	InventoryItems []InventoryItem   `json:"inventory_items,omitempty"`
+	SaleItems      []SaleItem        `json:"sale_items,omitempty"`
 }
 
 type ProductCreate struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Category    string  `json:"category" binding:"required"`
-	Brand       string  `json:"brand"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	CostPrice   float64 `json:"cost_price"`
-	SKU         string  `json:"sku" binding:"required"`
-	Color       string  `json:"color"`
-	Size        string  `json:"size"`
-	Material    string  `json:"material"`
-	Gender      string  `json:"gender"`
-	Season      string  `json:"season"`
-	ImageURL    string  `json:"image_url"`
+	Name        string       `json:"name" binding:"required"`
+	Description string       `json:"description"`
+	Category    string       `json:"category" binding:"required_without=CategoryID"`
+	CategoryID  *uint        `json:"category_id"`
+	Brand       string       `json:"brand"`
+	Price       money.Amount `json:"price" binding:"required,gt=0"`
+	CostPrice   money.Amount `json:"cost_price"`
+	SKU         string       `json:"sku" binding:"required"`
+	Barcode     string       `json:"barcode"`
+	Color       string       `json:"color"`
+	Size        string       `json:"size"`
+	Material    string       `json:"material"`
+	Gender      string       `json:"gender"`
+	Season      string       `json:"season"`
+	ImageURL    string       `json:"image_url"`
 }
 
 type ProductUpdate struct {
-	Name        *string  `json:"name"`
-	Description *string  `json:"description"`
-	Category    *string  `json:"category"`
-	Brand       *string  `json:"brand"`
-	Price       *float64 `json:"price"`
-	CostPrice   *float64 `json:"cost_price"`
-	Color       *string  `json:"color"`
-	Size        *string  `json:"size"`
-	Material    *string  `json:"material"`
-	Gender      *string  `json:"gender"`
-	Season      *string  `json:"season"`
-	ImageURL    *string  `json:"image_url"`
-	Active      *bool    `json:"active"`
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Category    *string       `json:"category"`
+	CategoryID  *uint         `json:"category_id"`
+	Brand       *string       `json:"brand"`
+	Price       *money.Amount `json:"price"`
+	Barcode     *string       `json:"barcode"`
+	CostPrice   *money.Amount `json:"cost_price"`
+	Color       *string       `json:"color"`
+	Size        *string       `json:"size"`
+	Material    *string       `json:"material"`
+	Gender      *string       `json:"gender"`
+	Season      *string       `json:"season"`
+	ImageURL    *string       `json:"image_url"`
+	Active      *bool         `json:"active"`
+}
+
+// IsVariant indica se o produto é uma variação de um estilo
+func (p *Product) IsVariant() bool {
+	return p.ParentID != nil
+}
+
+// VariantGridCreate cria as variações de um estilo para cada combinação de
+// tamanho e cor. Variants ajusta SKU, código de barras ou preço de células
+// específicas da grade; com GenerateBarcodes, as variações sem código de
+// barras recebem um código interno.
+type VariantGridCreate struct {
+	Sizes            []string       `json:"sizes"`
+	Colors           []string       `json:"colors"`
+	Variants         []VariantInput `json:"variants" binding:"dive"`
+	GenerateBarcodes bool           `json:"generate_barcodes"`
+}
+
+type VariantInput struct {
+	Size    string        `json:"size"`
+	Color   string        `json:"color"`
+	SKU     string        `json:"sku"`
+	Barcode string        `json:"barcode"`
+	Price   *money.Amount `json:"price" binding:"omitempty,gt=0"`
+}
+
+// ProductLabelsRequest pede etiquetas dos produtos; um estilo gera a
+// quantidade pedida para cada uma de suas variações
+type ProductLabelsRequest struct {
+	Format string             `json:"format" binding:"required,oneof=pdf zpl"`
+	Items  []ProductLabelItem `json:"items" binding:"required,min=1,dive"`
+}
+
+type ProductLabelItem struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,gt=0,lte=1000"`
+}
+
+var skuReplacer = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C",
+)
+
+// VariantSKU monta o SKU da variação a partir do SKU do estilo, das três
+// primeiras letras da cor e do tamanho: CAM-BAS, Branco, M → CAM-BAS-BRA-M
+func VariantSKU(styleSKU, color, size string) string {
+	parts := []string{styleSKU}
+	if code := skuCode(color); code != "" {
+		if len(code) > 3 {
+			code = code[:3]
+		}
+		parts = append(parts, code)
+	}
+	if code := skuCode(size); code != "" {
+		parts = append(parts, code)
+	}
+	return strings.Join(parts, "-")
+}
+
+// skuCode mantém apenas letras e números, em maiúsculas e sem acentos
+func skuCode(value string) string {
+	value = skuReplacer.Replace(strings.ToUpper(value))
+	var code strings.Builder
+	for _, r := range value {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			code.WriteRune(r)
+		}
+	}
+	return code.String()
 }
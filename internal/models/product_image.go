@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MediaPath é o prefixo das URLs que servem os arquivos do Storage
+const MediaPath = "/media/"
+
+// Nome do arquivo original em ImageFiles; os demais são as miniaturas,
+// identificadas pela maior dimensão ("150", "400"...)
+const ImageFileOriginal = "original"
+
+// ProductImage é uma imagem do produto, com a original e as miniaturas no
+// Storage. A imagem principal também é gravada em Product.ImageURL.
+type ProductImage struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	ProductID   uint       `json:"product_id" gorm:"not null;index"`
+	Position    int        `json:"position" gorm:"not null;default:0"`
+	IsPrimary   bool       `json:"primary" gorm:"default:false"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"` // Bytes da original
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Files       ImageFiles `json:"-" gorm:"type:jsonb"` // Chaves no Storage
+	CreatedAt   time.Time  `json:"created_at"`
+
+	URL        string            `json:"url" gorm:"-"`
+	Thumbnails map[string]string `json:"thumbnails" gorm:"-"`
+}
+
+// AfterFind preenche as URLs a partir das chaves no Storage
+func (i *ProductImage) AfterFind(tx *gorm.DB) error {
+	i.FillURLs()
+	return nil
+}
+
+// FillURLs preenche URL e Thumbnails a partir de Files
+func (i *ProductImage) FillURLs() {
+	i.Thumbnails = make(map[string]string, len(i.Files))
+	for name, key := range i.Files {
+		if name == ImageFileOriginal {
+			i.URL = MediaPath + key
+		} else {
+			i.Thumbnails[name] = MediaPath + key
+		}
+	}
+}
+
+// ImageFiles associa cada arquivo da imagem à sua chave no Storage, armazenado como jsonb
+type ImageFiles map[string]string
+
+// Value implementa driver.Valuer
+func (f ImageFiles) Value() (driver.Value, error) {
+	return JSONB[ImageFiles]{&f}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (f *ImageFiles) Scan(value interface{}) error {
+	return JSONB[ImageFiles]{f}.Scan(value)
+}
+
+type ProductImageOrder struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "strings"
+
+// ProductImportColumns são os campos da planilha de produtos, na ordem da
+// exportação. O SKU identifica o produto: existente é atualizado, novo é criado.
+var ProductImportColumns = []string{
+	"sku", "name", "description", "category", "brand", "price", "cost_price", "barcode",
+	"color", "size", "material", "gender", "season", "active", "image_url", "stock",
+}
+
+// productImportAliases associa os nomes usuais das colunas em português aos campos
+var productImportAliases = map[string]string{
+	"NOME":             "name",
+	"DESCRICAO":        "description",
+	"CATEGORIA":        "category",
+	"MARCA":            "brand",
+	"PRECO":            "price",
+	"PRECO_DE_VENDA":   "price",
+	"CUSTO":            "cost_price",
+	"PRECO_DE_CUSTO":   "cost_price",
+	"CODIGO_DE_BARRAS": "barcode",
+	"EAN":              "barcode",
+	"COR":              "color",
+	"TAMANHO":          "size",
+	"GENERO":           "gender",
+	"ESTACAO":          "season",
+	"TEMPORADA":        "season",
+	"ATIVO":            "active",
+	"IMAGEM":           "image_url",
+	"ESTOQUE":          "stock",
+	"ESTOQUE_INICIAL":  "stock",
+}
+
+// ProductImportField identifica o campo de uma coluna pelo nome, sem
+// diferenciar maiúsculas, acentos e espaços ("Preço de custo" → cost_price)
+func ProductImportField(column string) (string, bool) {
+	key := skuReplacer.Replace(strings.ToUpper(strings.TrimSpace(column)))
+	key = strings.Join(strings.FieldsFunc(key, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-'
+	}), "_")
+
+	if field, ok := productImportAliases[key]; ok {
+		return field, true
+	}
+	for _, field := range ProductImportColumns {
+		if strings.ToUpper(field) == key {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// ProductImportIssue é um erro ou aviso sobre uma linha da planilha
+type ProductImportIssue struct {
+	Row     int    `json:"row"` // Número da linha no arquivo, contando o cabeçalho
+	SKU     string `json:"sku,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
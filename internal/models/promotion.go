@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"loja-online/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de promoção
+const (
+	PromotionPercentage  = "percentage"  // Percentual sobre o preço
+	PromotionFixed       = "fixed"       // Valor fixo por unidade
+	PromotionBuyXPayY    = "buy_x_pay_y" // Leve X pague Y: as unidades mais baratas saem de graça
+	PromotionProgressive = "progressive" // Percentual conforme a quantidade de peças
+)
+
+// Promotion é um desconto aplicado automaticamente nas vendas. Os campos de
+// escopo preenchidos precisam coincidir com o produto; listas vazias valem
+// para todos. As promoções são avaliadas por prioridade, e uma promoção não
+// cumulativa só vale para itens ainda sem desconto e impede as seguintes.
+type Promotion struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"not null"`
+	Description string       `json:"description"`
+	Type        string       `json:"type" gorm:"not null"`                                // percentage, fixed, buy_x_pay_y, progressive
+	Value       money.Amount `json:"value" gorm:"type:numeric(12,2)"`                     // Desconto por unidade em reais, na promoção fixed
+	Percent     money.Rate   `json:"percent" gorm:"type:numeric(5,2);not null;default:0"` // Desconto da promoção percentage
+	BuyQuantity int          `json:"buy_quantity"`                                        // Leve X
+	PayQuantity int          `json:"pay_quantity"`                                        // Pague Y
+	// Faixas do desconto progressivo: vale a maior faixa atingida pela
+	// quantidade de peças da promoção na venda
+	Tiers PromotionTiers `json:"tiers" gorm:"type:jsonb"`
+
+	// Escopo
+	Categories StringList `json:"categories" gorm:"type:jsonb"`
+	Brands     StringList `json:"brands" gorm:"type:jsonb"`
+	Seasons    StringList `json:"seasons" gorm:"type:jsonb"`
+	Genders    StringList `json:"genders" gorm:"type:jsonb"`
+	SKUs       StringList `json:"skus" gorm:"column:skus;type:jsonb"` // SKU da variação ou do estilo
+	StoreID    *uint      `json:"store_id"`
+
+	StartsAt   *time.Time     `json:"starts_at"`
+	EndsAt     *time.Time     `json:"ends_at"` // Exclusivo
+	Priority   int            `json:"priority" gorm:"not null;default:0"`
+	Cumulative bool           `json:"cumulative" gorm:"default:false"`
+	Active     bool           `json:"active" gorm:"default:true"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// PromotionTier é uma faixa do desconto progressivo
+type PromotionTier struct {
+	MinQuantity int        `json:"min_quantity" binding:"gt=0"`
+	Percent     money.Rate `json:"percent" binding:"gt=0,lte=10000"` // Até 100%, em pontos-base
+}
+
+// PromotionTiers é a lista de faixas, armazenada como jsonb
+type PromotionTiers []PromotionTier
+
+// Value implementa driver.Valuer
+func (t PromotionTiers) Value() (driver.Value, error) {
+	return JSONB[PromotionTiers]{&t}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (t *PromotionTiers) Scan(value interface{}) error {
+	return JSONB[PromotionTiers]{t}.Scan(value)
+}
+
+// StringList é uma lista de textos, armazenada como jsonb
+type StringList []string
+
+// Value implementa driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	return JSONB[StringList]{&l}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	return JSONB[StringList]{l}.Scan(value)
+}
+
+// AppliedPromotion é o desconto de uma promoção em um item da venda
+type AppliedPromotion struct {
+	PromotionID uint         `json:"promotion_id"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Amount      money.Amount `json:"amount"`
+}
+
+// AppliedPromotions é o detalhamento dos descontos do item, armazenado como jsonb
+type AppliedPromotions []AppliedPromotion
+
+// Value implementa driver.Valuer
+func (a AppliedPromotions) Value() (driver.Value, error) {
+	return JSONB[AppliedPromotions]{&a}.Value()
+}
+
+// Scan implementa sql.Scanner
+func (a *AppliedPromotions) Scan(value interface{}) error {
+	return JSONB[AppliedPromotions]{a}.Scan(value)
+}
+
+type PromotionInput struct {
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description"`
+	Type        string          `json:"type" binding:"required,oneof=percentage fixed buy_x_pay_y progressive"`
+	Value       money.Amount    `json:"value" binding:"gte=0"`
+	Percent     money.Rate      `json:"percent" binding:"gte=0"`
+	BuyQuantity int             `json:"buy_quantity" binding:"gte=0"`
+	PayQuantity int             `json:"pay_quantity" binding:"gte=0"`
+	Tiers       []PromotionTier `json:"tiers" binding:"dive"`
+	Categories  []string        `json:"categories"`
+	Brands      []string        `json:"brands"`
+	Seasons     []string        `json:"seasons"`
+	Genders     []string        `json:"genders"`
+	SKUs        []string        `json:"skus"`
+	StoreID     *uint           `json:"store_id"`
+	StartsAt    *time.Time      `json:"starts_at"`
+	EndsAt      *time.Time      `json:"ends_at"`
+	Priority    int             `json:"priority"`
+	Cumulative  bool            `json:"cumulative"`
+	Active      *bool           `json:"active"`
+}
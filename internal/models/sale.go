@@ -3,23 +3,34 @@ package models
 import (
 	"time"
 
+	"loja-online/internal/money"
+
 	"gorm.io/gorm"
 )
 
+// Situações de venda que anulam o valor vendido
+const (
+	SaleStatusCancelled = "cancelled"
+	SaleStatusReturned  = "returned"
+)
+
 type Sale struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	CustomerID    uint           `json:"customer_id"`
-	UserID        uint           `json:"user_id" gorm:"not null"` // Vendedor
-	TotalAmount   float64        `json:"total_amount" gorm:"not null"`
-	Discount      float64        `json:"discount" gorm:"default:0"`
-	FinalAmount   float64        `json:"final_amount" gorm:"not null"`
-	Status        string         `json:"status" gorm:"default:'pending'"` // pending, confirmed, shipped, delivered, cancelled
-	PaymentMethod string         `json:"payment_method"`                  // cash, card, pix, etc.
-	Notes         string         `json:"notes"`
-	SaleDate      time.Time      `json:"sale_date"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	StoreID           uint           `json:"store_id" gorm:"index"`
+	CustomerID        uint           `json:"customer_id"`
+	UserID            uint           `json:"user_id" gorm:"not null"`                                // Vendedor
+	TotalAmount       money.Amount   `json:"total_amount" gorm:"type:numeric(12,2);not null"`        // Soma dos itens a preço cheio
+	PromotionDiscount money.Amount   `json:"promotion_discount" gorm:"type:numeric(12,2);default:0"` // Soma dos descontos de promoção dos itens
+	Discount          money.Amount   `json:"discount" gorm:"type:numeric(12,2);default:0"`           // Desconto manual
+	FinalAmount       money.Amount   `json:"final_amount" gorm:"type:numeric(12,2);not null"`
+	Status            string         `json:"status" gorm:"default:'pending'"` // pending, confirmed, shipped, delivered, cancelled, returned
+	CancelledAt       *time.Time     `json:"cancelled_at"`                    // Quando foi cancelada ou devolvida
+	PaymentMethod     string         `json:"payment_method"`                  // cash, card, pix, etc.
+	Notes             string         `json:"notes"`
+	SaleDate          time.Time      `json:"sale_date"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relacionamentos
 	Customer  Customer   `json:"customer"`
@@ -28,12 +39,19 @@ type Sale struct {
 }
 
 type SaleItem struct {
-	ID         uint    `json:"id" gorm:"primaryKey"`
-	SaleID     uint    `json:"sale_id" gorm:"not null"`
-	ProductID  uint    `json:"product_id" gorm:"not null"`
-	Quantity   int     `json:"quantity" gorm:"not null"`
-	UnitPrice  float64 `json:"unit_price" gorm:"not null"`
-	TotalPrice float64 `json:"total_price" gorm:"not null"`
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	SaleID     uint         `json:"sale_id" gorm:"not null"`
+	ProductID  uint         `json:"product_id" gorm:"not null"`
+	Quantity   int          `json:"quantity" gorm:"not null"`
+	UnitPrice  money.Amount `json:"unit_price" gorm:"type:numeric(12,2);not null"`
+	ListPrice  money.Amount `json:"list_price" gorm:"type:numeric(12,2)"`           // Preço do cadastro no momento da venda
+	UnitCost   money.Amount `json:"unit_cost" gorm:"type:numeric(12,2)"`            // Custo unitário no momento da venda
+	Discount   money.Amount `json:"discount" gorm:"type:numeric(12,2);default:0"`   // Desconto de promoções
+	TotalPrice money.Amount `json:"total_price" gorm:"type:numeric(12,2);not null"` // Quantidade vezes o preço, menos o desconto
+	// Detalhamento do desconto por promoção
+	Promotions AppliedPromotions `json:"promotions" gorm:"type:jsonb"`
+	// Componentes consumidos e receita rateada, quando o item é um kit
+	Components []SaleItemComponent `json:"components,omitempty"`
 
 	// Relacionamentos
 	Sale    Sale    `json:"sale,omitempty"`
@@ -43,7 +61,7 @@ type SaleItem struct {
 type SaleCreate struct {
 	CustomerID    uint             `json:"customer_id"`
 	PaymentMethod string           `json:"payment_method" binding:"required"`
-	Discount      float64          `json:"discount"`
+	Discount      money.Amount     `json:"discount"`
 	Notes         string           `json:"notes"`
 	Items         []SaleItemCreate `json:"items" binding:"required,dive"`
 }
@@ -60,18 +78,23 @@ type SaleUpdate struct {
 }
 
 type SalesReport struct {
-	Period      string  `json:"period"`
-	TotalSales  int     `json:"total_sales"`
-	TotalAmount float64 `json:"total_amount"`
+	Period      string       `json:"period"`
+	TotalSales  int          `json:"total_sales"`
+	TotalAmount money.Amount `json:"total_amount"`
 	TopProducts []struct {
-		ProductName string  `json:"product_name"`
-		Quantity    int     `json:"quantity"`
-		Revenue     float64 `json:"revenue"`
+		ProductName string       `json:"product_name"`
+		Quantity    int          `json:"quantity"`
+		Revenue     money.Amount `json:"revenue"`
 	} `json:"top_products"`
 	SalesByStatus map[string]int `json:"sales_by_status"`
 	SalesByMonth  []struct {
-		Month  string  `json:"month"`
-		Count  int     `json:"count"`
-		Amount float64 `json:"amount"`
+		Month  string       `json:"month"`
+		Count  int          `json:"count"`
+		Amount money.Amount `json:"amount"`
 	} `json:"sales_by_month"`
 }
+
+// IsVoidStatus indica se a situação anula a venda (cancelada ou devolvida)
+func IsVoidStatus(status string) bool {
+	return status == SaleStatusCancelled || status == SaleStatusReturned
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Session representa uma sessão de login com refresh token rotativo
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	RefreshTokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	PreviousTokenHash string     `json:"-" gorm:"index"` // Detecta reutilização de refresh token já rotacionado
+	StoreID           *uint      `json:"store_id"`       // Loja ativa, enviada no token de acesso
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Relacionamentos
+	User User `json:"-"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// IsActive indica se a sessão ainda pode ser utilizada
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// SigningKey é uma chave assimétrica usada para assinar os tokens de acesso.
+// A chave mais recente não aposentada assina; as aposentadas continuam
+// publicadas no JWKS até que os tokens assinados por elas expirem.
+type SigningKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	KID        string     `json:"kid" gorm:"uniqueIndex;not null"`
+	Algorithm  string     `json:"algorithm" gorm:"not null"`   // RS256 ou EdDSA
+	PrivateKey string     `json:"-" gorm:"type:text;not null"` // PKCS#8 em PEM
+	RetiredAt  *time.Time `json:"retired_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Store é uma loja física. Estoque, movimentos e vendas pertencem a uma loja;
+// o catálogo de produtos e os clientes são compartilhados.
+type Store struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"not null"`
+	Code      string         `json:"code" gorm:"uniqueIndex;not null"` // Identificador curto, ex.: "centro"
+	Address   string         `json:"address"`
+	Phone     string         `json:"phone"`
+	Active    bool           `json:"active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type StoreCreate struct {
+	Name    string `json:"name" binding:"required"`
+	Code    string `json:"code" binding:"required,max=30"`
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+}
+
+type StoreUpdate struct {
+	Name    *string `json:"name"`
+	Address *string `json:"address"`
+	Phone   *string `json:"phone"`
+	Active  *bool   `json:"active"`
+}
+
+// UserStoresUpdate substitui as lojas às quais um usuário tem acesso
+type UserStoresUpdate struct {
+	StoreIDs []uint `json:"store_ids" binding:"required"`
+}
+
+// StoreSelect troca a loja ativa da sessão
+type StoreSelect struct {
+	StoreID uint `json:"store_id"`
+}
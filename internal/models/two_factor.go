@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode é um código de recuperação de uso único para a autenticação
+// em dois fatores
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorDisable struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TwoFactorLogin conclui o login em duas etapas com o código do aplicativo
+// autenticador ou com um código de recuperação
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
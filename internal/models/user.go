@@ -18,8 +18,23 @@ type User struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Autenticação em dois fatores (TOTP)
+	TwoFactorEnabled  bool   `json:"two_factor_enabled" gorm:"default:false"`
+	TwoFactorSecret   string `json:"-"`
+	TwoFactorLastStep int64  `json:"-" gorm:"default:0"` // Último código aceito, evita reutilização
+
+	// Identidade no provedor OIDC (claim "sub"), vinculada no primeiro login único
+	OIDCSubject *string `json:"-" gorm:"uniqueIndex"`
+
+	// Proteção contra força bruta
+	FailedLoginAttempts int        `json:"failed_login_attempts" gorm:"default:0"`
+	LockedUntil         *time.Time `json:"locked_until"`
+
 	// Permissões por módulo
 	Permissions UserPermissions `json:"permissions" gorm:"embedded"`
+
+	// Lojas às quais o usuário tem acesso (administradores acessam todas)
+	Stores []Store `json:"stores,omitempty" gorm:"many2many:user_stores"`
 }
 
 type UserPermissions struct {
@@ -29,6 +44,33 @@ type UserPermissions struct {
 	Sales     bool `json:"sales" gorm:"default:false"`     // Controle de vendas
 	Reports   bool `json:"reports" gorm:"default:false"`   // Relatórios
 	Users     bool `json:"users" gorm:"default:false"`     // Controle de usuários
+
+	// Capacidades detalhadas ("products:read", "sales:cancel", ...). Quando
+	// presentes, determinam o acesso; os flags acima indicam apenas se há
+	// alguma capacidade no módulo.
+	Capabilities Capabilities `json:"capabilities" gorm:"type:jsonb"`
+}
+
+// UserCreate são os dados aceitos na criação de um usuário. Contas de
+// serviço não fazem login por senha e dispensam password.
+type UserCreate struct {
+	Name        string          `json:"name" binding:"required"`
+	Email       string          `json:"email" binding:"required,email"`
+	Password    string          `json:"password" binding:"required_unless=Role service,omitempty,min=6"`
+	Role        string          `json:"role" binding:"required,oneof=admin manager user service"`
+	Active      *bool           `json:"active"`
+	Permissions UserPermissions `json:"permissions"`
+}
+
+// UserUpdate são os campos alteráveis de um usuário; os ausentes não mudam e
+// permissions substitui as permissões por completo
+type UserUpdate struct {
+	Name        *string          `json:"name"`
+	Email       *string          `json:"email" binding:"omitempty,email"`
+	Password    *string          `json:"password" binding:"omitempty,min=6"`
+	Role        *string          `json:"role" binding:"omitempty,oneof=admin manager user service"`
+	Active      *bool            `json:"active"`
+	Permissions *UserPermissions `json:"permissions"`
 }
 
 type UserLogin struct {
@@ -55,3 +97,41 @@ func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// Módulos controlados por UserPermissions
+const (
+	ModuleProducts  = "products"
+	ModuleCustomers = "customers"
+	ModuleInventory = "inventory"
+	ModuleSales     = "sales"
+	ModuleReports   = "reports"
+	ModuleUsers     = "users"
+)
+
+// IsAdmin indica se o usuário possui o papel de administrador
+func (u *User) IsAdmin() bool {
+	return u.Role == "admin"
+}
+
+// IsLocked indica se a conta está temporariamente bloqueada
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// IsPrivileged indica se o usuário é administrador ou gerente
+func (u *User) IsPrivileged() bool {
+	return IsPrivilegedRole(u.Role)
+}
+
+// IsPrivilegedRole indica se o papel é de administrador ou gerente
+func IsPrivilegedRole(role string) bool {
+	return role == "admin" || role == "manager"
+}
+
+// Allows verifica se o usuário tem alguma capacidade no módulo informado
+func (p UserPermissions) Allows(module string) bool {
+	if p.Capabilities == nil {
+		return p.moduleFlag(module)
+	}
+	return p.hasModule(module)
+}
@@ -0,0 +1,250 @@
+// Package money representa valores em reais com precisão exata de centavos.
+// Os cálculos são feitos em inteiros e arredondados para o centavo mais
+// próximo, com a metade arredondada para longe do zero (1,005 → 1,01).
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount é um valor em reais guardado em centavos. No JSON é um número
+// decimal (49.9) e no banco uma coluna numeric(12,2).
+type Amount int64
+
+// ErrInvalid indica um texto que não é um valor monetário
+var ErrInvalid = errors.New("valor monetário inválido")
+
+// FromCents cria o valor a partir dos centavos
+func FromCents(cents int64) Amount {
+	return Amount(cents)
+}
+
+// FromFloat converte um valor em reais, arredondando para centavos pela
+// representação decimal do número, para 1.005 virar 1,01 e não 1,00
+func FromFloat(value float64) Amount {
+	amount, _ := parseRat(strconv.FormatFloat(value, 'f', -1, 64))
+	return amount
+}
+
+// Parse aceita 49.90, 49,90, 1.234,56 e R$ 1.234,56. Com vírgula, os pontos
+// são separadores de milhar; sem ela, o ponto é o separador decimal.
+func Parse(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimSpace(strings.TrimPrefix(value, "-"))
+	value = strings.TrimSpace(strings.TrimPrefix(value, "R$"))
+	if strings.Contains(value, ",") {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	if value == "" || strings.ContainsAny(value, "+-/") {
+		return 0, ErrInvalid
+	}
+
+	amount, err := parseRat(value)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// Cents retorna o valor em centavos
+func (a Amount) Cents() int64 {
+	return int64(a)
+}
+
+// Float64 retorna o valor em reais, para cálculos que não são monetários,
+// como percentuais
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+// Mul multiplica o valor pela quantidade
+func (a Amount) Mul(quantity int) Amount {
+	return a * Amount(quantity)
+}
+
+// Fraction retorna numerator/denominator do valor, arredondado para centavos
+func (a Amount) Fraction(numerator, denominator int64) Amount {
+	if denominator == 0 {
+		return 0
+	}
+	return roundRat(new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(numerator)),
+		big.NewInt(denominator),
+	))
+}
+
+// Allocate divide o valor em partes proporcionais aos pesos, que não podem
+// ser negativos. As partes somam exatamente o valor: os centavos que sobram
+// do arredondamento vão para as maiores frações, empatadas pela ordem. Sem
+// peso nenhum, a divisão é igual.
+func (a Amount) Allocate(weights []Amount) []Amount {
+	parts := make([]Amount, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	total := new(big.Int)
+	for _, weight := range weights {
+		total.Add(total, big.NewInt(int64(weight)))
+	}
+	if total.Sign() == 0 {
+		equal := make([]Amount, len(weights))
+		for i := range equal {
+			equal[i] = 1
+		}
+		return a.Allocate(equal)
+	}
+
+	sign, value := Amount(1), a
+	if value < 0 {
+		sign, value = -1, -value
+	}
+
+	remainders := make([]*big.Int, len(weights))
+	allocated := Amount(0)
+	for i, weight := range weights {
+		share, remainder := new(big.Int).QuoRem(
+			new(big.Int).Mul(big.NewInt(int64(value)), big.NewInt(int64(weight))), total, new(big.Int))
+		parts[i] = Amount(share.Int64())
+		remainders[i] = remainder
+		allocated += parts[i]
+	}
+
+	for left := value - allocated; left > 0; left-- {
+		best := -1
+		for i, remainder := range remainders {
+			if remainder.Sign() > 0 && (best < 0 || remainder.Cmp(remainders[best]) > 0) {
+				best = i
+			}
+		}
+		parts[best]++
+		remainders[best].SetInt64(0)
+	}
+
+	for i := range parts {
+		parts[i] *= sign
+	}
+	return parts
+}
+
+// String retorna o valor com ponto e duas casas decimais: 1234.50
+func (a Amount) String() string {
+	sign, cents := "", int64(a)
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Format formata o valor em reais: R$ 1.234,50
+func (a Amount) Format() string {
+	sign, cents := "", int64(a)
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	units := strconv.FormatInt(cents/100, 10)
+	var grouped strings.Builder
+	for i, r := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(r)
+	}
+	return fmt.Sprintf("%sR$ %s,%02d", sign, grouped.String(), cents%100)
+}
+
+// MarshalJSON grava o valor como número decimal
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON aceita um número ou um texto no formato de Parse
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if value == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		parsed, err := Parse(unquoted)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	}
+
+	parsed, err := parseRat(value)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value implementa driver.Valuer
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan implementa sql.Scanner. O banco guarda reais: numeric, que chega como
+// texto, ou inteiros e double precision de colunas ainda não migradas.
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanText(string(v))
+	case string:
+		return a.scanText(v)
+	case int64:
+		*a = Amount(v * 100)
+		return nil
+	case float64:
+		*a = FromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("tipo incompatível com money.Amount: %T", value)
+}
+
+func (a *Amount) scanText(value string) error {
+	parsed, err := parseRat(value)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// parseRat converte um número decimal (49.90, 1e2) em centavos
+func parseRat(value string) (Amount, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return 0, ErrInvalid
+	}
+	return roundRat(rat.Mul(rat, big.NewRat(100, 1))), nil
+}
+
+// roundRat arredonda um valor em centavos para o inteiro mais próximo, com a
+// metade para longe do zero
+func roundRat(cents *big.Rat) Amount {
+	numerator := new(big.Int).Abs(cents.Num())
+	quotient, remainder := new(big.Int).QuoRem(numerator, cents.Denom(), new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(cents.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if cents.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return Amount(quotient.Int64())
+}
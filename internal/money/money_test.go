@@ -0,0 +1,185 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Amount
+		ok    bool
+	}{
+		{"49.90", 4990, true},
+		{"49,9", 4990, true},
+		{"1.234,56", 123456, true},
+		{"R$ 1.234,56", 123456, true},
+		{"-R$ 0,50", -50, true},
+		{"10", 1000, true},
+		{"1,005", 101, true},
+		{"0.004", 0, true},
+		{"", 0, false},
+		{"abc", 0, false},
+		{"1/3", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v; esperado %v, ok=%v", tt.input, got.Cents(), err, tt.want.Cents(), tt.ok)
+		}
+	}
+}
+
+func TestFromFloatRoundsHalfAwayFromZero(t *testing.T) {
+	tests := map[float64]Amount{
+		1.005:  101,
+		2.675:  268,
+		-1.005: -101,
+		0.1:    10,
+		59.9:   5990,
+	}
+	for value, want := range tests {
+		if got := FromFloat(value); got != want {
+			t.Errorf("FromFloat(%v) = %d, esperado %d", value, got, want)
+		}
+	}
+}
+
+func TestSumHasNoDrift(t *testing.T) {
+	var total Amount
+	for i := 0; i < 1000; i++ {
+		total += FromFloat(0.1)
+	}
+	if total != FromCents(10000) {
+		t.Fatalf("soma = %s, esperado 100.00", total)
+	}
+}
+
+func TestPercentAndFraction(t *testing.T) {
+	if got := FromCents(1250).Percent(BasisPoints(410)); got != 51 {
+		t.Errorf("4,1%% de 12,50 = %d centavos, esperado 51", got)
+	}
+	if got := FromCents(1005).Percent(BasisPoints(10_00)); got != 101 {
+		t.Errorf("10%% de 10,05 = %d centavos, esperado 101", got)
+	}
+	if got := FromCents(10000).Fraction(1, 3); got != 3333 {
+		t.Errorf("1/3 de 100,00 = %d centavos, esperado 3333", got)
+	}
+	if got := FromCents(-1000).Fraction(1, 8); got != -125 {
+		t.Errorf("1/8 de -10,00 = %d centavos, esperado -125", got)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  Amount
+		weights []Amount
+		want    []Amount
+	}{
+		{"proporcional exato", 18000, []Amount{10000, 10000}, []Amount{9000, 9000}},
+		{"sobra vai para a maior fração", 10000, []Amount{1, 1, 1}, []Amount{3334, 3333, 3333}},
+		{"pesos diferentes", 1000, []Amount{5990, 3990, 1990}, []Amount{501, 333, 166}},
+		{"sem pesos divide igual", 100, []Amount{0, 0, 0}, []Amount{34, 33, 33}},
+		{"negativo", -10000, []Amount{1, 1, 1}, []Amount{-3334, -3333, -3333}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Allocate(tt.weights)
+			var sum Amount
+			for i := range got {
+				sum += got[i]
+				if got[i] != tt.want[i] {
+					t.Fatalf("Allocate = %v, esperado %v", got, tt.want)
+				}
+			}
+			if sum != tt.amount {
+				t.Fatalf("partes somam %d, esperado %d", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[Amount]string{
+		0:         "R$ 0,00",
+		990:       "R$ 9,90",
+		12999:     "R$ 129,99",
+		123450:    "R$ 1.234,50",
+		123456780: "R$ 1.234.567,80",
+		-50:       "-R$ 0,50",
+	}
+	for value, want := range tests {
+		if got := value.Format(); got != want {
+			t.Errorf("Format(%d) = %q, esperado %q", value, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var payload struct {
+		Price    Amount  `json:"price"`
+		Cost     Amount  `json:"cost"`
+		Discount *Amount `json:"discount"`
+	}
+	if err := json.Unmarshal([]byte(`{"price": 59.9, "cost": "R$ 1.234,50", "discount": null}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Price != 5990 || payload.Cost != 123450 || payload.Discount != nil {
+		t.Fatalf("valores lidos: %+v", payload)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":59.90,"cost":1234.50,"discount":null}` {
+		t.Fatalf("JSON gerado: %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"price": "abc"}`), &payload); err == nil {
+		t.Fatal("texto inválido deveria falhar")
+	}
+}
+
+func TestRateJSON(t *testing.T) {
+	var payload struct {
+		Rate  Rate `json:"rate"`
+		Text  Rate `json:"text"`
+		Whole Rate `json:"whole"`
+	}
+	if err := json.Unmarshal([]byte(`{"rate": 12.5, "text": "4,1%", "whole": 100}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Rate != BasisPoints(1250) || payload.Text != BasisPoints(410) || payload.Whole != BasisPoints(100_00) {
+		t.Fatalf("percentuais lidos: %+v", payload)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"rate":12.50,"text":4.10,"whole":100.00}` {
+		t.Fatalf("JSON gerado: %s", data)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  Amount
+	}{
+		{"49.90", 4990},
+		{[]byte("1234.5"), 123450},
+		{59.9, 5990},
+		{int64(12), 1200},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if err := got.Scan(tt.value); err != nil || got != tt.want {
+			t.Errorf("Scan(%#v) = %d, %v; esperado %d", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rate é um percentual guardado em pontos-base, centésimos de ponto
+// percentual: 12,5% é 1250. No JSON é um número decimal (12.5) e no banco
+// uma coluna numeric(5,2). Comissões e descontos percentuais usam Rate; o
+// valor em reais a que ele se aplica continua sendo Amount.
+type Rate int64
+
+// BasisPoints cria o percentual a partir dos pontos-base
+func BasisPoints(bp int64) Rate {
+	return Rate(bp)
+}
+
+// ParseRate aceita 12.5, 12,5 e 12,5%
+func ParseRate(value string) (Rate, error) {
+	amount, err := Parse(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if err != nil {
+		return 0, err
+	}
+	return Rate(amount), nil
+}
+
+// BasisPoints retorna o percentual em pontos-base
+func (r Rate) BasisPoints() int64 {
+	return int64(r)
+}
+
+// Float64 retorna o percentual como número, para exibição
+func (r Rate) Float64() float64 {
+	return float64(r) / 100
+}
+
+// String retorna o percentual com ponto e duas casas decimais: 12.50
+func (r Rate) String() string {
+	return Amount(r).String()
+}
+
+// Percent retorna rate do valor, arredondado para centavos
+func (a Amount) Percent(rate Rate) Amount {
+	return a.Fraction(int64(rate), 100_00)
+}
+
+// MarshalJSON grava o percentual como número decimal
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON aceita um número ou um texto no formato de ParseRate
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if value == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	parsed, err := ParseRate(value)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value implementa driver.Valuer
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Scan implementa sql.Scanner. Como em Amount, aceita numeric e as colunas
+// ainda em double precision.
+func (r *Rate) Scan(value interface{}) error {
+	var amount Amount
+	if err := amount.Scan(value); err != nil {
+		return fmt.Errorf("tipo incompatível com money.Rate: %T", value)
+	}
+	*r = Rate(amount)
+	return nil
+}
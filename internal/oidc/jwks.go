@@ -0,0 +1,140 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// jwk é uma chave pública no formato JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet guarda as chaves do provedor e as recarrega quando aparece um kid
+// desconhecido, o que acompanha a rotação de chaves do emissor
+type keySet struct {
+	uri     string
+	getJSON func(ctx context.Context, rawURL string, dest interface{}) error
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// Intervalo mínimo entre recargas, para que tokens com kid inválido não
+// provoquem uma requisição ao provedor a cada chamada
+const minRefreshInterval = time.Minute
+
+func newKeySet(uri string, getJSON func(ctx context.Context, rawURL string, dest interface{}) error) *keySet {
+	return &keySet{uri: uri, getJSON: getJSON}
+}
+
+// get retorna a chave pública do kid informado
+func (s *keySet) get(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(s.fetchedAt) < minRefreshInterval && s.keys != nil {
+		return nil, fmt.Errorf("chave %q não encontrada", kid)
+	}
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("chave %q não encontrada", kid)
+}
+
+// lookup procura a chave; sem kid, aceita apenas um conjunto com chave única
+func (s *keySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := s.getJSON(ctx, s.uri, &document); err != nil {
+		return fmt.Errorf("erro ao carregar JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if publicKey, err := key.publicKey(); err == nil {
+			keys[key.Kid] = publicKey
+		}
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+// publicKey converte a JWK em chave RSA ou ECDSA
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curva não suportada: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errors.New("tipo de chave não suportado: " + k.Kty)
+}
@@ -0,0 +1,217 @@
+// Package oidc implementa o fluxo authorization code do OpenID Connect
+// (descoberta, troca do código com PKCE e validação do ID token via JWKS).
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config reúne os dados do cliente registrado no provedor
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// discovery é o subconjunto usado de /.well-known/openid-configuration
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims são as informações do usuário extraídas do ID token
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	HostedDomain  string // "hd" do Google Workspace
+}
+
+// Provider é um cliente OIDC. A descoberta é feita sob demanda para que a
+// aplicação suba mesmo com o provedor indisponível.
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	metadata *discovery
+	keys     *keySet
+}
+
+// NewProvider cria um cliente OIDC para o emissor configurado
+func NewProvider(config Config) *Provider {
+	return &Provider{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// discover carrega e guarda os metadados do emissor
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	var metadata discovery
+	if err := p.getJSON(ctx, wellKnown, &metadata); err != nil {
+		return nil, fmt.Errorf("erro na descoberta OIDC: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return nil, fmt.Errorf("emissor divergente na descoberta: %s", metadata.Issuer)
+	}
+
+	p.metadata = &metadata
+	p.keys = newKeySet(metadata.JWKSURI, p.getJSON)
+	return p.metadata, nil
+}
+
+// AuthCodeURL monta a URL de autorização com state, nonce e desafio PKCE (S256)
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.config.ClientID)
+	v.Set("redirect_uri", p.config.RedirectURL)
+	v.Set("scope", strings.Join(p.config.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	v.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + v.Encode(), nil
+}
+
+// Exchange troca o código de autorização e retorna as claims do ID token validado
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("client_secret", p.config.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao trocar código: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provedor recusou o código (%d): %s", resp.StatusCode, body)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.IDToken == "" {
+		return nil, errors.New("resposta do provedor sem id_token")
+	}
+
+	return p.verifyIDToken(ctx, metadata, token.IDToken, nonce)
+}
+
+// verifyIDToken valida assinatura, emissor, audiência, expiração e nonce
+func (p *Provider) verifyIDToken(ctx context.Context, metadata *discovery, raw, nonce string) (*Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.get(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("id_token inválido: %w", err)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("id_token com nonce inválido")
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	result.HostedDomain, _ = claims["hd"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	if result.Subject == "" {
+		return nil, errors.New("id_token sem sub")
+	}
+	return result, nil
+}
+
+// getJSON faz um GET e decodifica a resposta JSON
+func (p *Provider) getJSON(ctx context.Context, rawURL string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s respondeu %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(dest)
+}
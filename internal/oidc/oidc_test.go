@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider simula um provedor OIDC com descoberta, JWKS e token endpoint
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	// Ajustes do próximo ID token emitido
+	audience string
+	nonce    string
+	email    string
+
+	// Dados recebidos na última chamada ao token endpoint
+	challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key, kid: "chave-1", audience: "loja", email: "ana@loja.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": m.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "codigo-valido" || base64.RawURLEncoding.EncodeToString(verifier[:]) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken(t)})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) idToken(t *testing.T) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            m.audience,
+		"sub":            "123456",
+		"email":          m.email,
+		"email_verified": true,
+		"name":           "Ana",
+		"nonce":          m.nonce,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+	})
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// authorize simula o redirecionamento do navegador e guarda o desafio PKCE
+func (m *mockProvider) authorize(t *testing.T, p *Provider, nonce, verifier string) {
+	t.Helper()
+
+	authURL, err := p.AuthCodeURL(context.Background(), "estado", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	if query.Get("state") != "estado" || query.Get("nonce") != nonce || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("URL de autorização incompleta: %s", authURL)
+	}
+	m.challenge = query.Get("code_challenge")
+	m.nonce = query.Get("nonce")
+}
+
+func newTestProvider(m *mockProvider) *Provider {
+	return NewProvider(Config{
+		Issuer:       m.server.URL,
+		ClientID:     "loja",
+		ClientSecret: "segredo",
+		RedirectURL:  "http://localhost:8080/api/v1/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	})
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	m.authorize(t, p, "nonce-1", "verificador")
+
+	claims, err := p.Exchange(context.Background(), "codigo-valido", "verificador", "nonce-1")
+	if err != nil {
+		t.Fatalf("troca do código falhou: %v", err)
+	}
+	if claims.Subject != "123456" || claims.Email != "ana@loja.com" || !claims.EmailVerified || claims.Name != "Ana" {
+		t.Errorf("claims inesperadas: %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	m.authorize(t, p, "nonce-1", "verificador")
+
+	if _, err := p.Exchange(context.Background(), "codigo-valido", "outro", "nonce-1"); err == nil {
+		t.Error("esperado erro com verificador PKCE diferente")
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	m.authorize(t, p, "nonce-1", "verificador")
+
+	if _, err := p.Exchange(context.Background(), "codigo-valido", "verificador", "nonce-2"); err == nil {
+		t.Error("esperado erro com nonce diferente")
+	}
+}
+
+func TestExchangeRejectsWrongAudience(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	m.authorize(t, p, "nonce-1", "verificador")
+	m.audience = "outro-cliente"
+
+	if _, err := p.Exchange(context.Background(), "codigo-valido", "verificador", "nonce-1"); err == nil {
+		t.Error("esperado erro com audiência de outro cliente")
+	}
+}
+
+func TestExchangeFollowsKeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	p := newTestProvider(m)
+	m.authorize(t, p, "nonce-1", "verificador")
+
+	if _, err := p.Exchange(context.Background(), "codigo-valido", "verificador", "nonce-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// O provedor troca a chave; o kid novo força a recarga do JWKS
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.key, m.kid = key, "chave-2"
+	p.keys.fetchedAt = time.Time{}
+
+	if _, err := p.Exchange(context.Background(), "codigo-valido", "verificador", "nonce-1"); err != nil {
+		t.Errorf("troca após rotação falhou: %v", err)
+	}
+}
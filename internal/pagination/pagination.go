@@ -0,0 +1,323 @@
+// Package pagination implementa a ordenação e a paginação das listagens da
+// API, por deslocamento (limit e offset) ou por cursor, com as mesmas
+// convenções de parâmetros e de resposta em todos os recursos.
+//
+// Parâmetros aceitos:
+//   - sort: campos separados por vírgula; o prefixo "-" ordena de forma
+//     decrescente (ex.: "-price,name")
+//   - limit: itens por página
+//   - offset: itens a pular (paginação por deslocamento)
+//   - cursor: next_cursor ou prev_cursor de uma resposta anterior; quando
+//     presente, offset é ignorado
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// ErrInvalidCursor indica um cursor malformado ou de outra ordenação
+var ErrInvalidCursor = errors.New("cursor inválido")
+
+// Field é um campo ordenável: a expressão SQL, que não pode resultar em
+// NULL, e o valor da linha usado no cursor
+type Field[T any] struct {
+	Column string
+	Value  func(*T) any
+}
+
+// Spec define os campos ordenáveis de um recurso e a ordenação padrão. O
+// campo "id" é sempre usado como desempate e deve estar em Fields.
+type Spec[T any] struct {
+	Fields      map[string]Field[T]
+	DefaultSort string
+}
+
+// sortField é um campo da ordenação pedida
+type sortField struct {
+	name string
+	desc bool
+}
+
+// cursor guarda os valores da linha de referência, em texto, e a direção
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	Before bool     `json:"b,omitempty"`
+}
+
+// Page é a página pedida na requisição
+type Page[T any] struct {
+	spec   *Spec[T]
+	url    url.URL
+	sort   []sortField
+	Limit  int
+	Offset int
+	cursor *cursor
+	// Quando há uma linha além do limite
+	hasMore bool
+}
+
+// Parse lê sort, limit, offset e cursor da URL da requisição
+func Parse[T any](u *url.URL, spec *Spec[T]) (*Page[T], error) {
+	values := u.Query()
+	page := &Page[T]{spec: spec, url: *u, Limit: defaultLimit}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, errors.New("limit deve ser um número positivo")
+		}
+		page.Limit = min(limit, maxLimit)
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	sort, err := parseSort(sortParam, spec)
+	if err != nil {
+		return nil, err
+	}
+	page.sort = sort
+
+	if values.Has("cursor") && values.Get("cursor") != "" {
+		cur, err := decodeCursor(values.Get("cursor"))
+		if err != nil || cur.Sort != page.sortString() || len(cur.Values) != len(page.sort) {
+			return nil, ErrInvalidCursor
+		}
+		page.cursor = cur
+		return page, nil
+	}
+
+	if value := values.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return nil, errors.New("offset deve ser um número maior ou igual a zero")
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+// parseSort valida os campos pedidos e acrescenta o id como desempate
+func parseSort[T any](value string, spec *Spec[T]) ([]sortField, error) {
+	var fields []sortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := sortField{name: strings.TrimPrefix(part, "-"), desc: strings.HasPrefix(part, "-")}
+		if _, ok := spec.Fields[field.name]; !ok {
+			return nil, fmt.Errorf("não é possível ordenar por %s", field.name)
+		}
+		if seen[field.name] {
+			continue
+		}
+		seen[field.name] = true
+		fields = append(fields, field)
+	}
+	if !seen["id"] {
+		fields = append(fields, sortField{name: "id"})
+	}
+	return fields, nil
+}
+
+// Apply aplica a ordenação, a condição do cursor e o limite na consulta, que
+// já deve conter os filtros. Busca uma linha a mais para saber se há próxima página.
+func (p *Page[T]) Apply(query *gorm.DB) *gorm.DB {
+	before := p.cursor != nil && p.cursor.Before
+
+	if p.cursor != nil {
+		condition, args := p.keysetCondition(before)
+		query = query.Where(condition, args...)
+	}
+
+	for _, field := range p.sort {
+		// Para buscar a página anterior, a ordem é invertida e depois desfeita
+		desc := field.desc != before
+		direction := " ASC"
+		if desc {
+			direction = " DESC"
+		}
+		query = query.Order(p.spec.Fields[field.name].Column + direction)
+	}
+
+	query = query.Limit(p.Limit + 1)
+	if p.cursor == nil && p.Offset > 0 {
+		query = query.Offset(p.Offset)
+	}
+	return query
+}
+
+// keysetCondition monta (a > x) OR (a = x AND b > y) OR ... para as linhas
+// depois (ou antes) da linha do cursor
+func (p *Page[T]) keysetCondition(before bool) (string, []any) {
+	var clauses []string
+	var args []any
+	for i, field := range p.sort {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, p.spec.Fields[p.sort[j].name].Column+" = ?")
+			args = append(args, p.cursor.Values[j])
+		}
+		operator := " > ?"
+		if field.desc != before {
+			operator = " < ?"
+		}
+		parts = append(parts, p.spec.Fields[field.name].Column+operator)
+		args = append(args, p.cursor.Values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// Trim remove a linha extra buscada por Apply e restaura a ordem pedida
+func (p *Page[T]) Trim(rows []T) []T {
+	p.hasMore = len(rows) > p.Limit
+	if p.hasMore {
+		rows = rows[:p.Limit]
+	}
+	if p.cursor != nil && p.cursor.Before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows
+}
+
+// Meta descreve a página na resposta
+type Meta struct {
+	Total      int64   `json:"total"`
+	Limit      int     `json:"limit"`
+	Offset     *int    `json:"offset,omitempty"`
+	Sort       string  `json:"sort"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+	Next       *string `json:"next"`
+	Prev       *string `json:"prev"`
+}
+
+// Meta monta os totais, os cursores e os links da página já recortada por Trim
+func (p *Page[T]) Meta(rows []T, total int64) Meta {
+	meta := Meta{Total: total, Limit: p.Limit, Sort: p.sortString()}
+
+	var hasNext, hasPrev bool
+	switch {
+	case p.cursor == nil:
+		hasNext = p.hasMore
+		hasPrev = p.Offset > 0
+	case p.cursor.Before:
+		hasNext = true
+		hasPrev = p.hasMore
+	default:
+		hasNext = p.hasMore
+		hasPrev = true
+	}
+
+	if len(rows) > 0 {
+		if hasNext {
+			next := p.encodeCursor(&rows[len(rows)-1], false)
+			meta.NextCursor = &next
+		}
+		if hasPrev {
+			prev := p.encodeCursor(&rows[0], true)
+			meta.PrevCursor = &prev
+		}
+	}
+
+	if p.cursor == nil {
+		offset := p.Offset
+		meta.Offset = &offset
+		if hasNext {
+			meta.Next = p.link(map[string]string{"offset": strconv.Itoa(p.Offset + p.Limit)})
+		}
+		if hasPrev {
+			meta.Prev = p.link(map[string]string{"offset": strconv.Itoa(max(p.Offset-p.Limit, 0))})
+		}
+		return meta
+	}
+
+	if meta.NextCursor != nil {
+		meta.Next = p.link(map[string]string{"cursor": *meta.NextCursor})
+	}
+	if meta.PrevCursor != nil {
+		meta.Prev = p.link(map[string]string{"cursor": *meta.PrevCursor})
+	}
+	return meta
+}
+
+// link repete a URL da requisição, com os mesmos filtros, trocando a posição
+func (p *Page[T]) link(set map[string]string) *string {
+	values := p.url.Query()
+	values.Del("offset")
+	values.Del("cursor")
+	values.Set("limit", strconv.Itoa(p.Limit))
+	for key, value := range set {
+		values.Set(key, value)
+	}
+
+	u := url.URL{Path: p.url.Path, RawQuery: values.Encode()}
+	link := u.String()
+	return &link
+}
+
+func (p *Page[T]) encodeCursor(row *T, before bool) string {
+	cur := cursor{Sort: p.sortString(), Before: before}
+	for _, field := range p.sort {
+		cur.Values = append(cur.Values, formatValue(p.spec.Fields[field.name].Value(row)))
+	}
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cur cursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// formatValue converte o valor do campo para o texto gravado no cursor, que
+// volta para a consulta como parâmetro
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (p *Page[T]) sortString() string {
+	parts := make([]string, len(p.sort))
+	for i, field := range p.sort {
+		parts[i] = field.name
+		if field.desc {
+			parts[i] = "-" + field.name
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,133 @@
+package pagination
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type item struct {
+	ID    uint
+	Name  string
+	Price float64
+}
+
+var spec = &Spec[item]{
+	DefaultSort: "id",
+	Fields: map[string]Field[item]{
+		"id":    {Column: "id", Value: func(i *item) any { return i.ID }},
+		"name":  {Column: "name", Value: func(i *item) any { return i.Name }},
+		"price": {Column: "price", Value: func(i *item) any { return i.Price }},
+	},
+}
+
+func parse(t *testing.T, rawURL string) *Page[item] {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := Parse(u, spec)
+	if err != nil {
+		t.Fatalf("Parse(%q) = %v", rawURL, err)
+	}
+	return page
+}
+
+func items(ids ...uint) []item {
+	rows := make([]item, len(ids))
+	for i, id := range ids {
+		rows[i] = item{ID: id, Name: "produto", Price: float64(id) * 10}
+	}
+	return rows
+}
+
+func TestParseValidatesParameters(t *testing.T) {
+	for _, rawURL := range []string{
+		"/products?sort=stock",
+		"/products?limit=0",
+		"/products?limit=abc",
+		"/products?offset=-1",
+		"/products?cursor=invalido",
+	} {
+		u, _ := url.Parse(rawURL)
+		if _, err := Parse(u, spec); err == nil {
+			t.Errorf("Parse(%q) aceitou parâmetros inválidos", rawURL)
+		}
+	}
+
+	page := parse(t, "/products?limit=1000&sort=-price,name")
+	if page.Limit != maxLimit {
+		t.Errorf("Limit = %d, esperado %d", page.Limit, maxLimit)
+	}
+	if got := page.sortString(); got != "-price,name,id" {
+		t.Errorf("sort = %q, esperado id como desempate", got)
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	page := parse(t, "/products?sort=-price")
+	page.cursor = &cursor{Sort: "-price,id", Values: []string{"99.9", "7"}}
+
+	condition, args := page.keysetCondition(false)
+	if want := "((price < ?) OR (price = ? AND id > ?))"; condition != want {
+		t.Errorf("condição = %q, esperado %q", condition, want)
+	}
+	if want := []any{"99.9", "99.9", "7"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, esperado %v", args, want)
+	}
+
+	condition, _ = page.keysetCondition(true)
+	if want := "((price > ?) OR (price = ? AND id < ?))"; condition != want {
+		t.Errorf("condição anterior = %q, esperado %q", condition, want)
+	}
+}
+
+func TestOffsetLinks(t *testing.T) {
+	page := parse(t, "/api/v1/products?brand=nike&limit=2&offset=2")
+	rows := page.Trim(items(3, 4, 5))
+	meta := page.Meta(rows, 10)
+
+	if len(rows) != 2 || meta.Total != 10 || *meta.Offset != 2 {
+		t.Fatalf("página = %v, meta = %+v", rows, meta)
+	}
+	if want := "/api/v1/products?brand=nike&limit=2&offset=4"; meta.Next == nil || *meta.Next != want {
+		t.Errorf("next = %v, esperado %q", meta.Next, want)
+	}
+	if want := "/api/v1/products?brand=nike&limit=2&offset=0"; meta.Prev == nil || *meta.Prev != want {
+		t.Errorf("prev = %v, esperado %q", meta.Prev, want)
+	}
+
+	last := parse(t, "/api/v1/products?limit=2")
+	lastRows := last.Trim(items(1, 2))
+	if meta := last.Meta(lastRows, 2); meta.Next != nil || meta.Prev != nil {
+		t.Errorf("página única com links: %+v", meta)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	first := parse(t, "/products?sort=-price&limit=2")
+	meta := first.Meta(first.Trim(items(9, 8, 7)), 3)
+	if meta.NextCursor == nil || meta.PrevCursor != nil {
+		t.Fatalf("primeira página: %+v", meta)
+	}
+
+	next := parse(t, "/products?sort=-price&limit=2&cursor="+*meta.NextCursor)
+	if want := []string{"80", "8"}; !reflect.DeepEqual(next.cursor.Values, want) || next.cursor.Before {
+		t.Fatalf("cursor = %+v, esperado valores %v", next.cursor, want)
+	}
+
+	// A página anterior é buscada na ordem inversa e devolvida na ordem pedida
+	prevMeta := next.Meta(next.Trim(items(7)), 3)
+	prev := parse(t, "/products?sort=-price&limit=2&cursor="+*prevMeta.PrevCursor)
+	rows := prev.Trim(items(8, 9))
+	if rows[0].ID != 9 || rows[1].ID != 8 {
+		t.Errorf("página anterior = %v, esperado 9, 8", rows)
+	}
+
+	// O cursor não vale para outra ordenação
+	u, _ := url.Parse("/products?sort=name&cursor=" + *meta.NextCursor)
+	if _, err := Parse(u, spec); err != ErrInvalidCursor {
+		t.Errorf("Parse com outra ordenação = %v, esperado ErrInvalidCursor", err)
+	}
+}
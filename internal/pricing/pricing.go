@@ -0,0 +1,105 @@
+// Package pricing registra o histórico de preço e custo dos produtos e
+// aplica as alterações de preço agendadas.
+package pricing
+
+import (
+	"time"
+
+	"loja-online/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Author identifica quem e o que originou uma alteração de preço
+type Author struct {
+	UserID           *uint
+	Source           string
+	ScheduledPriceID *uint
+}
+
+// RecordChange registra o preço e o custo de after quando diferem de before.
+// Com before nil, registra os valores do cadastro, sem os anteriores.
+func RecordChange(tx *gorm.DB, before, after *models.Product, author Author) error {
+	entry := models.PriceHistory{
+		ProductID:        after.ID,
+		Price:            after.Price,
+		CostPrice:        after.CostPrice,
+		Source:           author.Source,
+		UserID:           author.UserID,
+		ScheduledPriceID: author.ScheduledPriceID,
+		ChangedAt:        time.Now(),
+	}
+	if before != nil {
+		if before.Price == after.Price && before.CostPrice == after.CostPrice {
+			return nil
+		}
+		entry.PreviousPrice = &before.Price
+		entry.PreviousCostPrice = &before.CostPrice
+	}
+	return tx.Create(&entry).Error
+}
+
+// SyncVariants replica nas variações o custo e o preço alterados no estilo,
+// registrando o histórico de cada uma. O preço só é replicado nas variações
+// sem preço próprio.
+func SyncVariants(tx *gorm.DB, before, style *models.Product, author Author) error {
+	priceChanged := before.Price != style.Price
+	if !priceChanged && before.CostPrice == style.CostPrice {
+		return nil
+	}
+
+	var variants []models.Product
+	if err := tx.Where("parent_id = ?", style.ID).Find(&variants).Error; err != nil {
+		return err
+	}
+
+	variantAuthor := author
+	variantAuthor.Source = models.PriceSourceStyle
+	for i := range variants {
+		variant := variants[i]
+		variant.CostPrice = style.CostPrice
+		if priceChanged && !variant.PriceOverride {
+			variant.Price = style.Price
+		}
+		if variant.Price == variants[i].Price && variant.CostPrice == variants[i].CostPrice {
+			continue
+		}
+		if err := tx.Model(&variant).Updates(map[string]interface{}{
+			"price":      variant.Price,
+			"cost_price": variant.CostPrice,
+		}).Error; err != nil {
+			return err
+		}
+		if err := RecordChange(tx, &variants[i], &variant, variantAuthor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncPriceOverride marca se a variação tem preço próprio. Informar o mesmo
+// preço do estilo volta a seguir o preço do estilo.
+func SyncPriceOverride(tx *gorm.DB, variant *models.Product) error {
+	var style models.Product
+	if err := tx.Select("id, price").First(&style, *variant.ParentID).Error; err != nil {
+		return err
+	}
+
+	override := variant.Price != style.Price
+	if override == variant.PriceOverride {
+		return nil
+	}
+	variant.PriceOverride = override
+	return tx.Model(variant).Update("price_override", override).Error
+}
+
+// PriceAt retorna o registro do histórico vigente no instante informado, ou
+// gorm.ErrRecordNotFound se o produto ainda não existia
+func PriceAt(db *gorm.DB, productID uint, at time.Time) (*models.PriceHistory, error) {
+	var entry models.PriceHistory
+	if err := db.Where("product_id = ? AND changed_at <= ?", productID, at).
+		Order("changed_at DESC, id DESC").First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
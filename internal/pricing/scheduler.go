@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"loja-online/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errNothingDue encerra o laço de ApplyDue quando não há alterações vencidas
+var errNothingDue = errors.New("nenhuma alteração de preço vencida")
+
+// ApplyDue aplica as alterações de preço agendadas até now, uma por
+// transação e na ordem da data de vigência. As linhas são travadas com SKIP
+// LOCKED, então várias instâncias podem rodar ao mesmo tempo.
+func ApplyDue(db *gorm.DB, now time.Time) (int, error) {
+	applied := 0
+	for {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var schedule models.ScheduledPrice
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ? AND effective_at <= ?", models.ScheduledPricePending, now).
+				Order("effective_at, id").
+				First(&schedule).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errNothingDue
+			}
+			if err != nil {
+				return err
+			}
+			return apply(tx, &schedule, now)
+		})
+		if errors.Is(err, errNothingDue) {
+			return applied, nil
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+}
+
+// apply altera o produto e marca a alteração como aplicada; produto removido
+// cancela a alteração
+func apply(tx *gorm.DB, schedule *models.ScheduledPrice, now time.Time) error {
+	var product models.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, schedule.ProductID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Model(schedule).Updates(map[string]interface{}{
+			"status": models.ScheduledPriceCancelled,
+			"error":  "Produto removido",
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	before := product
+	if schedule.Price != nil {
+		product.Price = *schedule.Price
+	}
+	if schedule.CostPrice != nil {
+		product.CostPrice = *schedule.CostPrice
+	}
+	if err := tx.Model(&product).Updates(map[string]interface{}{
+		"price":      product.Price,
+		"cost_price": product.CostPrice,
+	}).Error; err != nil {
+		return err
+	}
+
+	author := Author{UserID: &schedule.CreatedByID, Source: models.PriceSourceSchedule, ScheduledPriceID: &schedule.ID}
+	if err := RecordChange(tx, &before, &product, author); err != nil {
+		return err
+	}
+	if product.IsVariant() {
+		if err := SyncPriceOverride(tx, &product); err != nil {
+			return err
+		}
+	}
+	if product.HasVariants {
+		if err := SyncVariants(tx, &before, &product, author); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(schedule).Updates(map[string]interface{}{
+		"status":     models.ScheduledPriceApplied,
+		"applied_at": now,
+	}).Error
+}
+
+// Run aplica periodicamente as alterações de preço vencidas. Bloqueia até
+// stop ser fechado.
+func Run(db *gorm.DB, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			applied, err := ApplyDue(db, time.Now())
+			if err != nil {
+				log.Printf("Erro ao aplicar alterações de preço agendadas: %v", err)
+			}
+			if applied > 0 {
+				log.Printf("%d alterações de preço agendadas aplicadas", applied)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package pricing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"loja-online/internal/dbtest"
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+var productColumns = []string{"id", "price", "cost_price", "parent_id", "has_variants", "price_override"}
+
+// dueSchedule responde uma única vez à busca de alterações vencidas, com a
+// alteração 11 do preço do produto 5 para 79,90
+func dueSchedule(fake *dbtest.DB) {
+	fake.On(`FROM "scheduled_prices"`).Once().Rows(
+		[]string{"id", "product_id", "price", "status", "created_by_id"},
+		[]interface{}{11, 5, money.FromCents(79_90), models.ScheduledPricePending, 2})
+}
+
+// hasArg indica se value está entre os argumentos do comando
+func hasArg(query dbtest.Query, value interface{}) bool {
+	for _, arg := range query.Args {
+		if arg == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestApplyDue(t *testing.T) {
+	db, fake := dbtest.Open(t)
+	dueSchedule(fake)
+	fake.On(`FROM "products"`).Rows(productColumns,
+		[]interface{}{5, money.FromCents(99_90), money.FromCents(40_00), nil, false, false})
+	now := time.Date(2026, 6, 1, 3, 0, 0, 0, time.UTC)
+
+	applied, err := ApplyDue(db, now)
+	if err != nil || applied != 1 {
+		t.Fatalf("aplicadas = %d, %v; esperada 1", applied, err)
+	}
+
+	lookup := fake.Queries(`FROM "scheduled_prices"`)
+	if len(lookup) != 2 {
+		t.Fatalf("%d buscas de alterações, esperadas 2 (a segunda sem resultado)", len(lookup))
+	}
+	if !strings.Contains(lookup[0].SQL, "FOR UPDATE SKIP LOCKED") || !hasArg(lookup[0], models.ScheduledPricePending) || !hasArg(lookup[0], now) {
+		t.Errorf("a busca deve travar as alterações pendentes vencidas: %v", lookup[0])
+	}
+
+	updates := fake.Queries(`UPDATE "products"`)
+	if len(updates) != 1 || !hasArg(updates[0], "79.90") || !hasArg(updates[0], "40.00") {
+		t.Fatalf("o produto deve receber o novo preço e manter o custo: %v", updates)
+	}
+
+	history := fake.Queries(`INSERT INTO "price_histories"`)
+	if len(history) != 1 {
+		t.Fatalf("%d registros de histórico, esperado 1", len(history))
+	}
+	for _, value := range []interface{}{"79.90", "99.90", models.PriceSourceSchedule, int64(11), int64(2)} {
+		if !hasArg(history[0], value) {
+			t.Errorf("o histórico deve registrar %v: %v", value, history[0].Args)
+		}
+	}
+
+	marked := fake.Queries(`UPDATE "scheduled_prices"`)
+	if len(marked) != 1 || !hasArg(marked[0], models.ScheduledPriceApplied) || !hasArg(marked[0], now) {
+		t.Errorf("a alteração deve ser marcada como aplicada: %v", marked)
+	}
+}
+
+func TestApplyDueRemovedProduct(t *testing.T) {
+	db, fake := dbtest.Open(t)
+	dueSchedule(fake)
+
+	applied, err := ApplyDue(db, time.Now())
+	if err != nil || applied != 1 {
+		t.Fatalf("aplicadas = %d, %v; esperada 1", applied, err)
+	}
+	if fake.Executed(`UPDATE "products"`) || fake.Executed(`INSERT INTO "price_histories"`) {
+		t.Error("produto removido não deve ser alterado")
+	}
+
+	marked := fake.Queries(`UPDATE "scheduled_prices"`)
+	if len(marked) != 1 || !hasArg(marked[0], models.ScheduledPriceCancelled) || !hasArg(marked[0], "Produto removido") {
+		t.Errorf("a alteração deve ser cancelada com o motivo: %v", marked)
+	}
+}
+
+// No estilo, o novo preço passa às variações sem preço próprio
+func TestApplyDueStyle(t *testing.T) {
+	db, fake := dbtest.Open(t)
+	dueSchedule(fake)
+	fake.On("parent_id = $1").Rows(productColumns,
+		[]interface{}{6, money.FromCents(99_90), money.FromCents(40_00), 5, false, false},
+		[]interface{}{7, money.FromCents(120_00), money.FromCents(40_00), 5, false, true})
+	fake.On(`FROM "products"`).Rows(productColumns,
+		[]interface{}{5, money.FromCents(99_90), money.FromCents(40_00), nil, true, false})
+
+	if _, err := ApplyDue(db, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := fake.Queries(`UPDATE "products"`)
+	if len(updates) != 2 || !hasArg(updates[0], int64(5)) || !hasArg(updates[1], int64(6)) || !hasArg(updates[1], "79.90") {
+		t.Fatalf("devem ser alterados o estilo e a variação 6: %v", updates)
+	}
+
+	history := fake.Queries(`INSERT INTO "price_histories"`)
+	if len(history) != 2 || !hasArg(history[1], models.PriceSourceStyle) || !hasArg(history[1], int64(6)) {
+		t.Errorf("a variação deve ter o histórico replicado do estilo: %v", history)
+	}
+}
+
+func TestApplyDueRollsBackOnError(t *testing.T) {
+	db, fake := dbtest.Open(t)
+	dueSchedule(fake)
+	fake.On(`FROM "products"`).Rows(productColumns,
+		[]interface{}{5, money.FromCents(99_90), money.FromCents(40_00), nil, false, false})
+	fake.On(`UPDATE "products"`).Fail(errors.New("conexão perdida"))
+
+	applied, err := ApplyDue(db, time.Now())
+	if err == nil || applied != 0 {
+		t.Fatalf("aplicadas = %d, %v; esperado erro", applied, err)
+	}
+	if !fake.Executed("ROLLBACK") || fake.Executed(`UPDATE "scheduled_prices"`) {
+		t.Error("a transação deve ser desfeita sem marcar a alteração")
+	}
+}
+
+func TestApplyDueNothingDue(t *testing.T) {
+	db, fake := dbtest.Open(t)
+
+	applied, err := ApplyDue(db, time.Now())
+	if err != nil || applied != 0 {
+		t.Fatalf("aplicadas = %d, %v; esperado 0", applied, err)
+	}
+	if fake.Executed(`UPDATE "`) {
+		t.Error("nada deve ser alterado")
+	}
+}
+
+func TestRecordChange(t *testing.T) {
+	product := models.Product{ID: 5, Price: money.FromCents(99_90), CostPrice: money.FromCents(40_00)}
+	repriced := product
+	repriced.Price = money.FromCents(79_90)
+
+	tests := []struct {
+		name     string
+		before   *models.Product
+		recorded bool
+		previous bool
+	}{
+		{"cadastro", nil, true, false},
+		{"preço alterado", &product, true, true},
+		{"sem alteração", &repriced, false, false},
+	}
+
+	for _, tt := range tests {
+		db, fake := dbtest.Open(t)
+		if err := RecordChange(db, tt.before, &repriced, Author{Source: models.PriceSourceManual}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		inserts := fake.Queries(`INSERT INTO "price_histories"`)
+		if recorded := len(inserts) == 1; recorded != tt.recorded {
+			t.Errorf("%s: registrado = %v, esperado %v", tt.name, recorded, tt.recorded)
+			continue
+		}
+		if tt.recorded && hasArg(inserts[0], "99.90") != tt.previous {
+			t.Errorf("%s: preço anterior registrado = %v, esperado %v", tt.name, !tt.previous, tt.previous)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package promotions calcula os descontos automáticos dos itens de uma venda
+// a partir das promoções cadastradas.
+package promotions
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+// Line é um item da venda. StyleSKU é o SKU do estilo quando o produto é uma
+// variação, para as promoções por lista de SKUs, e Categories os nomes das
+// categorias ancestrais, para uma promoção de Masculino valer em Masculino >
+// Bermudas.
+type Line struct {
+	Product    models.Product
+	StyleSKU   string
+	Categories []string
+	Quantity   int
+	UnitPrice  money.Amount
+}
+
+// Result é o desconto de um item e o detalhamento por promoção
+type Result struct {
+	Discount money.Amount
+	Applied  models.AppliedPromotions
+}
+
+// Apply calcula o desconto de cada item, na mesma ordem de lines. As
+// promoções valem por ordem de prioridade (maior primeiro, empate pelo ID),
+// cada uma sobre o valor do item já descontado pelas anteriores. Uma promoção
+// não cumulativa só vale para itens ainda sem desconto, e os itens em que ela
+// vale não recebem as promoções seguintes.
+func Apply(promotions []models.Promotion, lines []Line, storeID uint, at time.Time) []Result {
+	sorted := make([]models.Promotion, 0, len(promotions))
+	for _, promotion := range promotions {
+		if validAt(&promotion, at) && (promotion.StoreID == nil || *promotion.StoreID == storeID) {
+			sorted = append(sorted, promotion)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	results := make([]Result, len(lines))
+	net := make([]money.Amount, len(lines))
+	locked := make([]bool, len(lines))
+	for i, line := range lines {
+		net[i] = line.UnitPrice.Mul(line.Quantity)
+	}
+
+	for i := range sorted {
+		promotion := &sorted[i]
+
+		var eligible []int
+		for j := range lines {
+			if locked[j] || net[j] <= 0 || lines[j].Quantity <= 0 {
+				continue
+			}
+			if !promotion.Cumulative && len(results[j].Applied) > 0 {
+				continue
+			}
+			if Matches(promotion, &lines[j]) {
+				eligible = append(eligible, j)
+			}
+		}
+
+		for j, amount := range discounts(promotion, lines, net, eligible) {
+			amount = min(amount, net[j])
+			if amount <= 0 {
+				continue
+			}
+			net[j] -= amount
+			results[j].Discount += amount
+			results[j].Applied = append(results[j].Applied, models.AppliedPromotion{
+				PromotionID: promotion.ID,
+				Name:        promotion.Name,
+				Type:        promotion.Type,
+				Amount:      amount,
+			})
+			if !promotion.Cumulative {
+				locked[j] = true
+			}
+		}
+	}
+	return results
+}
+
+// Matches verifica se todos os campos de escopo da promoção coincidem com o
+// produto do item
+func Matches(promotion *models.Promotion, line *Line) bool {
+	product := &line.Product
+	return matchCategory(promotion.Categories, line) &&
+		matchAny(promotion.Brands, product.Brand) &&
+		matchAny(promotion.Seasons, product.Season) &&
+		matchAny(promotion.Genders, product.Gender) &&
+		(matchAny(promotion.SKUs, product.SKU) || (line.StyleSKU != "" && matchAny(promotion.SKUs, line.StyleSKU)))
+}
+
+// matchCategory aceita a categoria do produto ou qualquer ancestral dela
+func matchCategory(list models.StringList, line *Line) bool {
+	if matchAny(list, line.Product.Category) {
+		return true
+	}
+	for _, name := range line.Categories {
+		if matchAny(list, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny aceita qualquer valor com a lista vazia; sem distinguir maiúsculas
+func matchAny(list models.StringList, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// discounts retorna o desconto da promoção em cada item elegível, calculado
+// sobre o valor ainda não descontado
+func discounts(promotion *models.Promotion, lines []Line, net []money.Amount, eligible []int) map[int]money.Amount {
+	amounts := make(map[int]money.Amount, len(eligible))
+	switch promotion.Type {
+	case models.PromotionPercentage:
+		for _, j := range eligible {
+			amounts[j] = net[j].Percent(promotion.Percent)
+		}
+
+	case models.PromotionFixed:
+		for _, j := range eligible {
+			amounts[j] = promotion.Value.Mul(lines[j].Quantity)
+		}
+
+	case models.PromotionProgressive:
+		quantity := 0
+		for _, j := range eligible {
+			quantity += lines[j].Quantity
+		}
+		percent := tierPercent(promotion.Tiers, quantity)
+		for _, j := range eligible {
+			amounts[j] = net[j].Percent(percent)
+		}
+
+	case models.PromotionBuyXPayY:
+		buy, pay := promotion.BuyQuantity, promotion.PayQuantity
+		if buy <= 0 || pay < 0 || pay >= buy {
+			break
+		}
+
+		// Cada unidade entra pelo valor já descontado; as mais baratas de cada
+		// grupo de X saem de graça. O desconto do item é a fração do seu valor
+		// correspondente às unidades gratuitas.
+		var units []int
+		for _, j := range eligible {
+			for k := 0; k < lines[j].Quantity; k++ {
+				units = append(units, j)
+			}
+		}
+		sort.SliceStable(units, func(a, b int) bool {
+			// Compara os preços unitários net/quantidade sem dividir
+			x, y := units[a], units[b]
+			return net[x].Cents()*int64(lines[y].Quantity) < net[y].Cents()*int64(lines[x].Quantity)
+		})
+
+		free := make(map[int]int64)
+		for _, j := range units[:len(units)/buy*(buy-pay)] {
+			free[j]++
+		}
+		for j, count := range free {
+			amounts[j] = net[j].Fraction(count, int64(lines[j].Quantity))
+		}
+	}
+	return amounts
+}
+
+// tierPercent retorna o percentual da maior faixa atingida pela quantidade
+func tierPercent(tiers models.PromotionTiers, quantity int) money.Rate {
+	percent, reached := money.Rate(0), 0
+	for _, tier := range tiers {
+		if quantity >= tier.MinQuantity && tier.MinQuantity > reached {
+			percent, reached = tier.Percent, tier.MinQuantity
+		}
+	}
+	return percent
+}
+
+func validAt(promotion *models.Promotion, at time.Time) bool {
+	if !promotion.Active {
+		return false
+	}
+	if promotion.StartsAt != nil && at.Before(*promotion.StartsAt) {
+		return false
+	}
+	if promotion.EndsAt != nil && !at.Before(*promotion.EndsAt) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package promotions
+
+import (
+	"testing"
+	"time"
+
+	"loja-online/internal/models"
+	"loja-online/internal/money"
+)
+
+var now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func line(sku, category, brand string, quantity int, price float64) Line {
+	return Line{
+		Product:   models.Product{SKU: sku, Category: category, Brand: brand, Season: "Verão", Gender: "F"},
+		Quantity:  quantity,
+		UnitPrice: money.FromFloat(price),
+	}
+}
+
+func discountsOf(results []Result) []float64 {
+	out := make([]float64, len(results))
+	for i, result := range results {
+		out[i] = result.Discount.Float64()
+	}
+	return out
+}
+
+func assertDiscounts(t *testing.T, got []Result, want ...float64) {
+	t.Helper()
+	values := discountsOf(got)
+	if len(values) != len(want) {
+		t.Fatalf("descontos = %v, esperado %v", values, want)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Fatalf("descontos = %v, esperado %v", values, want)
+		}
+	}
+}
+
+func TestApplyPercentageAndFixedByScope(t *testing.T) {
+	promotions := []models.Promotion{
+		{ID: 1, Name: "Camisetas 10%", Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Categories: models.StringList{"camiseta"}, Active: true},
+		{ID: 2, Name: "Marca X R$ 5", Type: models.PromotionFixed, Value: money.FromCents(5_00), Brands: models.StringList{"Marca X"}, Active: true},
+	}
+	lines := []Line{
+		line("CAM-1", "Camiseta", "Marca Y", 2, 50),
+		line("CAL-1", "Calça", "Marca X", 3, 120),
+		line("BON-1", "Boné", "Marca Z", 1, 40),
+	}
+
+	results := Apply(promotions, lines, 1, now)
+	assertDiscounts(t, results, 10, 15, 0)
+	if len(results[0].Applied) != 1 || results[0].Applied[0].PromotionID != 1 {
+		t.Fatalf("detalhamento inesperado: %+v", results[0].Applied)
+	}
+}
+
+func TestApplyFixedNeverExceedsPrice(t *testing.T) {
+	promotions := []models.Promotion{{ID: 1, Type: models.PromotionFixed, Value: money.FromCents(80_00), Active: true}}
+	results := Apply(promotions, []Line{line("A", "Meia", "", 2, 15)}, 1, now)
+	assertDiscounts(t, results, 30)
+}
+
+func TestApplyBuyXPayYFreesCheapestUnits(t *testing.T) {
+	promotions := []models.Promotion{
+		{ID: 1, Name: "Leve 3 pague 2", Type: models.PromotionBuyXPayY, BuyQuantity: 3, PayQuantity: 2, Categories: models.StringList{"Camiseta"}, Active: true},
+	}
+	lines := []Line{
+		line("A", "Camiseta", "", 2, 60),
+		line("B", "Camiseta", "", 3, 40),
+		line("C", "Calça", "", 1, 10),
+	}
+
+	// 5 camisetas: um grupo completo de 3, a unidade mais barata sai de graça
+	assertDiscounts(t, Apply(promotions, lines, 1, now), 0, 40, 0)
+
+	// 6 camisetas: dois grupos, as duas mais baratas saem de graça
+	lines[1].Quantity = 4
+	assertDiscounts(t, Apply(promotions, lines, 1, now), 0, 80, 0)
+}
+
+func TestApplyProgressiveUsesHighestTierReached(t *testing.T) {
+	promotions := []models.Promotion{{
+		ID: 1, Type: models.PromotionProgressive, Active: true,
+		Tiers: models.PromotionTiers{{MinQuantity: 2, Percent: money.BasisPoints(10_00)}, {MinQuantity: 4, Percent: money.BasisPoints(20_00)}},
+	}}
+
+	assertDiscounts(t, Apply(promotions, []Line{line("A", "", "", 1, 100)}, 1, now), 0)
+	assertDiscounts(t, Apply(promotions, []Line{line("A", "", "", 1, 100), line("B", "", "", 2, 50)}, 1, now), 10, 10)
+	assertDiscounts(t, Apply(promotions, []Line{line("A", "", "", 1, 100), line("B", "", "", 3, 50)}, 1, now), 20, 30)
+}
+
+func TestApplyStacking(t *testing.T) {
+	lines := []Line{line("A", "Camiseta", "", 1, 100)}
+
+	tests := []struct {
+		name       string
+		promotions []models.Promotion
+		want       float64
+		applied    int
+	}{
+		{
+			"cumulativas somam sobre o valor já descontado",
+			[]models.Promotion{
+				{ID: 1, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Cumulative: true, Active: true},
+				{ID: 2, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Cumulative: true, Active: true},
+			},
+			19, 2,
+		},
+		{
+			"não cumulativa de maior prioridade impede as seguintes",
+			[]models.Promotion{
+				{ID: 1, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Cumulative: true, Active: true},
+				{ID: 2, Type: models.PromotionPercentage, Percent: money.BasisPoints(30_00), Priority: 5, Active: true},
+			},
+			30, 1,
+		},
+		{
+			"não cumulativa não vale para item já descontado",
+			[]models.Promotion{
+				{ID: 1, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Priority: 5, Cumulative: true, Active: true},
+				{ID: 2, Type: models.PromotionPercentage, Percent: money.BasisPoints(30_00), Active: true},
+			},
+			10, 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := Apply(tt.promotions, lines, 1, now)
+			if results[0].Discount != money.FromFloat(tt.want) || len(results[0].Applied) != tt.applied {
+				t.Fatalf("desconto = %v com %d promoções, esperado %v com %d", results[0].Discount, len(results[0].Applied), tt.want, tt.applied)
+			}
+		})
+	}
+}
+
+func TestApplyIgnoresInactiveOutOfWindowAndOtherStores(t *testing.T) {
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	otherStore := uint(2)
+	promotions := []models.Promotion{
+		{ID: 1, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Active: false},
+		{ID: 2, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Active: true, StartsAt: &tomorrow},
+		{ID: 3, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Active: true, EndsAt: &now},
+		{ID: 4, Type: models.PromotionPercentage, Percent: money.BasisPoints(10_00), Active: true, StoreID: &otherStore},
+		{ID: 5, Type: models.PromotionPercentage, Percent: money.BasisPoints(5_00), Active: true, StartsAt: &yesterday, EndsAt: &tomorrow},
+	}
+
+	results := Apply(promotions, []Line{line("A", "", "", 1, 100)}, 1, now)
+	assertDiscounts(t, results, 5)
+}
+
+func TestMatchesStyleSKU(t *testing.T) {
+	promotion := models.Promotion{SKUs: models.StringList{"CAM-001"}}
+	variant := line("CAM-001-P-AZUL", "", "", 1, 10)
+	if Matches(&promotion, &variant) {
+		t.Fatal("variação não deveria coincidir sem o SKU do estilo")
+	}
+	variant.StyleSKU = "CAM-001"
+	if !Matches(&promotion, &variant) {
+		t.Fatal("variação deveria coincidir pelo SKU do estilo")
+	}
+}
+
+func TestMatchesAncestorCategory(t *testing.T) {
+	promotion := models.Promotion{Categories: models.StringList{"masculino"}}
+	bermuda := line("BER-1", "Surf", "", 1, 10)
+	if Matches(&promotion, &bermuda) {
+		t.Fatal("não deveria coincidir sem as categorias ancestrais")
+	}
+	bermuda.Categories = []string{"Masculino", "Bermudas", "Surf"}
+	if !Matches(&promotion, &bermuda) {
+		t.Fatal("deveria coincidir pela categoria ancestral")
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryBucket struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryStore mantém os contadores em memória, válido apenas para uma instância
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	hits    int
+	now     func() time.Time
+}
+
+// NewMemoryStore cria um Store em memória
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: time.Now}
+}
+
+// Hit registra uma tentativa para a chave
+func (s *MemoryStore) Hit(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.hits++
+	if s.hits%1000 == 0 {
+		s.cleanup(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok || !now.Before(bucket.resetAt) {
+		bucket = &memoryBucket{resetAt: now.Add(window)}
+		s.buckets[key] = bucket
+	}
+
+	bucket.count++
+	if bucket.count > limit {
+		return false, bucket.resetAt.Sub(now), nil
+	}
+	return true, 0, nil
+}
+
+// cleanup remove janelas expiradas para limitar o uso de memória
+func (s *MemoryStore) cleanup(now time.Time) {
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.resetAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreLimitAndRetryAfter(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	for i := 1; i <= 3; i++ {
+		if allowed, _, _ := store.Hit("login:ana", 3, time.Minute); !allowed {
+			t.Fatalf("tentativa %d dentro do limite recusada", i)
+		}
+	}
+
+	now = now.Add(20 * time.Second)
+	allowed, retryAfter, err := store.Hit("login:ana", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed {
+		t.Fatal("tentativa acima do limite aceita")
+	}
+	if retryAfter != 40*time.Second {
+		t.Errorf("retry-after %v, esperado 40s até o fim da janela", retryAfter)
+	}
+
+	// Outra chave tem contador próprio
+	if allowed, _, _ := store.Hit("login:bia", 3, time.Minute); !allowed {
+		t.Error("chave diferente não deveria ser afetada")
+	}
+}
+
+func TestMemoryStoreWindowReset(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.Hit("login:ana", 1, time.Minute)
+	if allowed, _, _ := store.Hit("login:ana", 1, time.Minute); allowed {
+		t.Fatal("segunda tentativa na janela aceita")
+	}
+
+	// Ao fim da janela, a contagem recomeça
+	now = now.Add(time.Minute)
+	if allowed, retryAfter, _ := store.Hit("login:ana", 1, time.Minute); !allowed || retryAfter != 0 {
+		t.Errorf("janela nova: aceito = %v, retry-after = %v", allowed, retryAfter)
+	}
+	if allowed, retryAfter, _ := store.Hit("login:ana", 1, time.Minute); allowed || retryAfter != time.Minute {
+		t.Errorf("limite da janela nova: aceito = %v, retry-after = %v", allowed, retryAfter)
+	}
+}
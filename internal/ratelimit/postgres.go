@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bucket é a tabela compartilhada de contadores
+type bucket struct {
+	Key     string    `gorm:"primaryKey"`
+	Count   int       `gorm:"not null"`
+	ResetAt time.Time `gorm:"not null;index"`
+}
+
+func (bucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// PostgresStore mantém os contadores no PostgreSQL, compartilhados entre instâncias
+type PostgresStore struct {
+	db   *gorm.DB
+	hits atomic.Int64
+}
+
+// NewPostgresStore cria um Store no PostgreSQL, criando a tabela se necessário
+func NewPostgresStore(db *gorm.DB) (*PostgresStore, error) {
+	if err := db.AutoMigrate(&bucket{}); err != nil {
+		return nil, err
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Hit registra uma tentativa para a chave com um único upsert atômico
+func (s *PostgresStore) Hit(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	if s.hits.Add(1)%1000 == 0 {
+		s.db.Where("reset_at <= ?", now).Delete(&bucket{})
+	}
+
+	var count int
+	var resetAt time.Time
+	err := s.db.Raw(`
+		INSERT INTO rate_limit_buckets (key, count, reset_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limit_buckets.reset_at <= ? THEN 1 ELSE rate_limit_buckets.count + 1 END,
+			reset_at = CASE WHEN rate_limit_buckets.reset_at <= ? THEN EXCLUDED.reset_at ELSE rate_limit_buckets.reset_at END
+		RETURNING count, reset_at`,
+		key, now.Add(window), now, now,
+	).Row().Scan(&count, &resetAt)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if count > limit {
+		return false, resetAt.Sub(now), nil
+	}
+	return true, 0, nil
+}
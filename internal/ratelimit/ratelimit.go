@@ -0,0 +1,31 @@
+// Package ratelimit implementa limitação de requisições por janela fixa com
+// armazenamento plugável: em memória para uma única instância ou no
+// PostgreSQL para várias instâncias atrás de um balanceador.
+package ratelimit
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Store conta tentativas por chave dentro de uma janela de tempo
+type Store interface {
+	// Hit registra uma tentativa e informa se ela está dentro do limite. Quando
+	// o limite é excedido, retryAfter indica quanto falta para a janela reiniciar.
+	Hit(key string, limit int, window time.Duration) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// NewStore cria o Store configurado ("memory" ou "postgres"). Em caso de falha
+// ao preparar o PostgreSQL, usa o armazenamento em memória.
+func NewStore(driver string, db *gorm.DB) Store {
+	if driver == "postgres" {
+		store, err := NewPostgresStore(db)
+		if err == nil {
+			return store
+		}
+		log.Printf("Aviso: Falha ao preparar rate limit no PostgreSQL (%v), usando memória", err)
+	}
+	return NewMemoryStore()
+}
@@ -0,0 +1,114 @@
+// Package spreadsheet lê e grava planilhas CSV e XLSX usando apenas a
+// biblioteca padrão. Do XLSX é lida a primeira planilha, com os valores das
+// células como texto; a gravação gera uma planilha simples, sem estilos.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Formatos suportados
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnsupported indica um arquivo que não é CSV nem XLSX
+var ErrUnsupported = errors.New("formato de planilha não suportado; envie CSV ou XLSX")
+
+// ContentType retorna o tipo de conteúdo do formato
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Detect identifica o formato pelo conteúdo: XLSX é um arquivo ZIP; qualquer
+// outro texto é tratado como CSV
+func Detect(data []byte) string {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX
+	}
+	return FormatCSV
+}
+
+// Read lê as linhas da planilha. Linhas totalmente vazias são mantidas, para
+// que o número da linha nos erros corresponda ao do arquivo.
+func Read(data []byte, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(data)
+	case FormatXLSX:
+		return readXLSX(data)
+	}
+	return nil, ErrUnsupported
+}
+
+// Write grava as linhas no formato pedido. Nos valores float64 e int, o XLSX
+// usa células numéricas; os demais são gravados como texto.
+func Write(w io.Writer, format string, rows [][]any) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, rows)
+	case FormatXLSX:
+		return writeXLSX(w, rows)
+	}
+	return ErrUnsupported
+}
+
+// readCSV aceita vírgula ou ponto e vírgula (padrão do Excel em português)
+// como separador e ignora o BOM do UTF-8
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV inválido: %w", err)
+	}
+	return rows, nil
+}
+
+func writeCSV(w io.Writer, rows [][]any) error {
+	writer := csv.NewWriter(w)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatCell(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatCell converte o valor da célula em texto, com ponto decimal
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
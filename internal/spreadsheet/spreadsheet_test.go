@@ -0,0 +1,67 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestXLSXRoundTrip(t *testing.T) {
+	rows := [][]any{
+		{"sku", "nome", "preço", "estoque"},
+		{"CAM-001", "Camiseta <Básica> & Cia", 49.9, 10},
+		{},
+		{"BER-002", "Bermuda", nil, 0},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatXLSX, rows); err != nil {
+		t.Fatal(err)
+	}
+	if format := Detect(buf.Bytes()); format != FormatXLSX {
+		t.Fatalf("Detect = %q, esperado xlsx", format)
+	}
+
+	got, err := Read(buf.Bytes(), FormatXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"sku", "nome", "preço", "estoque"},
+		{"CAM-001", "Camiseta <Básica> & Cia", "49.9", "10"},
+		nil,
+		{"BER-002", "Bermuda", "", "0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %q, esperado %q", got, want)
+	}
+}
+
+func TestReadCSVDetectsSeparator(t *testing.T) {
+	data := []byte("\xef\xbb\xbfsku;nome;preço\nCAM-001;\"Camiseta; branca\";49,90\n")
+	got, err := Read(data, Detect(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"sku", "nome", "preço"}, {"CAM-001", "Camiseta; branca", "49,90"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read = %q, esperado %q", got, want)
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != name {
+			t.Errorf("columnName(%d) = %q, esperado %q", index, got, name)
+		}
+		if got, err := columnIndex(name + "12"); err != nil || got != index {
+			t.Errorf("columnIndex(%q) = %d, %v, esperado %d", name+"12", got, err, index)
+		}
+	}
+}
+
+func TestReadRejectsInvalidXLSX(t *testing.T) {
+	if _, err := Read([]byte("PK\x03\x04corrompido"), FormatXLSX); err == nil {
+		t.Error("Read aceitou um XLSX corrompido")
+	}
+}
@@ -0,0 +1,280 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Limite do tamanho descompactado de cada parte do XLSX, contra arquivos
+// que se expandem demais ao descompactar
+const maxPartSize = 64 << 20
+
+var errInvalidXLSX = errors.New("XLSX inválido")
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText é um texto com formatação opcional em trechos (<r>)
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string    `xml:"r,attr"`
+			T      string    `xml:"t,attr"`
+			V      string    `xml:"v"`
+			Inline *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errInvalidXLSX
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodePart(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	file, ok := files[sheetPath]
+	if !ok {
+		return nil, errInvalidXLSX
+	}
+	var sheet xlsxSheet
+	if err := decodePart(file, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// Linhas vazias não aparecem no XML; r guarda o número da linha
+		index := len(rows)
+		if row.R > 0 {
+			index = row.R - 1
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			column := i
+			if cell.R != "" {
+				if column, err = columnIndex(cell.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				n, err := strconv.Atoi(cell.V)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, errInvalidXLSX
+				}
+				values[column] = shared.Items[n].String()
+			case "inlineStr":
+				if cell.Inline != nil {
+					values[column] = cell.Inline.String()
+				}
+			case "b":
+				values[column] = strconv.FormatBool(cell.V == "1")
+			default:
+				values[column] = cell.V
+			}
+		}
+		rows[index] = values
+	}
+	return rows, nil
+}
+
+// firstSheetPath encontra o arquivo da primeira planilha pelo workbook e
+// pelos relacionamentos, que podem usar nomes diferentes de sheet1.xml
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	workbookFile, ok := files["xl/workbook.xml"]
+	relsFile, relsOK := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !relsOK {
+		return "", errInvalidXLSX
+	}
+	if err := decodePart(workbookFile, &workbook); err != nil {
+		return "", err
+	}
+	if err := decodePart(relsFile, &rels); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errInvalidXLSX
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RelID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", errInvalidXLSX
+}
+
+func decodePart(file *zip.File, v any) error {
+	reader, err := file.Open()
+	if err != nil {
+		return errInvalidXLSX
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(reader, maxPartSize)).Decode(v); err != nil {
+		return errInvalidXLSX
+	}
+	return nil
+}
+
+// columnIndex converte a referência da célula (ex.: "AB12") no índice da
+// coluna, a partir de zero
+func columnIndex(ref string) (int, error) {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A') + 1
+	}
+	if column == 0 || column > 16384 {
+		return 0, errInvalidXLSX
+	}
+	return column - 1, nil
+}
+
+// columnName converte o índice da coluna, a partir de zero, em letras
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// Partes fixas do XLSX gerado, com uma única planilha
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Planilha1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func writeXLSX(w io.Writer, rows [][]any) error {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var sheet bytes.Buffer
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for c, value := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch value.(type) {
+			case nil:
+				continue
+			case float64, int:
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, formatCell(value))
+			default:
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+				if err := xml.EscapeText(&sheet, []byte(formatCell(value))); err != nil {
+					return err
+				}
+				sheet.WriteString(`</t></is></c>`)
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+	if _, err := sheet.WriteTo(file); err != nil {
+		return err
+	}
+	return archive.Close()
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// LocalStorage guarda os objetos em um diretório do sistema de arquivos. O
+// tipo de conteúdo é deduzido da extensão da chave.
+type LocalStorage struct {
+	Dir string
+}
+
+// Put grava o objeto em um arquivo temporário e o renomeia, para que leituras
+// simultâneas nunca vejam um arquivo incompleto
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("erro ao criar diretório de %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao gravar %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", key, err)
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// Get abre o arquivo do objeto
+func (s *LocalStorage) Get(ctx context.Context, key string) (*Object, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %w", key, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Object{Body: f, ContentType: contentType, Size: info.Size()}, nil
+}
+
+// Delete remove o arquivo do objeto; remover um objeto inexistente não é erro
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao remover %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hash do corpo vazio, usado nas requisições sem conteúdo
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Storage guarda os objetos em um bucket de um serviço compatível com S3
+// (AWS S3, MinIO, Ceph e similares), com requisições assinadas em AWS
+// Signature Version 4. PathStyle monta as URLs como endpoint/bucket/chave,
+// formato exigido pelo MinIO e por endpoints locais.
+type S3Storage struct {
+	Endpoint  string // Ex.: https://s3.amazonaws.com ou http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+
+	// Client é o cliente HTTP usado; nil usa http.DefaultClient
+	Client *http.Client
+	now    func() time.Time
+}
+
+// Put envia o objeto com PUT
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, hashHex(data))
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao enviar %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return responseError("enviar", key, resp)
+	}
+	return nil
+}
+
+// Get lê o objeto com GET
+func (s *S3Storage) Get(ctx context.Context, key string) (*Object, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, emptyPayloadHash)
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %w", key, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, responseError("ler", key, resp)
+	}
+
+	return &Object{
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}, nil
+}
+
+// Delete remove o objeto; o S3 responde sucesso também para objetos inexistentes
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, emptyPayloadHash)
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao remover %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return responseError("remover", key, resp)
+	}
+	return nil
+}
+
+// newRequest monta a requisição para a URL do objeto
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("endpoint S3 inválido: %q", s.Endpoint)
+	}
+
+	objectURL := *endpoint
+	if s.PathStyle {
+		objectURL.Path = endpoint.Path + "/" + s.Bucket + "/" + cleaned
+	} else {
+		objectURL.Host = s.Bucket + "." + endpoint.Host
+		objectURL.Path = endpoint.Path + "/" + cleaned
+	}
+	objectURL.RawPath = escapePath(objectURL.Path)
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	return http.NewRequestWithContext(ctx, method, objectURL.String(), reader)
+}
+
+// sign adiciona os cabeçalhos de data e hash do corpo e a assinatura
+// AWS Signature Version 4 da requisição
+func (s *S3Storage) sign(req *http.Request, payloadHash string) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Assina o host, o tipo de conteúdo, o intervalo e os cabeçalhos x-amz-*
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func (s *S3Storage) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// escapePath codifica o caminho como exige a assinatura do S3: tudo exceto
+// letras, números, "-", ".", "_", "~" e o separador "/"
+func escapePath(p string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(p); i++ {
+		b := p[i]
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '.' || b == '_' || b == '~' || b == '/' {
+			escaped.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&escaped, "%%%02X", b)
+	}
+	return escaped.String()
+}
+
+func responseError(action, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("erro ao %s %s: status %d: %s", action, key, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,63 @@
+// Package storage guarda os arquivos enviados pela aplicação (imagens de
+// produtos) no sistema de arquivos local ou em um serviço compatível com S3.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"loja-online/internal/config"
+)
+
+// ErrNotFound indica que o objeto não existe
+var ErrNotFound = errors.New("objeto não encontrado")
+
+// ErrInvalidKey indica uma chave vazia ou que sai da raiz do armazenamento
+var ErrInvalidKey = errors.New("chave de objeto inválida")
+
+// Object é o conteúdo de um objeto lido do Storage. Body deve ser fechado.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// Storage grava, lê e remove objetos identificados por chaves no formato de
+// caminho relativo, como "products/1/abc/original.jpg"
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New cria o Storage configurado em STORAGE_DRIVER ("s3" ou "local")
+func New(cfg *config.Config) Storage {
+	switch cfg.StorageDriver {
+	case "s3":
+		return &S3Storage{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		}
+	default:
+		return &LocalStorage{Dir: cfg.StorageLocalDir}
+	}
+}
+
+// cleanKey normaliza a chave e rejeita caminhos absolutos ou com ".."
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,45 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// RecoveryCodeLength é a quantidade de caracteres do código de recuperação,
+// sem o hífen
+const RecoveryCodeLength = 10
+
+// GenerateRecoveryCodes gera n códigos de recuperação no formato xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:RecoveryCodeLength]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode remove separadores e padroniza o código informado
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// HashRecoveryCode retorna o hash guardado no lugar do código
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRecoveryCode indica se code corresponde ao hash guardado
+func VerifyRecoveryCode(hash, code string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashRecoveryCode(code))) == 1
+}
@@ -0,0 +1,132 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Segredo ASCII "12345678901234567890" dos vetores do RFC 6238, em base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeAtRFC6238(t *testing.T) {
+	// Vetores SHA-1 do apêndice B, com os 6 últimos dígitos dos códigos de 8
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.want {
+			t.Errorf("T=%d: código %s, esperado %s", tt.unix, code, tt.want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"janela atual", 0, true},
+		{"janela anterior", -1, true},
+		{"janela seguinte", 1, true},
+		{"duas janelas antes", -2, false},
+		{"duas janelas depois", 2, false},
+	}
+
+	for _, tt := range tests {
+		code, _ := CodeAt(rfcSecret, current+tt.offset)
+		step, ok := Validate(rfcSecret, code, now, 0)
+		if ok != tt.ok {
+			t.Errorf("%s: aceito = %v, esperado %v", tt.name, ok, tt.ok)
+		}
+		if ok && step != current+tt.offset {
+			t.Errorf("%s: contador %d, esperado %d", tt.name, step, current+tt.offset)
+		}
+	}
+}
+
+func TestValidateRejectsReplay(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code, _ := CodeAt(rfcSecret, current)
+
+	step, ok := Validate(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("código da janela atual deveria ser aceito")
+	}
+
+	tests := []struct {
+		name     string
+		lastStep int64
+	}{
+		{"mesmo contador", step},
+		{"contador posterior já usado", step + 1},
+	}
+	for _, tt := range tests {
+		if _, ok := Validate(rfcSecret, code, now, tt.lastStep); ok {
+			t.Errorf("%s: código reutilizado aceito", tt.name)
+		}
+	}
+
+	// Depois de usar o código atual, o da janela anterior também é recusado
+	previous, _ := CodeAt(rfcSecret, current-1)
+	if _, ok := Validate(rfcSecret, previous, now, step); ok {
+		t.Error("código de janela anterior à última usada aceito")
+	}
+}
+
+func TestValidateRejectsMalformedCode(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 0); ok {
+			t.Errorf("código %q aceito", code)
+		}
+	}
+	if _, ok := Validate(rfcSecret, "287 082", now, 0); !ok {
+		t.Error("código com espaço deveria ser aceito")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if len(NormalizeRecoveryCode(code)) != RecoveryCodeLength {
+			t.Fatalf("código %q fora do formato", code)
+		}
+	}
+	if codes[0] == codes[1] || codes[1] == codes[2] {
+		t.Errorf("códigos repetidos: %v", codes)
+	}
+
+	hash := HashRecoveryCode(codes[0])
+	if !VerifyRecoveryCode(hash, codes[0]) {
+		t.Error("código válido recusado")
+	}
+	// Maiúsculas, espaços e hífen são ignorados
+	if !VerifyRecoveryCode(hash, " "+strings.ToUpper(NormalizeRecoveryCode(codes[0]))+" ") {
+		t.Error("código em maiúsculas deveria ser aceito")
+	}
+	if VerifyRecoveryCode(hash, codes[1]) {
+		t.Error("código de outro registro aceito")
+	}
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"loja-online/internal/api"
 	"loja-online/internal/config"
 	"loja-online/internal/database"
+	"loja-online/internal/jwtkeys"
+	"loja-online/internal/pricing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -58,13 +61,23 @@ func main() {
 		log.Printf("Aviso: Falha ao criar usuário admin padrão: %v", err)
 	}
 
+	// Carrega as chaves de assinatura dos tokens e agenda a rotação
+	keys, err := jwtkeys.New(db, cfg)
+	if err != nil {
+		log.Fatal("Falha ao preparar chaves de assinatura:", err)
+	}
+	go keys.Run(time.Minute, nil)
+
+	// Aplica as alterações de preço agendadas
+	go pricing.Run(db, time.Minute, nil)
+
 	// Configura Gin
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	// Inicializa router
-	router := api.SetupRouter(db, cfg)
+	router := api.SetupRouter(db, cfg, keys)
 
 	// Inicia servidor
 	port := os.Getenv("PORT")